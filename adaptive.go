@@ -0,0 +1,119 @@
+package qlog
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// SampleRateLabel is the key of the label, written on logs sampled by adaptive sampling, of the rate they were sampled
+// at, so that counts derived from them can be scaled accordingly
+const SampleRateLabel = "sample_rate"
+
+// adaptiveSampler samples logs of each severity at a rate that is adjusted each second, such that the logs written
+// approach the target
+type adaptiveSampler struct {
+	target   float64
+	mx       sync.Mutex
+	window   atomic.Int64      // the unix second of the current window
+	arrived  [64]atomic.Int64  // the logs of the current window, indexed by the bit of their OutputFlag
+	observed [64]float64       // the moving average of the logs per second, guarded by mx
+	rates    [64]atomic.Uint64 // the bits of the float64 rate at which logs are sampled
+}
+
+// sampleRate is the rate at which a log was sampled, which is written to three significant figures, as labels of type
+// float64 are written to two decimal places
+type sampleRate float64
+
+var sampler = atomic.Pointer[adaptiveSampler]{}
+
+// SetAdaptiveSampling samples logs such that the number written per second approaches the target, adjusting the rate
+// at which each severity is sampled each second according to the recent volume of logs. The target is allocated to
+// the most severe logs first, so that, for example, Debug logs are sampled before Info logs, and Error logs are only
+// sampled should they alone exceed it. At least one log of each severity is written per second.
+//
+// Logs written while their severity is sampled have a `sample_rate` label of the rate, between 0 and 1. Fatal and Audit
+// logs, and those of traces marked verbose, are never sampled. Pass zero to disable adaptive sampling.
+// This operation is safe for concurrent use.
+func SetAdaptiveSampling(targetPerSecond int) {
+	if targetPerSecond <= 0 {
+		sampler.Store(nil)
+		return
+	}
+
+	s := &adaptiveSampler{target: float64(targetPerSecond)}
+	s.window.Store(timeNow().Unix())
+
+	for i := range s.rates {
+		s.rates[i].Store(math.Float64bits(1))
+	}
+
+	sampler.Store(s)
+}
+
+// adaptiveSample returns the rate at which logs with the specified flag are sampled and whether this log is sampled
+func adaptiveSample(ctx context.Context, flag OutputMask) (float64, bool) {
+	s := sampler.Load()
+
+	if s == nil || flag&(OutputFlagFatal|OutputFlagAudit) != 0 || Verbose(ctx) {
+		return 1, true
+	}
+
+	s.roll(timeNow().Unix())
+
+	i := bits.TrailingZeros64(uint64(flag))
+	s.arrived[i].Add(1)
+
+	rate := math.Float64frombits(s.rates[i].Load())
+
+	return rate, rate >= 1 || rand.Float64() < rate
+}
+
+// roll adjusts the rates of each severity once the window has ended, according to the logs that arrived in it
+func (s *adaptiveSampler) roll(now int64) {
+	if s.window.Load() == now {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	window := s.window.Load()
+
+	if window == now { // another goroutine adjusted the rates while this one waited
+		return
+	}
+
+	elapsed := float64(now - window)
+
+	if elapsed < 1 { // the clock went backwards, so the window is restarted
+		elapsed = 1
+	}
+
+	remaining := s.target
+
+	for _, sv := range sortedSeverities() {
+		i := bits.TrailingZeros64(uint64(sv.flag))
+		s.observed[i] = s.observed[i]/2 + float64(s.arrived[i].Swap(0))/elapsed/2
+
+		rate := 1.0
+
+		if sv.flag&(OutputFlagFatal|OutputFlagAudit) == 0 && s.observed[i] > remaining {
+			rate = math.Max(remaining, 1) / s.observed[i]
+		}
+
+		remaining -= s.observed[i] * rate
+		s.rates[i].Store(math.Float64bits(rate))
+	}
+
+	s.window.Store(now)
+}
+
+// String returns the rate to three significant figures
+func (r sampleRate) String() string {
+	return strconv.FormatFloat(float64(r), 'g', 3, 64)
+}
@@ -0,0 +1,58 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveSampling(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() {
+		SetAdaptiveSampling(0)
+		timeNow = time.Now
+	}()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	ctx := context.Background()
+
+	SetAdaptiveSampling(100)
+
+	for second := 0; second < 10; second++ {
+		sb.Reset()
+		now = now.Add(time.Second)
+
+		for i := 0; i < 1000; i++ {
+			l.Debug(ctx, "debugged")
+		}
+
+		for i := 0; i < 50; i++ {
+			l.Error(ctx, "failed", nil)
+		}
+	}
+
+	output := sb.String()
+
+	if errors := strings.Count(output, `severity="ERROR"`); errors != 50 || strings.Contains(output, `sample_rate=0.05 message="failed"`) {
+		t.Fatalf("expected no error logs to be sampled but got %v", errors)
+	}
+
+	if debugs := strings.Count(output, "DEBUG"); debugs < 20 || debugs > 90 || !strings.Contains(output, `sample_rate=0.05`) {
+		t.Fatalf("expected debug logs to be sampled at 0.05, to meet the target, but got %v: %v", debugs, output[:200])
+	}
+
+	sb.Reset()
+	l.Debug(WithVerbose(ctx), "verbose")
+
+	if !strings.Contains(sb.String(), `message="verbose"`) || strings.Contains(sb.String(), SampleRateLabel) {
+		t.Fatalf("expected the logs of verbose traces not to be sampled but got '%v'", sb.String())
+	}
+}
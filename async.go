@@ -0,0 +1,277 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AsyncWriter is an io.Writer that queues logs to be written to its underlying Writer by a background goroutine,
+// removing the latency of the write from the goroutine writing the log.
+//
+// Logs with a severity included in the priority mask are queued in a priority lane, which is always written ahead of
+// other logs. Should the normal lane be full, further logs are dropped rather than block; logs in the priority lane
+// are never dropped, instead the writer blocks until there is space to queue them. As logs are written whilst holding
+// the write lock shared by all Logs, every log blocks while the priority lane is full, not only those queued in it, so
+// it should be sized for bursts of priority logs.
+type AsyncWriter struct {
+	w          io.Writer
+	priority   chan []byte
+	normal     chan []byte
+	priorities OutputMask
+	dropped    atomic.Uint64
+	closeMx    sync.RWMutex
+	closed     bool
+	stop       chan struct{}
+	stopped    chan struct{}
+	pendingMx  sync.Mutex
+	pending    int
+	idle       []chan struct{}
+	dropPolicy DropPolicy
+	summary    time.Duration
+	reported   uint64 // the number of dropped logs included in summaries, only accessed by the background goroutine
+}
+
+// DropPolicy defines which logs an AsyncWriter drops when its normal lane is full
+type DropPolicy int
+
+// Supported DropPolicies
+const (
+	// DropNewest drops the log being written, retaining those already queued
+	DropNewest DropPolicy = iota
+	// DropOldest drops the oldest queued log to make space for the log being written, favouring the most recent logs
+	DropOldest
+)
+
+// NewAsyncWriter creates an AsyncWriter that writes to w. Each lane can queue up to size logs.
+// Error, Fatal and Audit logs are queued in the priority lane.
+func NewAsyncWriter(w io.Writer, size int) *AsyncWriter {
+	return NewPriorityAsyncWriter(w, size, OutputFlagError|OutputFlagFatal|OutputFlagAudit)
+}
+
+// NewPriorityAsyncWriter creates an AsyncWriter that writes to w, queueing logs with a severity included in priorities
+// in the priority lane. Each lane can queue up to size logs.
+func NewPriorityAsyncWriter(w io.Writer, size int, priorities OutputMask) *AsyncWriter {
+	aw := &AsyncWriter{
+		w:          w,
+		priority:   make(chan []byte, size),
+		normal:     make(chan []byte, size),
+		priorities: priorities,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go aw.run()
+
+	return aw
+}
+
+// NewNonBlockingWriter creates an AsyncWriter that writes to w and never blocks the goroutine writing a log. It has
+// no priority lane, so when the queue of up to size logs is full, logs of any severity are dropped according to the policy.
+//
+// If summary is greater than zero, then at that interval, should any logs have been dropped since the last summary, a
+// warning log with a `dropped_logs` label of the number dropped is written to w, ahead of any queued logs. The summary is
+// encoded with the format and labels of the default logger, and written directly to w.
+func NewNonBlockingWriter(w io.Writer, size int, policy DropPolicy, summary time.Duration) *AsyncWriter {
+	aw := &AsyncWriter{
+		w:          w,
+		priority:   make(chan []byte),
+		normal:     make(chan []byte, size),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		dropPolicy: policy,
+		summary:    summary,
+	}
+
+	go aw.run()
+
+	return aw
+}
+
+// Write queues b in the normal lane
+func (aw *AsyncWriter) Write(b []byte) (int, error) {
+	return aw.WriteSeverity(OutputFlagNone, b)
+}
+
+// WriteSeverity queues b in the lane for the severity of the log
+func (aw *AsyncWriter) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	aw.closeMx.RLock()
+	defer aw.closeMx.RUnlock()
+
+	if aw.closed { // logs written after Close are written synchronously rather than lost
+		return aw.w.Write(b)
+	}
+
+	b = append([]byte(nil), b...) // the caller may reuse b once Write returns
+
+	aw.setPending(1)
+
+	if aw.priorities&flag != 0 {
+		aw.priority <- b
+		return len(b), nil
+	}
+
+	for {
+		select {
+		case aw.normal <- b:
+			return len(b), nil
+		default:
+		}
+
+		if aw.dropPolicy == DropNewest {
+			aw.dropped.Add(1)
+			aw.setPending(-1)
+
+			return len(b), nil
+		}
+
+		select { // make space by dropping the oldest log, unless the background goroutine has already done so
+		case <-aw.normal:
+			aw.dropped.Add(1)
+			aw.setPending(-1)
+		default:
+		}
+	}
+}
+
+// Unwrap returns the underlying Writer
+func (aw *AsyncWriter) Unwrap() io.Writer {
+	return aw.w
+}
+
+// Dropped returns the number of logs dropped because the normal lane was full
+func (aw *AsyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
+// Flush blocks until all queued logs have been written or ctx is done
+func (aw *AsyncWriter) Flush(ctx context.Context) error {
+	aw.pendingMx.Lock()
+
+	if aw.pending == 0 {
+		aw.pendingMx.Unlock()
+		return nil
+	}
+
+	idle := make(chan struct{})
+	aw.idle = append(aw.idle, idle)
+	aw.pendingMx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes any queued logs and stops the background goroutine. Logs written after Close are written synchronously.
+// If the underlying Writer is an io.Closer, it is not closed.
+func (aw *AsyncWriter) Close() error {
+	aw.closeMx.Lock()
+
+	if aw.closed {
+		aw.closeMx.Unlock()
+		return nil
+	}
+
+	aw.closed = true
+	close(aw.stop)
+	aw.closeMx.Unlock()
+
+	<-aw.stopped
+
+	return nil
+}
+
+// SetAsync sets the Writer of the default logger to an AsyncWriter that writes to its current Writer, with lanes
+// that can each queue up to size logs. This removes the latency of writing from the goroutines writing logs.
+//
+// Call Close before the process exits, such as with a defer in main, so that queued logs are not lost.
+// This operation is safe for concurrent use.
+func SetAsync(size int) {
+	configure(func(l *Log) { l.Writer = NewAsyncWriter(l.Writer, size) })
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.stopped)
+
+	var tick <-chan time.Time
+
+	if aw.summary > 0 {
+		ticker := time.NewTicker(aw.summary)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select { // always empty the priority lane first
+		case b := <-aw.priority:
+			aw.write(b)
+			continue
+		default:
+		}
+
+		select {
+		case b := <-aw.priority:
+			aw.write(b)
+		case b := <-aw.normal:
+			aw.write(b)
+		case <-tick:
+			aw.summarise()
+		case <-aw.stop:
+			for {
+				select {
+				case b := <-aw.priority:
+					aw.write(b)
+				case b := <-aw.normal:
+					aw.write(b)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// summarise writes a warning with the number of logs dropped since the last summary, if any have been. It is encoded
+// and written directly, as a Write blocked on a full priority lane holds the write lock
+func (aw *AsyncWriter) summarise() {
+	dropped := aw.dropped.Load()
+
+	if dropped == aw.reported {
+		return
+	}
+
+	n, l := int(dropped-aw.reported), defaultLog.Load()
+	aw.reported = dropped
+
+	if !l.writes(context.Background(), OutputFlagWarning) {
+		return
+	}
+
+	b, _, _ := l.encode(nil, l.record(context.Background(), OutputFlagWarning, "WARNING", "logs dropped", nil, []any{"dropped_logs", n}))
+	aw.w.Write(b)
+}
+
+func (aw *AsyncWriter) write(b []byte) {
+	aw.w.Write(b)
+	aw.setPending(-1)
+}
+
+func (aw *AsyncWriter) setPending(delta int) {
+	aw.pendingMx.Lock()
+	defer aw.pendingMx.Unlock()
+
+	aw.pending += delta
+
+	if aw.pending == 0 {
+		for _, idle := range aw.idle {
+			close(idle)
+		}
+
+		aw.idle = nil
+	}
+}
@@ -0,0 +1,175 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type gatedWriter struct {
+	gate chan struct{}
+	mx   sync.Mutex
+	logs []string
+}
+
+func (gw *gatedWriter) Write(b []byte) (int, error) {
+	<-gw.gate
+
+	gw.mx.Lock()
+	defer gw.mx.Unlock()
+	gw.logs = append(gw.logs, string(b))
+
+	return len(b), nil
+}
+
+func TestAsyncWriter(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	aw := NewAsyncWriter(gw, 2)
+
+	aw.WriteSeverity(OutputFlagDebug, []byte("debug1")) // taken by the background goroutine and blocked on the gate
+	time.Sleep(10 * time.Millisecond)
+
+	aw.WriteSeverity(OutputFlagDebug, []byte("debug2"))
+	aw.WriteSeverity(OutputFlagDebug, []byte("debug3"))
+	aw.WriteSeverity(OutputFlagDebug, []byte("dropped"))
+	aw.WriteSeverity(OutputFlagError, []byte("error1"))
+	aw.WriteSeverity(OutputFlagFatal, []byte("fatal1"))
+
+	if aw.Dropped() != 1 {
+		t.Fatalf("expected 1 dropped log but got %v", aw.Dropped())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := aw.Flush(ctx); err == nil {
+		t.Fatalf("expected flush to time out whilst the writer is blocked")
+	}
+
+	close(gw.gate)
+
+	if err := aw.Flush(context.Background()); err != nil {
+		t.Fatalf("expected flush to complete but got '%v'", err)
+	}
+
+	if logs := strings.Join(gw.logs, ","); logs != "debug1,error1,fatal1,debug2,debug3" {
+		t.Fatalf("expected priority logs to be written first but got '%v'", logs)
+	}
+
+	aw.Close()
+	aw.WriteSeverity(OutputFlagDebug, []byte("closed"))
+
+	if logs := strings.Join(gw.logs, ","); !strings.HasSuffix(logs, ",closed") {
+		t.Fatalf("expected logs written after close to be written synchronously but got '%v'", logs)
+	}
+}
+
+func TestSetAsync(t *testing.T) {
+	defer resetDefaultLog()()
+
+	gw := &gatedWriter{gate: make(chan struct{})}
+	SetWriter(gw)
+	SetOutputFormat(FormatLogfmt)
+	SetAsync(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	Info(ctx, "queued")
+
+	if err := Flush(ctx); err == nil {
+		t.Fatalf("expected flush to time out whilst the writer is blocked")
+	}
+
+	close(gw.gate)
+
+	if err := Close(); err != nil {
+		t.Fatalf("expected close to drain the queue but got '%v'", err)
+	}
+
+	Info(context.Background(), "synchronous")
+
+	if logs := strings.Join(gw.logs, ""); !strings.Contains(logs, `message="queued"`) || !strings.Contains(logs, `message="synchronous"`) {
+		t.Fatalf("expected queued logs to be written on close but got '%v'", logs)
+	}
+}
+
+func TestFatalDrainsAsyncWriter(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	close(gw.gate)
+
+	l := New(OutputMaskAll, false)
+	l.Writer, l.FatalFunc = NewAsyncWriter(gw, 10), func() {}
+
+	l.Info(context.Background(), "queued")
+	l.Fatal(context.Background(), "fatal", nil)
+
+	if logs := strings.Join(gw.logs, ""); !strings.Contains(logs, `message="queued"`) || !strings.Contains(logs, `message="fatal"`) {
+		t.Fatalf("expected queued logs to be written before the fatal func is called but got '%v'", logs)
+	}
+}
+
+func TestNonBlockingWriter(t *testing.T) {
+	type testCase struct {
+		Policy   DropPolicy
+		Expected string
+	}
+
+	for name, tc := range map[string]testCase{
+		"drop newest": {Policy: DropNewest, Expected: "log1,log2,log3"},
+		"drop oldest": {Policy: DropOldest, Expected: "log1,log4,log5"},
+	} {
+		gw := &gatedWriter{gate: make(chan struct{})}
+		aw := NewNonBlockingWriter(gw, 2, tc.Policy, 0)
+
+		aw.WriteSeverity(OutputFlagDebug, []byte("log1")) // taken by the background goroutine and blocked on the gate
+		time.Sleep(10 * time.Millisecond)
+
+		for _, log := range []string{"log2", "log3", "log4"} {
+			aw.WriteSeverity(OutputFlagDebug, []byte(log))
+		}
+
+		aw.WriteSeverity(OutputFlagFatal, []byte("log5"))
+
+		if aw.Dropped() != 2 {
+			t.Fatalf("%v: expected 2 dropped logs but got %v", name, aw.Dropped())
+		}
+
+		close(gw.gate)
+		aw.Close()
+
+		if logs := strings.Join(gw.logs, ","); logs != tc.Expected {
+			t.Fatalf("%v: expected logs '%v' but got '%v'", name, tc.Expected, logs)
+		}
+	}
+}
+
+func TestDroppedLogsSummary(t *testing.T) {
+	SetOutputFormat(FormatLogfmt)
+	defer SetOutputFormat(FormatJSON)
+
+	gw := &gatedWriter{gate: make(chan struct{})}
+	aw := NewNonBlockingWriter(gw, 1, DropNewest, 20*time.Millisecond)
+
+	aw.Write([]byte("log1\n"))
+	time.Sleep(10 * time.Millisecond)
+	aw.Write([]byte("log2\n"))
+	aw.Write([]byte("dropped\n"))
+
+	mx.Lock() // the summary is written regardless of the write lock, which a Write blocked on the priority lane would hold
+	close(gw.gate)
+	time.Sleep(50 * time.Millisecond)
+
+	gw.mx.Lock()
+	logs := strings.Join(gw.logs, "")
+	gw.mx.Unlock()
+	mx.Unlock()
+
+	aw.Close()
+
+	if strings.Count(logs, "dropped_logs=1 ") != 1 || strings.Contains(logs, "dropped\n") {
+		t.Fatalf("expected a single summary of the dropped log but got '%v'", logs)
+	}
+}
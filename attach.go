@@ -0,0 +1,148 @@
+package qlog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Attach adds a Writer to the default logger that receives the logs with a severity included in the OutputMask,
+// returning a func that detaches it. If the Writer of the default logger is not already a Router, it is replaced with
+// one that routes all logs to it, along with the attached Writer. Detaching does not close the Writer.
+//
+// The logs written are still limited by the OutputMask of the default logger, so to capture more detailed logs, raise
+// its verbosity as well, such as with LevelHandler.
+// This operation is safe for concurrent use.
+func Attach(w io.Writer, m OutputMask) (detach func()) {
+	var r *Router
+
+	configure(func(l *Log) {
+		if rt, ok := l.Writer.(*Router); ok {
+			r = rt
+			return
+		}
+
+		r = NewRouter(Route{Writer: l.Writer, Mask: ^OutputFlagNone})
+		l.Writer = r
+	})
+
+	return r.Attach(Route{Writer: w, Mask: m})
+}
+
+// SinkConfig is the representation of a file attached to the default logger by SinkHandler
+type SinkConfig struct {
+	// Name is the name of the file within the directory of the SinkHandler
+	Name string `json:"name"`
+	// Mask is the OutputMask of the logs written to the file in the format accepted by ParseOutputMask
+	Mask string `json:"mask"`
+}
+
+// SinkHandler returns a http.Handler that allows files to be attached to, and detached from, the default logger at
+// runtime, such as to capture logs to a debug file during an incident, without a restart.
+//
+// A GET request returns the attached files as a JSON array of SinkConfig. A POST request with a SinkConfig as its JSON
+// body creates, or appends to, the named file within dir and attaches it with the mask. A DELETE request with a `name`
+// query parameter detaches and closes the named file. Only the base name of each file is used, so files cannot be
+// created outside of dir. Each change is recorded with an Audit log.
+//
+// For example:
+//
+//	curl -X POST -d '{"name":"incident.log","mask":"all"}' http://localhost:8081/log/sinks
+//	curl -X DELETE http://localhost:8081/log/sinks?name=incident.log
+//
+// If authorize is not nil, it is called for each request. If it returns false, the request is rejected with a 403
+func SinkHandler(authorize func(r *http.Request) bool, dir string) http.Handler {
+	type sink struct {
+		file   *os.File
+		mask   OutputMask
+		detach func()
+	}
+
+	sinksMx, sinks := sync.Mutex{}, map[string]sink{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize != nil && !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		sinksMx.Lock()
+		defer sinksMx.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			rq := SinkConfig{}
+
+			if err := json.NewDecoder(r.Body).Decode(&rq); err != nil {
+				http.Error(w, "invalid sink config: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			name := filepath.Base(rq.Name)
+
+			if name == "." || name == ".." || name == string(filepath.Separator) {
+				http.Error(w, "invalid sink name: "+rq.Name, http.StatusBadRequest)
+				return
+			}
+
+			if _, ok := sinks[name]; ok {
+				http.Error(w, "sink already attached: "+name, http.StatusConflict)
+				return
+			}
+
+			m, err := ParseOutputMask(rq.Mask)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			sinks[name] = sink{file: f, mask: m, detach: Attach(f, m)}
+
+			Audit(r.Context(), "log sink attached", "name", name, "mask", m.String(), "remote_addr", r.RemoteAddr)
+		case http.MethodDelete:
+			name := filepath.Base(r.URL.Query().Get("name"))
+			s, ok := sinks[name]
+
+			if !ok {
+				http.Error(w, "sink not attached: "+name, http.StatusNotFound)
+				return
+			}
+
+			mx.Lock() // so that no log is being written to the file as it is detached and closed
+			s.detach()
+			s.file.Close()
+			mx.Unlock()
+			delete(sinks, name)
+
+			Audit(r.Context(), "log sink detached", "name", name, "remote_addr", r.RemoteAddr)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		configs := []SinkConfig{}
+
+		for name, s := range sinks {
+			configs = append(configs, SinkConfig{Name: name, Mask: s.mask.String()})
+		}
+
+		sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(configs)
+	})
+}
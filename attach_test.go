@@ -0,0 +1,88 @@
+package qlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	defer resetDefaultLog()()
+
+	primary, attached := strings.Builder{}, strings.Builder{}
+	SetWriter(&primary)
+
+	detach := Attach(&attached, OutputFlagError)
+
+	Info(context.Background(), "info1")
+	Error(context.Background(), "error1", nil)
+	detach()
+	Error(context.Background(), "error2", nil)
+
+	if strings.Count(primary.String(), "\n") != 3 {
+		t.Fatalf("expected all logs to be written to the primary writer but got '%v'", primary.String())
+	}
+
+	if logs := attached.String(); strings.Count(logs, "\n") != 1 || !strings.Contains(logs, "error1") {
+		t.Fatalf("expected only errors written whilst attached to be written to the attached writer but got '%v'", logs)
+	}
+}
+
+func TestSinkHandler(t *testing.T) {
+	defer resetDefaultLog()()
+
+	dir := t.TempDir()
+	SetWriter(&strings.Builder{})
+
+	h := SinkHandler(nil, dir)
+
+	for _, tc := range []struct {
+		Desc           string
+		Method         string
+		Target         string
+		Body           string
+		ExpectedStatus int
+		ExpectedSinks  string
+	}{
+		{Desc: "none attached", Method: http.MethodGet, Target: "/", ExpectedStatus: http.StatusOK, ExpectedSinks: "[]"},
+		{Desc: "invalid mask", Method: http.MethodPost, Target: "/", Body: `{"name":"debug.log","mask":"verbose"}`, ExpectedStatus: http.StatusBadRequest},
+		{Desc: "attach", Method: http.MethodPost, Target: "/", Body: `{"name":"../../debug.log","mask":"error"}`, ExpectedStatus: http.StatusOK, ExpectedSinks: `[{"name":"debug.log","mask":"error"}]`},
+		{Desc: "already attached", Method: http.MethodPost, Target: "/", Body: `{"name":"debug.log","mask":"all"}`, ExpectedStatus: http.StatusConflict},
+		{Desc: "detach", Method: http.MethodDelete, Target: "/?name=debug.log", ExpectedStatus: http.StatusOK, ExpectedSinks: "[]"},
+		{Desc: "not attached", Method: http.MethodDelete, Target: "/?name=debug.log", ExpectedStatus: http.StatusNotFound},
+	} {
+		rs := httptest.NewRecorder()
+		h.ServeHTTP(rs, httptest.NewRequest(tc.Method, tc.Target, strings.NewReader(tc.Body)))
+
+		if rs.Code != tc.ExpectedStatus {
+			t.Fatalf("%v: expected status %v but got %v (%v)", tc.Desc, tc.ExpectedStatus, rs.Code, rs.Body.String())
+		}
+
+		if tc.ExpectedStatus != http.StatusOK {
+			continue
+		}
+
+		sinks := []SinkConfig{}
+
+		if err := json.NewDecoder(rs.Body).Decode(&sinks); err != nil {
+			t.Fatalf("%v: expected sinks but got '%v'", tc.Desc, err)
+		}
+
+		if b, _ := json.Marshal(sinks); string(b) != tc.ExpectedSinks {
+			t.Fatalf("%v: expected sinks '%v' but got '%v'", tc.Desc, tc.ExpectedSinks, string(b))
+		}
+
+		if tc.Desc == "attach" {
+			Error(context.Background(), "captured", nil)
+		}
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dir, "debug.log")); err != nil || !strings.Contains(string(b), `"message": "captured"`) {
+		t.Fatalf("expected the log written whilst attached to be captured in the file but got '%v' (%v)", string(b), err)
+	}
+}
@@ -0,0 +1,123 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ParseB3 parses a Zipkin B3 single header, such as `80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1`, returning
+// the Trace-ID, the Span-ID of the remote span and whether the remote span sampled the trace. Headers that carry only
+// a sampling decision, such as `0`, have no trace so are rejected
+func ParseB3(header string) (traceID, spanID string, sampled bool, err error) {
+	fields := strings.Split(strings.ToLower(strings.TrimSpace(header)), "-")
+
+	if len(fields) < 2 || len(fields) > 4 {
+		return "", "", false, fmt.Errorf("invalid b3: '%v' is not in the format traceid-spanid-sampled-parentspanid", header)
+	}
+
+	sampling := ""
+
+	if len(fields) > 2 {
+		sampling = fields[2]
+	}
+
+	return parseB3(fields[0], fields[1], sampling)
+}
+
+// parseB3 validates the IDs and sampling decision of either a B3 single header or B3 multi headers
+func parseB3(traceID, spanID, sampling string) (string, string, bool, error) {
+	if (len(traceID) != 16 && len(traceID) != 32) || !isLowerHex(traceID) || isZeros(traceID) {
+		return "", "", false, fmt.Errorf("invalid b3: invalid trace-id '%v'", traceID)
+	}
+
+	if len(spanID) != 16 || !isLowerHex(spanID) || isZeros(spanID) {
+		return "", "", false, fmt.Errorf("invalid b3: invalid span-id '%v'", spanID)
+	}
+
+	switch sampling {
+	case "", "1", "d", "true": // the absence of a decision defers it to the receiver, which samples all traces
+		return traceID, spanID, true, nil
+	case "0", "false":
+		return traceID, spanID, false, nil
+	}
+
+	return "", "", false, fmt.Errorf("invalid b3: invalid sampling state '%v'", sampling)
+}
+
+// b3Propagator is a Propagator for Zipkin B3 single or B3 multi headers
+type b3Propagator struct {
+	multi bool // whether B3 multi headers are injected, rather than a B3 single header
+}
+
+func (p b3Propagator) Extract(ctx context.Context, h http.Header) context.Context {
+	var (
+		traceID, spanID string
+		sampled         bool
+		err             error
+	)
+
+	if b3 := h.Get("b3"); b3 != "" {
+		traceID, spanID, sampled, err = ParseB3(b3)
+	} else {
+		sampling := strings.ToLower(h.Get("X-B3-Sampled"))
+
+		if h.Get("X-B3-Flags") == "1" { // debug traces are always sampled
+			sampling = "d"
+		}
+
+		traceID, spanID, sampled, err = parseB3(strings.ToLower(h.Get("X-B3-TraceId")), strings.ToLower(h.Get("X-B3-SpanId")), sampling)
+	}
+
+	if err != nil {
+		return ContextFrom(ctx, "")
+	}
+
+	return contextFromRemote(ctx, traceID, spanID, sampled, "")
+}
+
+func (p b3Propagator) Inject(ctx context.Context, h http.Header) {
+	traceID, ok := b3ID(TraceID(ctx), 32)
+
+	if !ok {
+		return
+	}
+
+	spanID, ok := b3ID(SpanID(ctx), 16)
+
+	if !ok {
+		return
+	}
+
+	sampled := "1"
+
+	if unsampled(ctx) {
+		sampled = "0"
+	}
+
+	if !p.multi {
+		h.Set("b3", traceID+"-"+spanID+"-"+sampled)
+		return
+	}
+
+	h.Set("X-B3-TraceId", traceID)
+	h.Set("X-B3-SpanId", spanID)
+	h.Set("X-B3-Sampled", sampled)
+
+	if parentSpanID, ok := b3ID(ParentSpanID(ctx), 16); ok {
+		h.Set("X-B3-ParentSpanId", parentSpanID)
+	}
+}
+
+// b3ID returns id as a lower case hex ID of at most n characters, if it can be represented as one. Trace-IDs that fit
+// in 64 bits are returned as 16 characters, as is conventional for B3
+func b3ID(id string, n int) (string, bool) {
+	id, ok := traceContextID(id, n)
+
+	if ok && n == 32 && strings.HasPrefix(id, "0000000000000000") {
+		id = id[16:]
+	}
+
+	return id, ok
+}
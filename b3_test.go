@@ -0,0 +1,71 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseB3(t *testing.T) {
+	type expected struct {
+		traceID, spanID string
+		sampled, err    bool
+	}
+
+	for header, expected := range map[string]expected{
+		"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90": {traceID: "80f198ee56343ba864fe8b2a57d3eff7", spanID: "e457b5a2e4d86bd1", sampled: true},
+		"64fe8b2a57d3eff7-E457B5A2E4D86BD1-0":                                  {traceID: "64fe8b2a57d3eff7", spanID: "e457b5a2e4d86bd1"},
+		"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d":                  {traceID: "80f198ee56343ba864fe8b2a57d3eff7", spanID: "e457b5a2e4d86bd1", sampled: true},
+		"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1":                    {traceID: "80f198ee56343ba864fe8b2a57d3eff7", spanID: "e457b5a2e4d86bd1", sampled: true},
+		"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-x":                  {err: true},
+		"80f198ee56343ba864fe8b2a57d3-e457b5a2e4d86bd1-1":                      {err: true},
+		"0000000000000000-e457b5a2e4d86bd1-1":                                  {err: true},
+		"0":                                                                    {err: true},
+	} {
+		traceID, spanID, sampled, err := ParseB3(header)
+
+		if (err != nil) != expected.err {
+			t.Fatalf("%v: expected error to be %v but got %v", header, expected.err, err)
+		}
+
+		if traceID != expected.traceID || spanID != expected.spanID || sampled != expected.sampled {
+			t.Fatalf("%v: expected %v, %v, %v but got %v, %v, %v", header, expected.traceID, expected.spanID, expected.sampled, traceID, spanID, sampled)
+		}
+	}
+}
+
+func TestB3Propagator(t *testing.T) {
+	for name, h := range map[string]http.Header{
+		"single": {"B3": {"64fe8b2a57d3eff7-e457b5a2e4d86bd1-0"}},
+		"multi":  {"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}, "X-B3-Sampled": {"0"}},
+	} {
+		ctx := B3Propagator.Extract(context.Background(), h)
+
+		if TraceID(ctx) != "64fe8b2a57d3eff7" || ParentSpanID(ctx) != "e457b5a2e4d86bd1" {
+			t.Fatalf("%v: expected the context to be a child of the remote span but got trace '%v' and parent '%v'", name, TraceID(ctx), ParentSpanID(ctx))
+		}
+
+		single, multi := http.Header{}, http.Header{}
+		B3Propagator.Inject(ctx, single)
+		B3MultiPropagator.Inject(ctx, multi)
+
+		if expected := "64fe8b2a57d3eff7-" + SpanID(ctx) + "-0"; single.Get("b3") != expected {
+			t.Fatalf("%v: expected b3 header '%v' but got '%v'", name, expected, single.Get("b3"))
+		}
+
+		for k, expected := range map[string]string{
+			"X-B3-TraceId":      "64fe8b2a57d3eff7",
+			"X-B3-SpanId":       SpanID(ctx),
+			"X-B3-ParentSpanId": "e457b5a2e4d86bd1",
+			"X-B3-Sampled":      "0",
+		} {
+			if multi.Get(k) != expected {
+				t.Fatalf("%v: expected %v header '%v' but got '%v'", name, k, expected, multi.Get(k))
+			}
+		}
+	}
+
+	if ctx := B3Propagator.Extract(context.Background(), http.Header{"B3": {"0"}}); TraceID(ctx) == "" || ParentSpanID(ctx) != "" {
+		t.Fatalf("expected a b3 header without a trace to start a new trace but got trace '%v' and parent '%v'", TraceID(ctx), ParentSpanID(ctx))
+	}
+}
@@ -0,0 +1,205 @@
+package qlog
+
+import (
+	"context"
+	"time"
+)
+
+// Batch accumulates logs, such as those generated per item by a loop over a bulk operation, so that they can be
+// written together with a single acquisition of the write lock and, where possible, a single call to the Writer.
+//
+// Each log is filtered, sampled and counted against any budgets or quota as it is added to the Batch, as it would be
+// were it written with the Log, then encoded, with any lazy label values evaluated, but nothing is written until Write
+// is called. Any escalations are written once the logs that triggered them are. A Batch is not safe for concurrent use.
+type Batch struct {
+	l           *Log
+	ctx         context.Context
+	b           []byte
+	records     []batchRecord
+	escalations []batchEscalation
+}
+
+// batchRecord locates an encoded log within the buffer of a Batch
+type batchRecord struct {
+	flag       OutputMask
+	start, end int
+	entry      Entry
+	hooked     bool
+}
+
+// batchEscalation is a log added to a Batch that is counted by the escalation rules of its Log once the Batch is written
+type batchEscalation struct {
+	flag    OutputMask
+	message string
+}
+
+// Batch creates a Batch of logs to be written by the Log, each with the specified ctx
+//
+// For example:
+//
+//	batch := logger.Batch(ctx)
+//
+//	for _, item := range items {
+//		batch.Info("item processed", "item", item.ID)
+//	}
+//
+//	batch.Write()
+func (l *Log) Batch(ctx context.Context) *Batch {
+	return &Batch{l: l, ctx: ctx}
+}
+
+// NewBatch creates a Batch of logs to be written by the default logger, each with the specified ctx
+func NewBatch(ctx context.Context) *Batch {
+	return defaultLog.Load().Batch(ctx)
+}
+
+// Error adds a log with error severity to the Batch, see Log.Error
+func (bt *Batch) Error(message string, err error, labels ...any) {
+	bt.add(OutputFlagError, "ERROR", message, err, labels)
+}
+
+// Warning adds a log with warning severity to the Batch, see Log.Warning
+func (bt *Batch) Warning(message string, err error, labels ...any) {
+	bt.add(OutputFlagWarning, "WARNING", message, err, labels)
+}
+
+// Notice adds a log with notice severity to the Batch, see Log.Notice
+func (bt *Batch) Notice(message string, labels ...any) {
+	bt.add(OutputFlagNotice, "NOTICE", message, nil, labels)
+}
+
+// NoticeError adds a log with notice severity and the specified error to the Batch, see Log.NoticeError
+func (bt *Batch) NoticeError(message string, err error, labels ...any) {
+	bt.add(OutputFlagNotice, "NOTICE", message, err, labels)
+}
+
+// Info adds a log with info severity to the Batch, see Log.Info
+func (bt *Batch) Info(message string, labels ...any) {
+	bt.add(OutputFlagInfo, "INFO", message, nil, labels)
+}
+
+// Len returns the number of logs in the Batch waiting to be written
+func (bt *Batch) Len() int {
+	return len(bt.records)
+}
+
+// Write writes all the logs in the Batch and empties it, so that it can be reused. Where the Writer of the Log is a
+// SeverityWriter or EntryWriter, it is called once per log so that each can be routed by its severity, or written in
+// its own format, otherwise it is called once
+func (bt *Batch) Write() {
+	defer bt.escalate() // the escalations are written after the logs that triggered them
+
+	if len(bt.records) == 0 {
+		return
+	}
+
+	for _, r := range bt.records {
+		if r.flag&TailFlushMask != 0 {
+			flushTail(r.entry.TraceID)
+		}
+	}
+
+	l := bt.l
+	_, perRecord := l.Writer.(SeverityWriter)
+	perRecord = perRecord || wantsEntry(l.Writer)
+	failed, written := make([]error, len(bt.records)), make([]int, len(bt.records))
+
+	mx.Lock()
+
+	if perRecord {
+		for i, r := range bt.records {
+			written[i], failed[i] = l.write(r.flag, r.entry, bt.b[r.start:r.end])
+		}
+	} else {
+		n, err := l.write(OutputFlagNone, Entry{}, bt.b)
+
+		for i, r := range bt.records { // each log is attributed the bytes of it that were written, should the write be short
+			written[i], failed[i] = n-r.start, err
+
+			if written[i] < 0 {
+				written[i] = 0
+			} else if written[i] > r.end-r.start {
+				written[i] = r.end - r.start
+			}
+		}
+	}
+
+	for i, r := range bt.records {
+		countWritten(r.flag, failed[i])
+		publish(r.flag, bt.b[r.start:r.end])
+		capture(r.entry.Context, bt.b[r.start:r.end])
+	}
+
+	mx.Unlock()
+
+	for i, r := range bt.records {
+		l.count()
+
+		if failed[i] != nil && l.onWriteError != nil {
+			l.onWriteError(failed[i], bt.b[r.start:r.end])
+		}
+
+		if r.hooked {
+			l.callHooks(r.flag, r.entry, written[i], failed[i])
+		}
+	}
+
+	bt.b, bt.records = bt.b[:0], bt.records[:0]
+}
+
+// add encodes a log and adds it to the Batch, should it pass the filters of the Log and be admitted, as a log written
+// with the Log would be
+func (bt *Batch) add(flag OutputMask, severity, message string, err error, labels []any) {
+	bt.addAt(time.Time{}, flag, severity, message, err, labels)
+}
+
+// addAt adds a log to the Batch, see add, with the timestamp at, unless it is zero, rather than the current time
+func (bt *Batch) addAt(at time.Time, flag OutputMask, severity, message string, err error, labels []any) {
+	l := bt.l
+
+	if !l.writes(bt.ctx, flag) {
+		return
+	}
+
+	rec, ok := l.filter(bt.ctx, flag, severity, message, err, labels)
+
+	if !ok {
+		return
+	}
+
+	if !at.IsZero() {
+		rec.Time = at
+	}
+
+	if len(l.escalations) > 0 {
+		bt.escalations = append(bt.escalations, batchEscalation{flag: flag, message: message})
+	}
+
+	if !l.admit(&rec) {
+		return
+	}
+
+	r := batchRecord{flag: flag, start: len(bt.b)}
+	bt.b, r.entry, r.hooked = l.encode(bt.b, rec)
+	r.end = len(bt.b)
+
+	if l.withhold(bt.ctx, flag, r.entry, bt.b[r.start:r.end], r.hooked) {
+		bt.b = bt.b[:r.start]
+		return
+	}
+
+	if !r.hooked && !wantsEntry(l.destination(flag)) {
+		r.entry = Entry{Context: r.entry.Context, TraceID: r.entry.TraceID, Flag: flag} // only the Context, Trace-ID and Flag are required, so the labels are not retained
+	}
+
+	bt.records = append(bt.records, r)
+}
+
+// escalate counts the logs of the Batch with the escalation rules of its Log, writing any escalations
+func (bt *Batch) escalate() {
+	for _, e := range bt.escalations {
+		bt.l.escalate(bt.ctx, e.flag, e.message)
+	}
+
+	bt.escalations = bt.escalations[:0]
+}
@@ -0,0 +1,125 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	cw.writes++
+	return cw.Buffer.Write(b)
+}
+
+func TestBatch(t *testing.T) {
+	cw := &countingWriter{}
+	l := New(MinSeverityMask(SeverityInfo), false)
+	l.Writer = cw
+
+	batch := l.Batch(context.Background())
+
+	for _, item := range []string{"a", "b", "c"} {
+		batch.Info("item processed", "item", item)
+	}
+
+	batch.Debug("excluded by the mask")
+	batch.Error("item failed", errors.New("test error"), "item", "d")
+
+	if batch.Len() != 4 {
+		t.Fatalf("expected 4 logs in the batch but got %v", batch.Len())
+	}
+
+	if cw.writes != 0 {
+		t.Fatalf("expected no writes before the batch is written but got %v", cw.writes)
+	}
+
+	batch.Write()
+
+	if cw.writes != 1 {
+		t.Fatalf("expected a single write of the batch but got %v", cw.writes)
+	}
+
+	if logs := cw.String(); strings.Count(logs, "\n") != 4 || !strings.Contains(logs, `item="c"`) || !strings.Contains(logs, `severity="ERROR"`) {
+		t.Fatalf("expected the batched logs to be written but got '%v'", logs)
+	}
+
+	if batch.Len() != 0 {
+		t.Fatalf("expected the batch to be empty once written but got %v", batch.Len())
+	}
+
+	batch.Notice("reused")
+	batch.Write()
+
+	if cw.writes != 2 || !strings.Contains(cw.String(), `message="reused"`) {
+		t.Fatalf("expected the batch to be reusable but got %v writes of '%v'", cw.writes, cw.String())
+	}
+
+	cw.Reset()
+	batch.NoticeError("retried", errors.New("transient"))
+	batch.Write()
+
+	if logs := cw.String(); !strings.Contains(logs, `severity="NOTICE"`) || !strings.Contains(logs, `error="transient"`) {
+		t.Fatalf("expected a notice log with the error to be written but got '%v'", logs)
+	}
+}
+
+func TestBatchSeverityWriter(t *testing.T) {
+	info, errs := &countingWriter{}, &countingWriter{}
+
+	l := New(OutputMaskAll, false)
+	l.Writer = NewRouter(Route{Writer: info, Mask: OutputFlagInfo}, Route{Writer: errs, Mask: OutputFlagError})
+
+	batch := l.Batch(context.Background())
+	batch.Info("info1")
+	batch.Error("error1", nil)
+	batch.Info("info2")
+	batch.Write()
+
+	if info.writes != 2 || errs.writes != 1 || strings.Contains(info.String(), "error1") {
+		t.Fatalf("expected the batched logs to be routed by severity but got '%v' and '%v'", info.String(), errs.String())
+	}
+}
+
+func TestBatchAdmission(t *testing.T) {
+	cw := &countingWriter{}
+	l := New(OutputMaskAll, false).WithWriter(cw)
+
+	if err := l.AddEscalation(EscalationRule{Message: "item failed", Threshold: 2, Window: time.Minute}); err != nil {
+		t.Fatalf("expected a valid rule to be added but got '%v'", err)
+	}
+
+	SetSampleAfter(2, 0)
+	SetTraceQuota(4)
+
+	defer func() {
+		SetSampleAfter(0, 0)
+		SetTraceQuota(0)
+	}()
+
+	batch := l.Batch(ContextFrom(context.Background(), "abc"))
+
+	for i := 0; i < 3; i++ {
+		batch.Info("item processed")
+		batch.Warning("item failed", nil)
+	}
+
+	if batch.Len() != 4 || strings.Contains(cw.String(), EscalatedMessage) {
+		t.Fatalf("expected the logs after the first 2 of each message to be sampled, and nothing escalated before the batch is written, but got %v logs", batch.Len())
+	}
+
+	batch.Write()
+	batch.Info("over quota")
+	batch.Write()
+
+	if logs := cw.String(); strings.Count(logs, `message="item processed"`) != 2 || strings.Count(logs, EscalatedMessage) != 1 || strings.Contains(logs, "over quota") {
+		t.Fatalf("expected the batched logs to be sampled, escalated and limited by the trace quota but got '%v'", logs)
+	}
+}
@@ -0,0 +1,98 @@
+package qlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore is implemented by external storage, such as an object store bucket, that holds label values too large
+// to be written inline in a log
+type BlobStore interface {
+	// Put stores b under the specified name and returns a reference, such as a URL, from which it can be retrieved
+	Put(ctx context.Context, name string, b []byte) (ref string, err error)
+}
+
+// BlobOffload configures the offloading of large label values to a BlobStore. Where a label passed to a log call
+// has one of the Keys and a string or []byte value longer than Threshold bytes, the value is written to the Store and
+// the reference returned by the Store is written in the log in its place. This keeps the log stream lean whilst
+// preserving the full data.
+//
+// Values are stored under the hex encoded SHA-256 hash of their content. Should the Store return an error,
+// the value is written inline as normal
+type BlobOffload struct {
+	// Store receives the values that exceed the Threshold
+	Store BlobStore
+	// Threshold is the length, in bytes, above which values are offloaded
+	Threshold int
+	// Keys are the label keys whose values are eligible to be offloaded, such as `payload`
+	Keys []string
+}
+
+// Sets the BlobOffload used by the default logger; pass nil to write all label values inline
+// This operation is safe for concurrent use.
+func SetBlobOffload(o *BlobOffload) {
+	configure(func(l *Log) { l.BlobOffload = o })
+}
+
+// offload returns a reference to value in the Store if it is eligible to be offloaded, otherwise value is returned unchanged
+func (o *BlobOffload) offload(ctx context.Context, key string, value any) any {
+	eligible := false
+
+	for _, k := range o.Keys {
+		if k == key {
+			eligible = true
+			break
+		}
+	}
+
+	if !eligible {
+		return value
+	}
+
+	value = resolve(value) // so that a lazy value is not evaluated twice
+	var b []byte
+
+	switch v := value.(type) {
+	case string:
+		if len(v) <= o.Threshold {
+			return value
+		}
+
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return value
+	}
+
+	if len(b) <= o.Threshold {
+		return value
+	}
+
+	hash := sha256.Sum256(b)
+	ref, err := o.Store.Put(ctx, hex.EncodeToString(hash[:]), b)
+
+	if err != nil {
+		return value
+	}
+
+	return ref
+}
+
+// DirBlobStore is a BlobStore that writes each value to a file in the named directory, such as a volume that
+// is shipped to external storage. The returned reference is the path of the file
+type DirBlobStore string
+
+// Put writes b to a file with the specified name in the directory
+func (d DirBlobStore) Put(_ context.Context, name string, b []byte) (string, error) {
+	path := filepath.Join(string(d), name)
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
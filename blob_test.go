@@ -0,0 +1,59 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingBlobStore struct{}
+
+func (failingBlobStore) Put(context.Context, string, []byte) (string, error) {
+	return "", errors.New("unavailable")
+}
+
+func TestBlobOffload(t *testing.T) {
+	dir := t.TempDir()
+	payload := strings.Repeat("x", 20)
+
+	type testCase struct {
+		Store    BlobStore
+		Labels   []any
+		Expected string
+	}
+
+	for name, tc := range map[string]testCase{
+		"offloaded": {Store: DirBlobStore(dir), Labels: []any{"payload", payload},
+			Expected: `payload="` + filepath.Join(dir, "d4fc1db665446507dc51b0c9392dd9649291581bfe1b48e241b2b08032b3b647") + `"`},
+		"lazy offloaded": {Store: DirBlobStore(dir), Labels: []any{"payload", func() string { return payload }},
+			Expected: `payload="` + filepath.Join(dir, "d4fc1db665446507dc51b0c9392dd9649291581bfe1b48e241b2b08032b3b647") + `"`},
+		"under threshold": {Store: DirBlobStore(dir), Labels: []any{"payload", "small"}, Expected: `payload="small"`},
+		"other key":       {Store: DirBlobStore(dir), Labels: []any{"body", payload}, Expected: `body="` + payload + `"`},
+		"store failure":   {Store: failingBlobStore{}, Labels: []any{"payload", payload}, Expected: `payload="` + payload + `"`},
+	} {
+		buf := bytes.Buffer{}
+		l := New(OutputMaskAll, false)
+		l.Writer = &buf
+		l.BlobOffload = &BlobOffload{Store: tc.Store, Threshold: 10, Keys: []string{"payload"}}
+
+		l.Info(context.Background(), "test", tc.Labels...)
+
+		if !strings.Contains(buf.String(), " "+tc.Expected+" ") {
+			t.Fatalf("%v: expected log to contain '%v' but got '%v'", name, tc.Expected, buf.String())
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+
+	if len(files) != 1 {
+		t.Fatalf("expected 1 offloaded value but got %v", len(files))
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, files[0].Name())); string(b) != payload {
+		t.Fatalf("expected offloaded value '%v' but got '%s'", payload, b)
+	}
+}
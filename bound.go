@@ -0,0 +1,87 @@
+package qlog
+
+import "context"
+
+// BoundLog is a Log bound to a context.Context, whose methods omit the ctx parameter. The Trace-ID and Span-IDs of the
+// context are resolved once, when the BoundLog is created, rather than for each log, which reduces the cost of each log
+// in handlers that write many of them.
+//
+// A BoundLog has the configuration of its Log at the time it was created, other than its verbosity, so it is intended
+// to be created for, and discarded with, each request or task
+type BoundLog struct {
+	l   *Log
+	ctx context.Context
+}
+
+// boundSpan is the pre-resolved trace of the context of a BoundLog
+type boundSpan struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+}
+
+// For creates a BoundLog that writes logs with the Log, each with the specified ctx
+//
+// For example:
+//
+//	l := logger.For(ctx)
+//
+//	l.Info("order received", "order", order.ID)
+//	l.Info("order placed", "order", order.ID)
+func (l *Log) For(ctx context.Context) *BoundLog {
+	d := *l
+	d.bound = &boundSpan{traceID: l.traceID(ctx), spanID: SpanID(ctx), parentSpanID: ParentSpanID(ctx)}
+
+	return &BoundLog{l: &d, ctx: ctx}
+}
+
+// For creates a BoundLog that writes logs with the default logger, each with the specified ctx
+// This operation is safe for concurrent use.
+func For(ctx context.Context) *BoundLog {
+	return defaultLog.Load().For(ctx)
+}
+
+// Context returns the context.Context the BoundLog is bound to
+func (bl *BoundLog) Context() context.Context {
+	return bl.ctx
+}
+
+// Fatal writes a log with fatal severity and terminates the process, see Log.Fatal
+func (bl *BoundLog) Fatal(message string, err error, labels ...any) {
+	bl.l.Fatal(bl.ctx, message, err, labels...)
+}
+
+// Error writes a log with error severity, see Log.Error
+func (bl *BoundLog) Error(message string, err error, labels ...any) {
+	bl.l.Error(bl.ctx, message, err, labels...)
+}
+
+// Warning writes a log with warning severity, see Log.Warning
+func (bl *BoundLog) Warning(message string, err error, labels ...any) {
+	bl.l.Warning(bl.ctx, message, err, labels...)
+}
+
+// Notice writes a log with notice severity, see Log.Notice
+func (bl *BoundLog) Notice(message string, labels ...any) {
+	bl.l.Notice(bl.ctx, message, labels...)
+}
+
+// NoticeError writes a log with notice severity and the specified error, see Log.NoticeError
+func (bl *BoundLog) NoticeError(message string, err error, labels ...any) {
+	bl.l.NoticeError(bl.ctx, message, err, labels...)
+}
+
+// Info writes a log with info severity, see Log.Info
+func (bl *BoundLog) Info(message string, labels ...any) {
+	bl.l.Info(bl.ctx, message, labels...)
+}
+
+// Audit writes a log with audit severity, see Log.Audit
+func (bl *BoundLog) Audit(message string, labels ...any) {
+	bl.l.Audit(bl.ctx, message, labels...)
+}
+
+// Custom writes a log with a custom Severity registered with RegisterSeverity, see Log.Custom
+func (bl *BoundLog) Custom(s Severity, message string, labels ...any) {
+	bl.l.Custom(bl.ctx, s, message, labels...)
+}
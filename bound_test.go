@@ -0,0 +1,49 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	w := &strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(w)
+	ctx := ContextFrom(ContextFrom(context.Background(), ""), "")
+	calls := 0
+
+	l.TraceID = func(ctx context.Context) string {
+		calls++
+		return TraceID(ctx)
+	}
+
+	bl := l.For(ctx)
+
+	bl.Info("bound", "key", "value")
+	bl.Warning("bound", nil)
+	bl.Debug("bound")
+
+	expected := `trace="` + TraceID(ctx) + `" span="` + SpanID(ctx) + `" parent_span="` + ParentSpanID(ctx) + `" severity=`
+
+	for i, log := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+		if !strings.HasPrefix(log, expected) || !strings.Contains(log, `message="bound"`) {
+			t.Fatalf("expected log %v to start '%v' but got '%v'", i, expected, log)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the trace id to be resolved once but it was resolved %v times", calls)
+	}
+
+	if bl.Context() != ctx {
+		t.Fatalf("expected the bound context to be returned")
+	}
+
+	w.Reset()
+	l.SetOutputMask(OutputFlagError)
+	bl.Info("unwritten")
+
+	if w.Len() != 0 {
+		t.Fatalf("expected the bound log to follow changes to the verbosity of its log but got '%v'", w.String())
+	}
+}
@@ -0,0 +1,220 @@
+package qlog
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// budgetExempt is the type of the context key that marks a log as exempt from any budget, quota, sampling or filter
+type budgetExempt struct{}
+
+var budgetExemptKey = budgetExempt{}
+
+// exempt reports whether the log of ctx is exempt from being filtered or shed, as are those qlog writes to summarise
+// logs that may have been, such as the heartbeat
+func exempt(ctx context.Context) bool {
+	return ctx.Value(budgetExemptKey) != nil
+}
+
+// fileLine identifies a call site, the same call site may have more than one pc where it is inlined
+type fileLine struct {
+	file string
+	line int
+}
+
+// callSiteBudget counts the logs written by a call site in the current one second window
+type callSiteBudget struct {
+	window    int64
+	count     int64
+	throttled bool
+}
+
+var (
+	budget    = atomic.Int64{} // the maximum logs per second of each call site, or zero if there is no maximum
+	budgetMx  = sync.Mutex{}
+	budgetsBy = map[fileLine]*callSiteBudget{}
+)
+
+// SetCallSiteBudget sets the maximum number of logs per second that each call site may write. Logs that exceed the
+// budget are dropped for the remainder of that second, so a call site that becomes unexpectedly noisy, such as one in
+// a hot loop, is sampled down to the budget rather than flood the log stream. When a call site is first throttled,
+// a notice identifying it is written. Fatal and Audit logs are never throttled.
+//
+// Enforcing the budget adds the cost of identifying the call site to each log written, so it is disabled by default.
+// Pass zero to disable it.
+// This operation is safe for concurrent use.
+func SetCallSiteBudget(perSecond int) {
+	budgetMx.Lock()
+	budgetsBy = map[fileLine]*callSiteBudget{}
+	budgetMx.Unlock()
+
+	budget.Store(int64(perSecond))
+}
+
+// withinBudget reports whether the call site of the log is within its budget, writing a notice if it is newly throttled
+func (l *Log) withinBudget(ctx context.Context, flag OutputMask) bool {
+	max := budget.Load()
+
+	if max <= 0 || flag&(OutputFlagFatal|OutputFlagAudit) != 0 || exempt(ctx) {
+		return true
+	}
+
+	c := callers{}
+	runtime.Callers(4, c[:]) // skips Callers, withinBudget, admit and its caller
+
+	site, ok := cachedCallSite(c)
+
+	if !ok {
+		return true
+	}
+
+	budgetMx.Lock()
+
+	b, ok := budgetsBy[fileLine{site.File, site.Line}]
+
+	if !ok {
+		b = &callSiteBudget{}
+		budgetsBy[fileLine{site.File, site.Line}] = b
+	}
+
+	if window := timeNow().Unix(); b.window != window {
+		if b.count <= max || window-b.window > 1 { // a window within budget ends the throttling
+			b.throttled = false
+		}
+
+		b.window, b.count = window, 0
+	}
+
+	b.count++
+	within, notify := b.count <= max, b.count > max && !b.throttled
+	b.throttled = b.throttled || notify
+
+	budgetMx.Unlock()
+
+	if notify && l.enabled(ctx, OutputFlagNotice) {
+		// the notice shares the call site of the throttled log, so it is exempted from the budget
+		l.log(context.WithValue(ctx, budgetExemptKey, true), OutputFlagNotice, "NOTICE", "call site throttled", nil, "call_site", site.File+":"+strconv.Itoa(site.Line), "budget_per_second", int(max))
+	}
+
+	return within
+}
+
+// byteBudget is the budget of bytes per second shared by all logs, along with the bytes written in the current one
+// second window and those shed since they were last reported
+type byteBudget struct {
+	mx        sync.Mutex
+	perSecond int64
+	window    int64
+	used      int64
+	shedLogs  int
+	shedBytes int64
+	l         *Log        // the Log that reports the logs shed
+	report    *time.Timer // reports the logs shed once the ShedReportInterval has elapsed since the first was shed
+}
+
+// byteBudgetShares are the proportions of the byte budget of each second, once used, above which logs of a severity
+// below each Severity are shed, so that the least important logs are shed first and the most important last
+var byteBudgetShares = []struct {
+	below Severity
+	share float64
+}{
+	{SeverityInfo, 0.5},
+	{SeverityNotice, 0.7},
+	{SeverityWarning, 0.8},
+	{SeverityError, 0.9},
+	{SeverityFatal, 1},
+}
+
+// ShedReportInterval is the interval over which the logs shed by the byte budget are counted before a warning reporting
+// them is written.
+//
+// It is intended for configuration during start-up. It is not safe for concurrent use.
+var ShedReportInterval = 10 * time.Second
+
+var bytesBudget = atomic.Pointer[byteBudget]{}
+
+// SetByteBudget sets the maximum number of bytes of logs per second that may be written by all Logs combined. As the
+// budget of each second is used, logs are progressively shed, least severe first, such that Debug and Trace logs are
+// shed once half of it is used, then Info, Notice and Warning logs in turn, until only Error logs may use the last
+// tenth. Fatal and Audit logs are never shed. This protects downstream pipelines from the log storms that often
+// accompany incidents, while retaining the logs most likely to explain them.
+//
+// A warning reporting the number of logs, and bytes, shed is written once the ShedReportInterval has elapsed since the
+// first was shed. Pass zero to disable the budget.
+// This operation is safe for concurrent use.
+func SetByteBudget(bytesPerSecond int) {
+	var bb *byteBudget
+
+	if bytesPerSecond > 0 {
+		bb = &byteBudget{perSecond: int64(bytesPerSecond)}
+	}
+
+	if old := bytesBudget.Swap(bb); old != nil {
+		old.mx.Lock()
+
+		if old.report != nil {
+			old.report.Stop()
+		}
+
+		old.mx.Unlock()
+	}
+}
+
+// withinByteBudget reports whether a log of n bytes, with the severity flag, may be written within the byte budget,
+// counting it as shed if it may not
+func (l *Log) withinByteBudget(ctx context.Context, flag OutputMask, n int) bool {
+	bb := bytesBudget.Load()
+
+	if bb == nil || flag&(OutputFlagFatal|OutputFlagAudit) != 0 || exempt(ctx) {
+		return true
+	}
+
+	share, s := 1.0, severityOf(flag)
+
+	for _, t := range byteBudgetShares {
+		if s < t.below {
+			share = t.share
+			break
+		}
+	}
+
+	bb.mx.Lock()
+	defer bb.mx.Unlock()
+
+	if window := timeNow().Unix(); bb.window != window {
+		bb.window, bb.used = window, 0
+	}
+
+	if float64(bb.used+int64(n)) <= share*float64(bb.perSecond) {
+		bb.used += int64(n)
+		return true
+	}
+
+	bb.shedLogs++
+	bb.shedBytes += int64(n)
+
+	if bb.report == nil {
+		bb.l = l
+		bb.report = time.AfterFunc(ShedReportInterval, bb.reportShed)
+	}
+
+	return false
+}
+
+// reportShed writes a warning reporting the logs shed since the last report
+func (bb *byteBudget) reportShed() {
+	bb.mx.Lock()
+	l, logs, bytes := bb.l, bb.shedLogs, bb.shedBytes
+	bb.report, bb.shedLogs, bb.shedBytes = nil, 0, 0
+	bb.mx.Unlock()
+
+	ctx := context.WithValue(context.Background(), budgetExemptKey, true) // the report is never shed
+
+	if logs > 0 && l.enabled(ctx, OutputFlagWarning) {
+		l.log(ctx, OutputFlagWarning, "WARNING", "logs shed", nil, "shed_logs", logs, "shed_bytes", int(bytes), "byte_budget_per_second", int(bb.perSecond))
+	}
+}
@@ -0,0 +1,111 @@
+package qlog
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallSiteBudget(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() {
+		SetCallSiteBudget(0)
+		timeNow = time.Now
+	}()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+	ctx := context.Background()
+
+	SetCallSiteBudget(2)
+
+	noisy := func() {
+		for i := 0; i < 5; i++ {
+			l.Info(ctx, "noisy")
+		}
+	}
+
+	noisy()
+	l.Info(ctx, "quiet")
+
+	if output := sb.String(); strings.Count(output, "noisy") != 2 || strings.Count(output, "quiet") != 1 {
+		t.Fatalf("expected the noisy call site to be throttled to its budget but got '%v'", output)
+	}
+
+	if output := sb.String(); strings.Count(output, "call site throttled") != 1 || !strings.Contains(output, `call_site="`) || !strings.Contains(output, "budget_test.go:") {
+		t.Fatalf("expected a notice identifying the throttled call site but got '%v'", output)
+	}
+
+	sb.Reset()
+	now = now.Add(time.Second)
+	noisy()
+
+	if output := sb.String(); strings.Count(output, "noisy") != 2 || strings.Contains(output, "call site throttled") {
+		t.Fatalf("expected the call site to be throttled without a further notice but got '%v'", output)
+	}
+
+	sb.Reset()
+	now = now.Add(time.Second)
+	l.Info(ctx, "within budget")
+	SetCallSiteBudget(0)
+	noisy()
+
+	if output := sb.String(); strings.Count(output, "noisy") != 5 {
+		t.Fatalf("expected no throttling once the budget is disabled but got '%v'", output)
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() {
+		SetByteBudget(0)
+		timeNow = time.Now
+	}()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+	ctx := context.Background()
+
+	l.Debug(ctx, "shed") // logs of DEBUG and ERROR severity with the same message are the same size
+	size := sb.Len()
+	sb.Reset()
+
+	SetByteBudget(10 * size)
+
+	for i := 0; i < 10; i++ {
+		l.Debug(ctx, "shed")
+	}
+
+	for i := 0; i < 10; i++ {
+		l.Error(ctx, "shed", nil)
+	}
+
+	l.Audit(ctx, "audited")
+
+	if output := sb.String(); strings.Count(output, "DEBUG") != 5 || strings.Count(output, "ERROR") != 5 || !strings.Contains(output, "audited") {
+		t.Fatalf("expected debug logs to be shed once half the budget was used and error logs once it was all used but got '%v'", output)
+	}
+
+	sb.Reset()
+	now = now.Add(time.Second)
+	l.Debug(ctx, "shed")
+	bytesBudget.Load().reportShed()
+
+	for _, expected := range []string{`severity="DEBUG"`, `severity="WARNING"`, `shed_logs=10 shed_bytes=` + strconv.Itoa(10*size) + ` byte_budget_per_second=` + strconv.Itoa(10*size) + ` message="logs shed"`} {
+		if output := sb.String(); !strings.Contains(output, expected) {
+			t.Fatalf("expected the budget to be renewed each second and the logs shed to be reported with '%v' but got '%v'", expected, output)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// BufferedWriter is an io.Writer that coalesces logs into batches, each written to its underlying Writer with a single
+// Write, reducing the number of syscalls made at high volumes.
+//
+// A batch is written once it reaches the size limit, or once the oldest log in it has waited for the interval, so
+// no log is delayed by more than the interval. Fatal logs are written immediately, along with any batched before them.
+//
+// Should a batch that was written by the interval elapsing fail to be written, the error is returned by the next call
+// to Write, or Flush
+type BufferedWriter struct {
+	w        io.Writer
+	size     int
+	interval time.Duration
+	mx       sync.Mutex
+	b        []byte
+	timer    *time.Timer
+	err      error // the error from writing a batch when the interval elapsed, if any
+	closed   bool
+}
+
+// NewBufferedWriter creates a BufferedWriter that writes to w in batches of up to size bytes, writing any logs that
+// have been waiting for longer than the interval regardless of the size of the batch. Logs larger than size are
+// written without being batched.
+func NewBufferedWriter(w io.Writer, size int, interval time.Duration) *BufferedWriter {
+	return &BufferedWriter{
+		w:        w,
+		size:     size,
+		interval: interval,
+		b:        make([]byte, 0, size),
+	}
+}
+
+// Write adds b to the current batch
+func (bw *BufferedWriter) Write(b []byte) (int, error) {
+	return bw.WriteSeverity(OutputFlagNone, b)
+}
+
+// WriteSeverity adds b to the current batch, writing the batch immediately if the severity flag is Fatal
+func (bw *BufferedWriter) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	bw.mx.Lock()
+	defer bw.mx.Unlock()
+
+	if err := bw.err; err != nil {
+		bw.err = nil
+		return 0, err
+	}
+
+	if bw.closed { // logs written after Close are written synchronously rather than lost
+		return bw.w.Write(b)
+	}
+
+	if len(bw.b)+len(b) > bw.size {
+		if err := bw.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(b) > bw.size {
+		return bw.w.Write(b)
+	}
+
+	bw.b = append(bw.b, b...)
+
+	if len(bw.b) == bw.size || flag&OutputFlagFatal != 0 {
+		if err := bw.flush(); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if bw.timer == nil {
+		bw.timer = time.AfterFunc(bw.interval, bw.elapsed)
+	}
+
+	return len(b), nil
+}
+
+// Unwrap returns the underlying Writer
+func (bw *BufferedWriter) Unwrap() io.Writer {
+	return bw.w
+}
+
+// Flush writes the current batch. It does not block on ctx, which is accepted so that BufferedWriter is flushed by
+// the package level Flush
+func (bw *BufferedWriter) Flush(ctx context.Context) error {
+	bw.mx.Lock()
+	defer bw.mx.Unlock()
+
+	if err := bw.err; err != nil {
+		bw.err = nil
+		return err
+	}
+
+	return bw.flush()
+}
+
+// Close writes the current batch and stops batching. Logs written after Close are written synchronously.
+// If the underlying Writer is an io.Closer, it is not closed.
+func (bw *BufferedWriter) Close() error {
+	bw.mx.Lock()
+	defer bw.mx.Unlock()
+
+	bw.closed = true
+
+	return bw.flush()
+}
+
+// SetBuffered sets the Writer of the default logger to a BufferedWriter that writes to its current Writer in batches of
+// up to size bytes, with no log delayed by more than the interval.
+//
+// Call Close before the process exits, such as with a defer in main, so that batched logs are not lost.
+// This operation is safe for concurrent use.
+func SetBuffered(size int, interval time.Duration) {
+	configure(func(l *Log) { l.Writer = NewBufferedWriter(l.Writer, size, interval) })
+}
+
+// elapsed writes the current batch once the interval has elapsed since its first log was added
+func (bw *BufferedWriter) elapsed() {
+	bw.mx.Lock()
+	defer bw.mx.Unlock()
+
+	bw.timer = nil // set before flushing, which stops any timer it finds
+
+	if err := bw.flush(); err != nil && bw.err == nil {
+		bw.err = err
+	}
+}
+
+// flush writes the current batch, the caller must hold mx
+func (bw *BufferedWriter) flush() error {
+	if bw.timer != nil {
+		bw.timer.Stop()
+		bw.timer = nil
+	}
+
+	if len(bw.b) == 0 {
+		return nil
+	}
+
+	n, err := bw.w.Write(bw.b)
+
+	if err == nil && n < len(bw.b) {
+		err = io.ErrShortWrite
+	}
+
+	bw.b = bw.b[:0]
+
+	return err
+}
@@ -0,0 +1,86 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	cw := &countingWriter{}
+	bw := NewBufferedWriter(cw, 10, time.Hour)
+
+	bw.Write([]byte("log1\n"))
+	bw.Write([]byte("log2\n")) // fills the batch
+
+	if cw.writes != 1 || cw.String() != "log1\nlog2\n" {
+		t.Fatalf("expected a full batch to be written with a single write but got %v writes of '%v'", cw.writes, cw.String())
+	}
+
+	bw.Write([]byte("log3\n"))
+	bw.Write([]byte("log4-exceeds\n")) // does not fit the batch, so the batch is written, then the log
+
+	if cw.writes != 3 || cw.String() != "log1\nlog2\nlog3\nlog4-exceeds\n" {
+		t.Fatalf("expected the batch to be written before an oversized log but got %v writes of '%v'", cw.writes, cw.String())
+	}
+
+	bw.Write([]byte("log5\n"))
+	bw.WriteSeverity(OutputFlagFatal, []byte("f\n"))
+
+	if cw.writes != 4 || !strings.HasSuffix(cw.String(), "log5\nf\n") {
+		t.Fatalf("expected a fatal log to write the batch immediately but got %v writes of '%v'", cw.writes, cw.String())
+	}
+
+	bw.Write([]byte("log6\n"))
+
+	if err := bw.Flush(context.Background()); err != nil || cw.writes != 5 {
+		t.Fatalf("expected flush to write the batch but got %v writes and '%v'", cw.writes, err)
+	}
+
+	bw.Close()
+	bw.Write([]byte("log7\n"))
+
+	if cw.writes != 6 {
+		t.Fatalf("expected logs written after close to be written synchronously but got %v writes", cw.writes)
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	gw := &gatedWriter{gate: make(chan struct{})}
+	close(gw.gate)
+
+	bw := NewBufferedWriter(gw, 1024, 20*time.Millisecond)
+	defer bw.Close()
+
+	bw.Write([]byte("log1\n"))
+	bw.Write([]byte("log2\n"))
+
+	gw.mx.Lock()
+	if len(gw.logs) != 0 {
+		t.Fatalf("expected logs to be batched but got '%v'", gw.logs)
+	}
+	gw.mx.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	gw.mx.Lock()
+	defer gw.mx.Unlock()
+
+	if len(gw.logs) != 1 || gw.logs[0] != "log1\nlog2\n" {
+		t.Fatalf("expected the batch to be written once the interval elapsed but got '%v'", gw.logs)
+	}
+}
+
+func TestBufferedWriterError(t *testing.T) {
+	bw := NewBufferedWriter(&flakyWriter{failures: 1, err: syscall.EPIPE}, 1024, time.Millisecond)
+
+	bw.Write([]byte("log1\n"))
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := bw.Write([]byte("log2\n")); !errors.Is(err, syscall.EPIPE) {
+		t.Fatalf("expected the error from writing the elapsed batch to be returned but got '%v'", err)
+	}
+}
@@ -0,0 +1,103 @@
+package qlog
+
+import (
+	"context"
+	"sync"
+)
+
+// canonicalKey is the type of the context key of the CanonicalLine of a request
+type canonicalKey struct{}
+
+// CanonicalLine accumulates the labels of a canonical log line; a single, wide log written at the end of a request
+// that summarises it, such as the time spent in each dependency, so that requests can be analysed without
+// collating many narrow logs. A CanonicalLine is safe for concurrent use.
+//
+// The methods of a nil CanonicalLine, as returned by Canonical for a context.Context without one, have no effect, so
+// labels can be added without checking that the request has a canonical log line
+type CanonicalLine struct {
+	mx     sync.Mutex
+	labels []any
+}
+
+// WithCanonical returns a context.Context that carries a CanonicalLine, unless ctx already carries one, in which case
+// it is returned unchanged, so that labels are accumulated in a single CanonicalLine for the request. Middleware
+// creates one for each request, writing its labels on the log of the request's completion.
+//
+// For example:
+//
+//	ctx = qlog.WithCanonical(ctx)
+//	defer func() { qlog.Info(ctx, "job completed", qlog.Canonical(ctx).Labels()...) }()
+func WithCanonical(ctx context.Context) context.Context {
+	if Canonical(ctx) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, canonicalKey{}, &CanonicalLine{})
+}
+
+// Canonical returns the CanonicalLine carried by ctx, or nil if it carries none
+//
+// For example:
+//
+//	start := time.Now()
+//	rows, err := db.QueryContext(ctx, query)
+//	qlog.Canonical(ctx).Set("db_ms", time.Since(start).Milliseconds())
+func Canonical(ctx context.Context) *CanonicalLine {
+	c, _ := ctx.Value(canonicalKey{}).(*CanonicalLine)
+
+	return c
+}
+
+// Set sets the label with the specified key to value, replacing any previously set. As with the labels passed to the
+// log methods, value may be expressed as a func() T, which is not evaluated unless the log is written
+func (c *CanonicalLine) Set(key string, value any) {
+	if c == nil {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for i := 0; i < len(c.labels); i += 2 {
+		if c.labels[i] == key {
+			c.labels[i+1] = value
+			return
+		}
+	}
+
+	c.labels = append(c.labels, key, value)
+}
+
+// Add adds delta to the label with the specified key, such as a count of queries, setting it to delta if it is not
+// set, or is not an int
+func (c *CanonicalLine) Add(key string, delta int) {
+	if c == nil {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for i := 0; i < len(c.labels); i += 2 {
+		if c.labels[i] == key {
+			n, _ := c.labels[i+1].(int)
+			c.labels[i+1] = n + delta
+			return
+		}
+	}
+
+	c.labels = append(c.labels, key, delta)
+}
+
+// Labels returns a copy of the labels of the CanonicalLine, as key, value pairs in the order they were first set, to
+// be passed to a log method
+func (c *CanonicalLine) Labels() []any {
+	if c == nil {
+		return nil
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return append([]any(nil), c.labels...)
+}
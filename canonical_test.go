@@ -0,0 +1,32 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	if c := Canonical(context.Background()); c != nil || c.Labels() != nil {
+		t.Fatalf("expected a context without a canonical log line to have none")
+	}
+
+	Canonical(context.Background()).Set("ignored", true) // a nil CanonicalLine has no effect
+
+	ctx := WithCanonical(context.Background())
+
+	if WithCanonical(ctx) != ctx {
+		t.Fatalf("expected a context with a canonical log line to be returned unchanged")
+	}
+
+	c := Canonical(ctx)
+	c.Set("db_ms", 12)
+	c.Add("queries", 1)
+	c.Set("cache", "miss")
+	c.Add("queries", 2)
+	c.Set("db_ms", 15)
+
+	if expected, actual := "[db_ms 15 queries 3 cache miss]", fmt.Sprint(c.Labels()); actual != expected {
+		t.Fatalf("expected labels %v but got %v", expected, actual)
+	}
+}
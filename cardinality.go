@@ -0,0 +1,115 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HighCardinality is written in place of the values of a label that exceed the cardinality limit
+const HighCardinality = "<high-cardinality>"
+
+// cardinalityGuard tracks the distinct values of each label key within the current window
+type cardinalityGuard struct {
+	limit  int
+	window time.Duration
+	keys   map[string]struct{} // the keys that are guarded, or nil if all keys are
+	mx     sync.Mutex
+	start  time.Time
+	values map[string]map[string]struct{}
+}
+
+var cardinality = atomic.Pointer[cardinalityGuard]{}
+
+// SetCardinalityLimit limits the number of distinct values of each label key, across all Logs, within each window
+// of the specified duration. Once a key has reached the limit, further values are written as HighCardinality for the
+// remainder of the window and a notice identifying the key is written. This protects index based log backends from
+// an explosion in cardinality, such as when an unbounded value, like a user ID, is logged in a label intended for a
+// bounded one, like a status.
+//
+// If keys are specified, only the labels with those keys are limited. Pass a limit of zero to disable the guard.
+// This operation is safe for concurrent use.
+func SetCardinalityLimit(limit int, window time.Duration, keys ...string) {
+	if limit <= 0 {
+		cardinality.Store(nil)
+		return
+	}
+
+	g := &cardinalityGuard{limit: limit, window: window, start: timeNow(), values: map[string]map[string]struct{}{}}
+
+	if len(keys) > 0 {
+		g.keys = map[string]struct{}{}
+
+		for _, k := range keys {
+			g.keys[k] = struct{}{}
+		}
+	}
+
+	cardinality.Store(g)
+}
+
+// guard returns value, or HighCardinality if the key has exceeded the limit, and whether the key has newly exceeded it
+func (g *cardinalityGuard) guard(key string, value any) (any, bool) {
+	if g.keys != nil {
+		if _, ok := g.keys[key]; !ok {
+			return value, false
+		}
+	}
+
+	value = resolve(value) // so that a lazy value is not evaluated twice
+	v, ok := value.(string)
+
+	if !ok {
+		v = fmt.Sprintf("%v", value)
+	}
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if now := timeNow(); now.Sub(g.start) >= g.window {
+		g.start, g.values = now, map[string]map[string]struct{}{}
+	}
+
+	values, ok := g.values[key]
+
+	if !ok {
+		values = map[string]struct{}{}
+		g.values[key] = values
+	}
+
+	if _, ok := values[v]; ok {
+		return value, false
+	}
+
+	if len(values) < g.limit {
+		values[v] = struct{}{}
+		return value, false
+	}
+
+	if _, notified := values[HighCardinality]; notified { // the marker is recorded, beyond the limit, once the notice is written
+		return HighCardinality, false
+	}
+
+	values[HighCardinality] = struct{}{}
+
+	return HighCardinality, true
+}
+
+// guardCardinality returns the value to write for the label, writing a notice should the key newly exceed the limit
+func (l *Log) guardCardinality(ctx context.Context, key string, value any) any {
+	g := cardinality.Load()
+
+	if g == nil {
+		return value
+	}
+
+	value, exceeded := g.guard(key, value)
+
+	if exceeded && l.enabled(ctx, OutputFlagNotice) {
+		l.log(context.WithValue(ctx, budgetExemptKey, true), OutputFlagNotice, "NOTICE", "label cardinality limit exceeded", nil, "label_key", key, "limit", g.limit)
+	}
+
+	return value
+}
@@ -0,0 +1,56 @@
+package qlog
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardinalityLimit(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() {
+		SetCardinalityLimit(0, 0)
+		timeNow = time.Now
+	}()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+	ctx := context.Background()
+
+	SetCardinalityLimit(2, time.Minute, "user", "status")
+
+	for i := 0; i < 4; i++ {
+		l.Info(ctx, "request", "user", i, "status", "ok", "path", "/"+strconv.Itoa(i))
+	}
+
+	l.Info(ctx, "request", "user", 1)
+
+	output := sb.String()
+
+	for _, expected := range []string{"user=0 ", "user=1 ", `user="<high-cardinality>" status="ok" path="/2"`, `user="<high-cardinality>" status="ok" path="/3"`} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected output to contain '%v' but got '%v'", expected, output)
+		}
+	}
+
+	if strings.Count(output, "user=1 ") != 2 {
+		t.Fatalf("expected values seen within the limit to continue to be written but got '%v'", output)
+	}
+
+	if strings.Count(output, `label_key="user" limit=2 message="label cardinality limit exceeded"`) != 1 {
+		t.Fatalf("expected a single notice identifying the key but got '%v'", output)
+	}
+
+	sb.Reset()
+	now = now.Add(time.Minute)
+	l.Info(ctx, "request", "user", 5)
+
+	if output := sb.String(); !strings.Contains(output, "user=5 ") {
+		t.Fatalf("expected the limit to be reset in the next window but got '%v'", output)
+	}
+}
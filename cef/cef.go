@@ -0,0 +1,186 @@
+// Package cef provides a qlog writer that encodes logs in the Common Event Format (CEF), for ingestion by SIEMs, such
+// as ArcSight or QRadar, without an intermediate transformation.
+//
+// As a Writer, it can be selected per sink, such as for a single Route of a qlog.Router. For example:
+//
+//	siem := cef.New(conn, cef.Config{Vendor: "Acme", Product: "Checkout", Version: "1.4", Fields: map[string]string{"user_id": "suser"}})
+//
+//	qlog.SetWriter(qlog.NewRouter(
+//		qlog.Route{Writer: os.Stderr, Mask: qlog.OutputMaskAll},
+//		qlog.Route{Writer: siem, Mask: qlog.OutputFlagAudit},
+//	))
+package cef
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// severities maps the names of qlog severities to CEF severities, from 0, the least severe, to 10. Unrecognised
+// severities, such as custom severities, are of medium severity
+var severities = map[string]int{
+	"FATAL":   10,
+	"ERROR":   8,
+	"WARNING": 6,
+	"AUDIT":   5,
+	"NOTICE":  4,
+	"INFO":    3,
+	"DEBUG":   1,
+	"TRACE":   1,
+}
+
+// Config configures the header of each CEF event and the mapping of labels to its extension
+type Config struct {
+	// Vendor, Product and Version identify the device, the application, writing the events
+	Vendor, Product, Version string
+	// SignatureLabel is the key of the label whose value is the Signature ID of the event, by default "event". Where a
+	// log has no such label, its severity is used
+	SignatureLabel string
+	// Fields maps label keys to the CEF extension keys that they are written as, such as "user_id" to "suser". Labels
+	// without a mapping are written with their own key, with any characters other than letters and digits removed
+	Fields map[string]string
+}
+
+// Writer is a qlog.EntryWriter that encodes each log as a CEF event, on a line, and writes it to an underlying Writer
+type Writer struct {
+	w      io.Writer
+	config Config
+}
+
+// New creates a Writer that writes logs encoded as CEF events to w, with the header and extension defined by the Config
+func New(w io.Writer, c Config) *Writer {
+	return &Writer{w: w, config: c}
+}
+
+// Write writes b, a log encoded by qlog, as the name of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e, encoded as a CEF event
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	if _, err := w.w.Write(Encode(e, w.config)); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// Unwrap returns the underlying Writer
+func (w *Writer) Unwrap() io.Writer {
+	return w.w
+}
+
+// Encode returns the log described by e encoded as a CEF event, on a line, with the header and extension defined by c.
+//
+// The message of the log is the Name of the event. The extension holds the timestamp, as `rt`, the error, as `reason`,
+// the trace ID, as the custom string `cs1`, and the labels of the log
+func Encode(e qlog.Entry, c Config) []byte {
+	if c.SignatureLabel == "" {
+		c.SignatureLabel = "event"
+	}
+
+	severity, ok := severities[e.Severity]
+
+	if !ok {
+		severity = 5
+	}
+
+	labels, signature := e.AllLabels(), e.Severity
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i] == c.SignatureLabel {
+			signature = fmt.Sprint(labels[i+1])
+		}
+	}
+
+	b := []byte("CEF:0|")
+
+	for _, field := range []string{c.Vendor, c.Product, c.Version, signature, e.Message} {
+		b = appendHeaderField(b, field)
+		b = append(b, '|')
+	}
+
+	b = strconv.AppendInt(b, int64(severity), 10)
+	b = append(b, "|rt="...)
+	b = strconv.AppendInt(b, e.Time.UnixMilli(), 10)
+
+	if e.Error != nil {
+		b = appendExtension(b, "reason", e.Error.Error())
+	}
+
+	if e.TraceID != "" {
+		b = appendExtension(b, "cs1Label", "trace")
+		b = appendExtension(b, "cs1", e.TraceID)
+	}
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		key := fmt.Sprint(labels[i])
+
+		if key == c.SignatureLabel {
+			continue
+		}
+
+		if field, ok := c.Fields[key]; ok {
+			key = field
+		} else {
+			key = strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+					return r
+				}
+
+				return -1
+			}, key)
+		}
+
+		if key != "" {
+			b = appendExtension(b, key, fmt.Sprint(labels[i+1]))
+		}
+	}
+
+	return append(b, '\n')
+}
+
+// appendHeaderField appends a header field to b, escaping backslashes and pipes and replacing line breaks with spaces
+func appendHeaderField(b []byte, v string) []byte {
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '\\', '|':
+			b = append(b, '\\', c)
+		case '\r', '\n':
+			b = append(b, ' ')
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return b
+}
+
+// appendExtension appends a key=value pair of the extension to b, escaping backslashes, equals signs and line breaks
+// in the value
+func appendExtension(b []byte, key, v string) []byte {
+	b = append(b, ' ')
+	b = append(b, key...)
+	b = append(b, '=')
+
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '\\', '=':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return b
+}
@@ -0,0 +1,58 @@
+package cef
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+func TestEncode(t *testing.T) {
+	type testCase struct {
+		Entry    qlog.Entry
+		Expected string
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 250000000, time.UTC)
+	c := Config{Vendor: "Acme", Product: "Check|out", Version: "1.4", Fields: map[string]string{"user_id": "suser"}}
+
+	for name, tc := range map[string]testCase{
+		"error": {
+			Entry:    qlog.Entry{Time: ts, Severity: "ERROR", TraceID: "abc", Message: "login failed", Error: errors.New("bad password"), Labels: []any{"event", "auth-1", "user_id", "u1"}},
+			Expected: `CEF:0|Acme|Check\|out|1.4|auth-1|login failed|8|rt=1709296200250 reason=bad password cs1Label=trace cs1=abc suser=u1` + "\n",
+		},
+		"escaping": {
+			Entry:    qlog.Entry{Time: ts, Severity: "SECURITY", Message: "a\nb", Labels: []any{"query", `a=b\c` + "\n", "src.ip", "10.0.0.1"}},
+			Expected: `CEF:0|Acme|Check\|out|1.4|SECURITY|a b|5|rt=1709296200250 query=a\=b\\c\n srcip=10.0.0.1` + "\n",
+		},
+	} {
+		if actual := string(Encode(tc.Entry, c)); actual != tc.Expected {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestWriterRoute(t *testing.T) {
+	stderr, siem := &bytes.Buffer{}, &bytes.Buffer{}
+
+	l := qlog.New(qlog.OutputMaskAll, true)
+	l.Writer = qlog.NewRouter(
+		qlog.Route{Writer: stderr, Mask: qlog.OutputMaskAll},
+		qlog.Route{Writer: New(siem, Config{Vendor: "Acme", Product: "Checkout", Version: "1"}), Mask: qlog.OutputFlagWarning},
+	)
+
+	l.Info(context.Background(), "started")
+	l.Warning(context.Background(), "suspicious", nil, "src", "10.0.0.1")
+
+	if log := siem.String(); !strings.HasPrefix(log, "CEF:0|Acme|Checkout|1|WARNING|suspicious|6|") || strings.Count(log, "\n") != 1 || !strings.HasSuffix(log, " src=10.0.0.1\n") {
+		t.Fatalf("expected a single CEF event on the route but got '%v'", log)
+	}
+
+	if strings.Count(stderr.String(), "\n") != 2 {
+		t.Fatalf("expected both logs encoded by qlog on the other route but got '%v'", stderr.String())
+	}
+}
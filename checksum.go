@@ -0,0 +1,53 @@
+package qlog
+
+import (
+	"bytes"
+	"hash/crc32"
+	"strconv"
+)
+
+// Sets whether the default logger writes a `checksum` label on each log, allowing consumers of lossy transports, such
+// as UDP syslog, to discard corrupted logs. The label is written last and is the CRC32 (IEEE) of the log up to, but
+// excluding, the label itself, as eight hexadecimal digits. VerifyChecksum checks it
+// This operation is safe for concurrent use.
+func SetChecksum(v bool) {
+	configure(func(l *Log) { l.Checksum = v })
+}
+
+// VerifyChecksum reports whether the log b, in either format, has a `checksum` label that matches its content.
+// Logs without a checksum label are reported as invalid
+func VerifyChecksum(b []byte) bool {
+	for _, field := range [][]byte{[]byte(`, "checksum": "`), []byte(` checksum="`)} {
+		i := bytes.LastIndex(b, field)
+
+		if i < 0 {
+			continue
+		}
+
+		v := b[i+len(field):]
+
+		if len(v) < 9 || v[8] != '"' {
+			return false
+		}
+
+		sum, err := strconv.ParseUint(string(v[:8]), 16, 32)
+
+		return err == nil && uint32(sum) == crc32.ChecksumIEEE(b[:i])
+	}
+
+	return false
+}
+
+// appendChecksum appends a checksum label, with the CRC32 of log, to b
+func appendChecksum(b, log []byte, openField, closeField string) []byte {
+	sum := crc32.ChecksumIEEE(log)
+
+	b = appendField(b, openField, "checksum", closeField)
+	b = append(b, '"')
+
+	for shift := 28; shift >= 0; shift -= 4 {
+		b = append(b, "0123456789abcdef"[sum>>shift&0xF])
+	}
+
+	return append(b, '"')
+}
@@ -0,0 +1,32 @@
+package qlog
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	for _, json := range []bool{true, false} {
+		w := strings.Builder{}
+
+		l := New(OutputMaskAll, json)
+		l.Writer, l.Checksum = &w, true
+		l.Info(context.Background(), "test", "key", "value")
+
+		log := w.String()
+
+		if !regexp.MustCompile(`message.*checksum(": |=)"[0-9a-f]{8}"( })?\n$`).MatchString(log) {
+			t.Fatalf("json=%v: expected a checksum label to be written last but got '%v'", json, log)
+		}
+
+		if !VerifyChecksum([]byte(log)) {
+			t.Fatalf("json=%v: expected the checksum of '%v' to be verified", json, log)
+		}
+
+		if corrupted := strings.Replace(log, "value", "vAlue", 1); VerifyChecksum([]byte(corrupted)) {
+			t.Fatalf("json=%v: expected the checksum of corrupted log '%v' not to be verified", json, corrupted)
+		}
+	}
+}
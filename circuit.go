@@ -0,0 +1,135 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker that has no Fallback while its circuit is open
+var ErrCircuitOpen = errors.New("qlog: circuit open")
+
+// CircuitBreaker is an io.Writer that stops attempting to write to its Writer, such as a network sink, once it has
+// failed a number of consecutive times, routing logs to the Fallback instead. This prevents a dead endpoint from
+// stalling the application with writes that are bound to fail.
+//
+// Once the circuit has been open for the Backoff, the next log is written to the Writer again, after first checking
+// the Probe passes, if one is set. Should that succeed, the circuit is closed, otherwise it remains open for another
+// Backoff. A warning is written to the Fallback, or stderr if there is none, when the circuit opens, and a notice when
+// it closes; these are written in the format of the default logger and with its labels.
+type CircuitBreaker struct {
+	// Writer is the destination of the logs
+	Writer io.Writer
+	// Fallback, if not nil, receives the logs while the circuit is open, and any log that fails to be written to the Writer
+	Fallback io.Writer
+	// Threshold is the number of consecutive failures after which the circuit is opened
+	Threshold int
+	// Backoff is the period the circuit remains open before the Writer is attempted again
+	Backoff time.Duration
+	// Probe, if not nil, checks the health of the Writer, such as by dialing its endpoint, before it is attempted again
+	Probe func() error
+
+	mx       sync.Mutex
+	failures int
+	open     bool
+	retryAt  time.Time
+}
+
+// NewCircuitBreaker creates a CircuitBreaker for w that opens after 5 consecutive failures, for 10s at a time, and uses
+// the passed fallback, which may be nil, while open
+func NewCircuitBreaker(w io.Writer, fallback io.Writer) *CircuitBreaker {
+	return &CircuitBreaker{
+		Writer:    w,
+		Fallback:  fallback,
+		Threshold: 5,
+		Backoff:   10 * time.Second,
+	}
+}
+
+// Open reports whether the circuit is open, in which case logs are not being written to the Writer
+func (cb *CircuitBreaker) Open() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.open
+}
+
+// Write writes b to the Writer, unless the circuit is open, in which case it is written to the Fallback
+func (cb *CircuitBreaker) Write(b []byte) (int, error) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	if cb.open {
+		if timeNow().Before(cb.retryAt) {
+			return cb.fallback(b, ErrCircuitOpen)
+		}
+
+		if cb.Probe != nil {
+			if err := cb.Probe(); err != nil {
+				cb.retryAt = timeNow().Add(cb.Backoff)
+				return cb.fallback(b, ErrCircuitOpen)
+			}
+		}
+	}
+
+	n, err := cb.Writer.Write(b)
+
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+
+	if err == nil {
+		if cb.open {
+			cb.open = false
+			cb.diagnose(OutputFlagNotice, "NOTICE", "sink circuit closed", nil)
+		}
+
+		cb.failures = 0
+
+		return n, nil
+	}
+
+	cb.failures++
+
+	if cb.open || cb.failures >= cb.Threshold {
+		if !cb.open {
+			cb.open = true
+			cb.diagnose(OutputFlagWarning, "WARNING", "sink circuit opened", err, "failures", cb.failures, "backoff_ms", int(cb.Backoff.Milliseconds()))
+		}
+
+		cb.retryAt = timeNow().Add(cb.Backoff)
+	}
+
+	return cb.fallback(b, err)
+}
+
+// fallback writes b to the Fallback, if there is one, otherwise it returns err
+func (cb *CircuitBreaker) fallback(b []byte, err error) (int, error) {
+	if cb.Fallback == nil {
+		return 0, err
+	}
+
+	return cb.Fallback.Write(b)
+}
+
+// diagnose writes a log describing a change in the state of the circuit to the Fallback, or stderr if there is none.
+// It is encoded directly, as the CircuitBreaker is written to whilst the write lock is held
+func (cb *CircuitBreaker) diagnose(flag OutputMask, severity, message string, err error, labels ...any) {
+	l := defaultLog.Load()
+
+	if !l.writes(context.Background(), flag) {
+		return
+	}
+
+	w := cb.Fallback
+
+	if w == nil {
+		w = os.Stderr
+	}
+
+	b, _, _ := l.encode(nil, l.record(context.Background(), flag, severity, message, err, labels))
+	w.Write(b)
+}
@@ -0,0 +1,64 @@
+package qlog
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	defer resetDefaultLog()()
+
+	SetOutputFormat(FormatLogfmt)
+
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	sink, fallback := &flakyWriter{failures: 3, err: syscall.ECONNREFUSED}, &strings.Builder{}
+	probe := errors.New("unhealthy")
+
+	cb := NewCircuitBreaker(sink, fallback)
+	cb.Threshold, cb.Backoff, cb.Probe = 2, time.Second, func() error { return probe }
+
+	for _, log := range []string{"log1\n", "log2\n", "log3\n"} {
+		if _, err := cb.Write([]byte(log)); err != nil {
+			t.Fatalf("expected the fallback to be used without error but got '%v'", err)
+		}
+	}
+
+	if !cb.Open() || sink.failures != 1 { // the third log is not attempted as the circuit is open
+		t.Fatalf("expected the circuit to open after 2 failures but it is open=%v with %v failures remaining", cb.Open(), sink.failures)
+	}
+
+	now = now.Add(2 * time.Second)
+	cb.Write([]byte("log4\n")) // the probe fails, so the circuit remains open
+
+	now = now.Add(2 * time.Second)
+	probe = nil
+	cb.Write([]byte("log5\n")) // the probe passes, but the write fails
+
+	now = now.Add(2 * time.Second)
+	cb.Write([]byte("log6\n"))
+
+	if cb.Open() || !strings.HasSuffix(sink.String(), "log6\n") {
+		t.Fatalf("expected the circuit to close once the sink recovered but got '%v'", sink.String())
+	}
+
+	logs := fallback.String()
+
+	for _, expected := range []string{
+		`severity="WARNING"`, `error="connection refused" failures=2 backoff_ms=1000 message="sink circuit opened"`,
+		"log2\nlog3\nlog4\nlog5\n", `message="sink circuit closed"`,
+	} {
+		if !strings.Contains(logs, expected) {
+			t.Fatalf("expected the fallback to contain '%v' but got '%v'", expected, logs)
+		}
+	}
+
+	if strings.Count(logs, "sink circuit opened") != 1 {
+		t.Fatalf("expected a single warning of the circuit opening but got '%v'", logs)
+	}
+}
@@ -0,0 +1,31 @@
+package qlog
+
+import "time"
+
+// Clock is a source of time for the timestamps of logs. Setting the Clock of a Log allows systems that run in virtual
+// time, such as simulations and tests, to write logs with timestamps that follow the simulated time
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Location returns the time zone that timestamps are written in; if nil, UTC is used
+	Location() *time.Location
+}
+
+// Sets the Clock used by the default logger; pass nil to use the wall clock, in UTC
+// This operation is safe for concurrent use.
+func SetClock(c Clock) {
+	configure(func(l *Log) { l.Clock = c })
+}
+
+// now returns the current time of the Log's Clock, in its Location, or the wall clock time in UTC if it has no Clock
+func (l *Log) now() time.Time {
+	if l.Clock == nil {
+		return timeNow().UTC()
+	}
+
+	if loc := l.Clock.Location(); loc != nil {
+		return l.Clock.Now().In(loc)
+	}
+
+	return l.Clock.Now().UTC()
+}
@@ -0,0 +1,48 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type simulatedClock struct {
+	now time.Time
+	loc *time.Location
+}
+
+func (c *simulatedClock) Now() time.Time           { return c.now }
+func (c *simulatedClock) Location() *time.Location { return c.loc }
+
+func TestClock(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+
+	clock := &simulatedClock{now: time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC), loc: time.FixedZone("sim", 2*60*60)}
+	l.Clock = clock
+
+	ctx := context.Background()
+	s, end := l.Scope(ctx, "simulation")
+
+	clock.now = clock.now.Add(90 * time.Second)
+	s.Info(ctx, "tick")
+	end()
+
+	if output := sb.String(); !strings.Contains(output, `timestamp="2030-06-01T14:01:30+02:00"`) {
+		t.Fatalf("expected timestamps to follow the simulated clock in its location but got '%v'", output)
+	}
+
+	if output := sb.String(); !strings.Contains(output, "duration_ms=90000") {
+		t.Fatalf("expected durations to follow the simulated clock but got '%v'", output)
+	}
+
+	sb.Reset()
+	clock.loc = nil
+	l.Info(ctx, "utc")
+
+	if output := sb.String(); !strings.Contains(output, `timestamp="2030-06-01T12:01:30Z"`) {
+		t.Fatalf("expected timestamps in UTC when the clock has no location but got '%v'", output)
+	}
+}
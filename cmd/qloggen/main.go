@@ -0,0 +1,262 @@
+// Command qloggen generates strongly-typed logging funcs from a declared label schema.
+//
+// A schema is a go source file containing struct types annotated with a `//qlog:event` directive
+// that specifies the severity and message of the log. Each field tagged with `qlog:"key"` becomes
+// a parameter of the generated func and is written as a label with the given key. For example:
+//
+//	//go:build qloggen
+//
+//	package logx
+//
+//	//qlog:event info request completed
+//	type RequestCompleted struct {
+//		Method string `qlog:"method"`
+//		Status int    `qlog:"status"`
+//		DurMs  int    `qlog:"dur_ms"`
+//	}
+//
+// generates:
+//
+//	func RequestCompleted(ctx context.Context, method string, status int, durMs int) {
+//		qlog.Info(ctx, "request completed", "method", method, "status", status, "dur_ms", durMs)
+//	}
+//
+// Severities of fatal, error and warning generate funcs that also accept an error.
+//
+// The build constraint on the schema prevents the schema types from being compiled into the package
+// alongside the generated funcs. Typical usage is via go generate:
+//
+//	//go:generate go run github.com/comradequinn/qlog/cmd/qloggen -schema schema.go -o logx.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type (
+	event struct {
+		Name     string
+		Doc      string
+		Func     string
+		Message  string
+		HasError bool
+		Params   []param
+	}
+	param struct {
+		Key  string
+		Name string
+		Type string
+	}
+)
+
+const directive = "//qlog:event "
+
+var severities = map[string]string{
+	"fatal":   "Fatal",
+	"error":   "Error",
+	"warning": "Warning",
+	"notice":  "Notice",
+	"info":    "Info",
+	"trace":   "Trace",
+	"debug":   "Debug",
+}
+
+var reserved = map[string]bool{"ctx": true, "err": true, "qlog": true, "context": true}
+
+var tmpl = template.Must(template.New("qloggen").Parse(`// Code generated by qloggen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/comradequinn/qlog"
+)
+{{range .Events}}
+{{.Doc}}func {{.Name}}(ctx context.Context{{if .HasError}}, err error{{end}}{{range .Params}}, {{.Name}} {{.Type}}{{end}}) {
+	qlog.{{.Func}}(ctx, {{.Message}}{{if .HasError}}, err{{end}}{{range .Params}}, "{{.Key}}", {{.Name}}{{end}})
+}
+{{end}}`))
+
+func main() {
+	schema := flag.String("schema", "", "the go source file declaring the label schema")
+	output := flag.String("o", "", "the file to write the generated code to, defaults to stdout")
+
+	flag.Parse()
+
+	if *schema == "" {
+		fmt.Fprintln(os.Stderr, "qloggen: a schema must be specified")
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(*schema)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "qloggen: %v\n", err)
+		os.Exit(1)
+	}
+
+	code, err := generate(*schema, src)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "qloggen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(code)
+		return
+	}
+
+	if err := os.WriteFile(*output, code, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "qloggen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := []event{}
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+
+			e, ok, err := parseEvent(fset, ts, doc)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				events = append(events, e)
+			}
+		}
+	}
+
+	buf := bytes.Buffer{}
+
+	if err := tmpl.Execute(&buf, struct {
+		Package string
+		Events  []event
+	}{Package: f.Name.Name, Events: events}); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func parseEvent(fset *token.FileSet, ts *ast.TypeSpec, doc *ast.CommentGroup) (event, bool, error) {
+	if doc == nil {
+		return event{}, false, nil
+	}
+
+	e, found := event{Name: ts.Name.Name}, false
+
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, directive) {
+			if !strings.HasPrefix(c.Text, "//qlog:") && !found {
+				e.Doc += c.Text + "\n"
+			}
+			continue
+		}
+
+		severity, message, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(c.Text, directive)), " ")
+
+		if e.Func, found = severities[severity]; !found {
+			return e, false, fmt.Errorf("%v: unknown severity '%s' for event %s", fset.Position(c.Pos()), severity, e.Name)
+		}
+
+		e.Message = strconv.Quote(strings.TrimSpace(message))
+		e.HasError = severity == "fatal" || severity == "error" || severity == "warning"
+	}
+
+	if !found {
+		return e, false, nil
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+
+	if !ok {
+		return e, false, fmt.Errorf("%v: event %s must be declared as a struct", fset.Position(ts.Pos()), e.Name)
+	}
+
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+
+		tag, err := strconv.Unquote(field.Tag.Value)
+
+		if err != nil {
+			return e, false, err
+		}
+
+		key, ok := reflect.StructTag(tag).Lookup("qlog")
+
+		if !ok || key == "" || key == "-" {
+			continue
+		}
+
+		typ := bytes.Buffer{}
+
+		if err := format.Node(&typ, fset, field.Type); err != nil {
+			return e, false, err
+		}
+
+		for _, name := range field.Names {
+			e.Params = append(e.Params, param{Key: key, Name: paramName(name.Name), Type: typ.String()})
+		}
+	}
+
+	return e, true, nil
+}
+
+// paramName converts a field name to a lower camel case parameter name that does not collide with keywords or the generated code
+func paramName(field string) string {
+	r := []rune(field)
+
+	for i := 0; i < len(r) && unicode.IsUpper(r[i]); i++ {
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break // leave the first letter of the next word, such as the 'R' in 'HTTPRequest', upper case
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+
+	name := string(r)
+
+	if token.IsKeyword(name) || reserved[name] {
+		name += "_"
+	}
+
+	return name
+}
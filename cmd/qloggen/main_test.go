@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	schema := "//go:build qloggen\n\n" + `package logx
+
+// RequestCompleted is written when a request has been served
+//
+//qlog:event info request "completed"
+type RequestCompleted struct {
+	Method   string ` + "`qlog:\"method\"`" + `
+	Status   int    ` + "`qlog:\"status\"`" + `
+	DurMs    int    ` + "`qlog:\"dur_ms\"`" + `
+	HTTPType string ` + "`qlog:\"type\"`" + `
+	Type     string ` + "`qlog:\"kind\"`" + `
+	Ignored  bool
+}
+
+//qlog:event error request failed
+type RequestFailed struct {
+	Path string ` + "`qlog:\"path\"`" + `
+}
+
+type NotAnEvent struct {
+	Path string ` + "`qlog:\"path\"`" + `
+}
+`
+
+	code, err := generate("schema.go", []byte(schema))
+
+	if err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	expected := []string{
+		"// Code generated by qloggen. DO NOT EDIT.",
+		"package logx",
+		"// RequestCompleted is written when a request has been served\nfunc RequestCompleted(ctx context.Context, method string, status int, durMs int, httpType string, type_ string) {",
+		`qlog.Info(ctx, "request \"completed\"", "method", method, "status", status, "dur_ms", durMs, "type", httpType, "kind", type_)`,
+		"func RequestFailed(ctx context.Context, err error, path string) {",
+		`qlog.Error(ctx, "request failed", err, "path", path)`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(code), e) {
+			t.Fatalf("expected generated code to contain '%s' but got\n%s", e, code)
+		}
+	}
+
+	if strings.Contains(string(code), "NotAnEvent") {
+		t.Fatalf("expected struct without directive to be ignored but got\n%s", code)
+	}
+
+	if _, err := generate("schema.go", []byte("package logx\n\n//qlog:event loud message\ntype E struct{}\n")); err == nil {
+		t.Fatalf("expected error for unknown severity")
+	}
+}
@@ -0,0 +1,231 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RepeatCountLabel is the key of the label, written on the log that summarises consecutive identical logs, of the
+// number of times it was repeated
+const RepeatCountLabel = "repeat_count"
+
+// CoalescingWriter is an EntryWriter that replaces consecutive identical logs, those with the same severity, message,
+// error and labels, with a single log carrying a `repeat_count` label, in the manner of syslog's "last message
+// repeated N times". This prevents a log written in a tight loop, such as a retry of a failing dependency, from
+// flooding its destination.
+//
+// The first of a run of identical logs is written immediately. Those that follow are held, and replaced by the last of
+// them, with a `repeat_count` of the number held, once a different log is written or once the window has elapsed
+// since the first was held, so no log is delayed by more than the window. Fatal logs are never held.
+//
+// Logs written with Write, rather than WriteEntry, cannot be compared, so are written immediately
+type CoalescingWriter struct {
+	w      io.Writer
+	window time.Duration
+	mx     sync.Mutex
+	key    uint64 // the hash of the last log written
+	last   []byte // the last of the logs held, with its repeat_count
+	count  int
+	timer  *time.Timer
+	err    error // the error from writing the held log when the window elapsed, if any
+	closed bool
+}
+
+// NewCoalescingWriter creates a CoalescingWriter that writes to w, holding consecutive identical logs for up to the
+// window before writing the log that replaces them
+func NewCoalescingWriter(w io.Writer, window time.Duration) *CoalescingWriter {
+	return &CoalescingWriter{
+		w:      w,
+		window: window,
+	}
+}
+
+// Write writes b, after any log that is held
+func (cw *CoalescingWriter) Write(b []byte) (int, error) {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if err := cw.flush(); err != nil {
+		return 0, err
+	}
+
+	cw.key = 0
+
+	return cw.w.Write(b)
+}
+
+// WriteEntry writes b, or holds it if it is identical to the log written before it
+func (cw *CoalescingWriter) WriteEntry(e Entry, b []byte) (int, error) {
+	key := hashEntry(e)
+
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if err := cw.err; err != nil {
+		cw.err = nil
+		return 0, err
+	}
+
+	if key == cw.key && !cw.closed && e.Flag&OutputFlagFatal == 0 {
+		cw.count++
+		cw.last = repeated(cw.last[:0], b, cw.count, e.log)
+
+		if cw.timer == nil {
+			cw.timer = time.AfterFunc(cw.window, cw.elapsed)
+		}
+
+		return len(b), nil
+	}
+
+	if err := cw.flush(); err != nil {
+		return 0, err
+	}
+
+	cw.key = key
+
+	return cw.w.Write(b)
+}
+
+// Unwrap returns the underlying Writer
+func (cw *CoalescingWriter) Unwrap() io.Writer {
+	return cw.w
+}
+
+// Flush writes any log that is held. It does not block on ctx, which is accepted so that CoalescingWriter is flushed
+// by the package level Flush
+func (cw *CoalescingWriter) Flush(ctx context.Context) error {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if err := cw.err; err != nil {
+		cw.err = nil
+		return err
+	}
+
+	return cw.flush()
+}
+
+// Close writes any log that is held and stops coalescing. Logs written after Close are written immediately.
+// If the underlying Writer is an io.Closer, it is not closed.
+func (cw *CoalescingWriter) Close() error {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	cw.closed = true
+
+	return cw.flush()
+}
+
+// SetCoalescing sets the Writer of the default logger to a CoalescingWriter that writes to its current Writer, replacing
+// consecutive identical logs with a single log carrying a `repeat_count` label, with none held for longer than the window.
+//
+// Call Close before the process exits, such as with a defer in main, so that held logs are not lost.
+// This operation is safe for concurrent use.
+func SetCoalescing(window time.Duration) {
+	configure(func(l *Log) { l.Writer = NewCoalescingWriter(l.Writer, window) })
+}
+
+// elapsed writes the held log once the window has elapsed since the first was held. Identical logs that follow are
+// held again, so a log that repeats indefinitely is summarised once per window
+func (cw *CoalescingWriter) elapsed() {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	cw.timer = nil // set before flushing, which stops any timer it finds
+
+	if err := cw.flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+}
+
+// flush writes the held log, the caller must hold mx
+func (cw *CoalescingWriter) flush() error {
+	if cw.timer != nil {
+		cw.timer.Stop()
+		cw.timer = nil
+	}
+
+	if cw.count == 0 {
+		return nil
+	}
+
+	n, err := cw.w.Write(cw.last)
+
+	if err == nil && n < len(cw.last) {
+		err = io.ErrShortWrite
+	}
+
+	cw.count = 0
+
+	return err
+}
+
+// hashEntry returns a hash of the severity, message, error and labels of the log described by e. Its time and trace
+// are excluded, as they differ between otherwise identical logs
+func hashEntry(e Entry) uint64 {
+	h := fnv.New64a()
+	b := make([]byte, 0, 256)
+
+	b = append(b, e.Logger...)
+	b = append(b, 0)
+	b = append(b, e.Severity...)
+	b = append(b, 0)
+	b = append(b, e.Message...)
+	b = append(b, 0)
+
+	if e.Error != nil {
+		b = append(b, e.Error.Error()...)
+	}
+
+	labels := e.AllLabels()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		if key, ok := labelKey(labels[i]); ok {
+			b = append(b, 0)
+			b = append(b, key...)
+			b = appendValue(b, labels[i+1])
+		}
+	}
+
+	h.Write(b)
+
+	return h.Sum64()
+}
+
+// repeated appends log, as encoded by l, to b with a repeat_count label of n, placed last other than any checksum,
+// which is recalculated to include it
+func repeated(b, log []byte, n int, l *Log) []byte {
+	openField, closeField, closeLog := ` `, `=`, ``
+
+	if l != nil && l.outputJSON {
+		openField, closeField, closeLog = `, "`, `": `, ` }`
+	}
+
+	end := len(log) - len(closeLog) - 1 // excludes the close of the log and its newline
+	checksum := l != nil && l.Checksum
+
+	if checksum {
+		if i := bytes.LastIndex(log[:end], []byte(openField+"checksum"+closeField)); i >= 0 {
+			end = i
+		}
+	}
+
+	start := len(b)
+
+	b = append(b, log[:end]...)
+	b = appendField(b, openField, RepeatCountLabel, closeField)
+	b = strconv.AppendInt(b, int64(n), 10)
+
+	if checksum {
+		b = appendChecksum(b, b[start:], openField, closeField)
+	}
+
+	b = append(b, closeLog...)
+
+	return append(b, '\n')
+}
@@ -0,0 +1,74 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoalescingWriter(t *testing.T) {
+	for _, outputJSON := range []bool{false, true} {
+		w := &strings.Builder{}
+		cw := NewCoalescingWriter(w, time.Hour)
+		l := New(OutputMaskAll, outputJSON, "app", "test").WithWriter(cw)
+		l.Checksum = true
+
+		ctx := context.Background()
+
+		for i := 0; i < 3; i++ {
+			l.Error(ContextFrom(ctx, ""), "connection failed", errors.New("refused"), "host", "db")
+		}
+
+		l.Error(ctx, "connection failed", errors.New("refused"), "host", "cache")
+		l.Info(ctx, "connection failed", "host", "cache")
+		l.Info(ctx, "connection failed", "host", "cache")
+
+		if err := cw.Flush(ctx); err != nil {
+			t.Fatalf("json %v: expected no error but got %v", outputJSON, err)
+		}
+
+		logs := strings.SplitAfter(strings.TrimSpace(w.String()), "\n")
+
+		if len(logs) != 5 {
+			t.Fatalf("json %v: expected 5 logs but got %v: %v", outputJSON, len(logs), w.String())
+		}
+
+		for i, expected := range []string{"", "2", "", "", "1"} {
+			if expected == "" && strings.Contains(logs[i], RepeatCountLabel) {
+				t.Fatalf("json %v: expected log %v to have no %v but got '%v'", outputJSON, i, RepeatCountLabel, logs[i])
+			}
+
+			label := RepeatCountLabel + "=" + expected
+
+			if outputJSON {
+				label = `"` + RepeatCountLabel + `": ` + expected
+			}
+
+			if expected != "" && !strings.Contains(logs[i], label) {
+				t.Fatalf("json %v: expected log %v to have a %v of %v but got '%v'", outputJSON, i, RepeatCountLabel, expected, logs[i])
+			}
+
+			if !VerifyChecksum([]byte(strings.TrimSpace(logs[i]))) {
+				t.Fatalf("json %v: expected log %v to have a valid checksum but got '%v'", outputJSON, i, logs[i])
+			}
+		}
+	}
+}
+
+func TestCoalescingWriterWindow(t *testing.T) {
+	w := &syncBuffer{}
+	cw := NewCoalescingWriter(w, time.Millisecond)
+	l := New(OutputMaskAll, false).WithWriter(cw)
+
+	for i := 0; i < 3; i++ {
+		l.Info(context.Background(), "polling")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if logs := strings.Split(strings.TrimSpace(string(w.Bytes())), "\n"); len(logs) != 2 || !strings.HasSuffix(logs[1], RepeatCountLabel+"=2") {
+		t.Fatalf("expected the held logs to be written once the window elapsed but got '%s'", w.Bytes())
+	}
+}
@@ -0,0 +1,158 @@
+package qlog
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Compressor is a streaming compressor, such as a *gzip.Writer or a zstd encoder, that writes compressed data to an
+// underlying Writer. Flush writes any pending data such that all data written so far can be decompressed, and Close
+// writes any footer required to complete the stream
+type Compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressingWriter is an io.Writer that streams logs through a Compressor before they reach the underlying Writer,
+// such as a file or socket, reducing the storage required for highly compressible archival logs.
+//
+// The Compressor is flushed once the interval has elapsed since the first log written after the previous flush, so
+// that a tail of the compressed stream, such as with `zcat`, is never more than the interval behind. Fatal logs are
+// flushed immediately.
+//
+// Close must be called to complete the compressed stream, such as with a defer in main.
+type CompressingWriter struct {
+	c        Compressor
+	w        io.Writer // the Writer the Compressor writes to, if known
+	interval time.Duration
+	mx       sync.Mutex
+	timer    *time.Timer
+	closed   bool
+	err      error // the error from a flush when the interval elapsed, if any
+}
+
+// NewCompressingWriter creates a CompressingWriter that writes logs to the Compressor, flushing it at the interval
+//
+// For example, with a zstd encoder:
+//
+//	enc, _ := zstd.NewWriter(file)
+//	qlog.SetWriter(qlog.NewCompressingWriter(enc, time.Second))
+func NewCompressingWriter(c Compressor, interval time.Duration) *CompressingWriter {
+	return &CompressingWriter{c: c, interval: interval}
+}
+
+// NewGzipWriter creates a CompressingWriter that writes logs to w compressed with gzip, flushing it at the interval
+func NewGzipWriter(w io.Writer, interval time.Duration) *CompressingWriter {
+	cw := NewCompressingWriter(gzip.NewWriter(w), interval)
+	cw.w = w
+
+	return cw
+}
+
+// Unwrap returns the Writer the Compressor writes to, if it was created by NewGzipWriter, otherwise nil
+func (cw *CompressingWriter) Unwrap() io.Writer {
+	return cw.w
+}
+
+// Write compresses b
+func (cw *CompressingWriter) Write(b []byte) (int, error) {
+	return cw.WriteSeverity(OutputFlagNone, b)
+}
+
+// WriteSeverity compresses b, flushing the Compressor immediately if the severity flag is Fatal
+func (cw *CompressingWriter) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if err := cw.err; err != nil {
+		cw.err = nil
+		return 0, err
+	}
+
+	if cw.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err := cw.c.Write(b)
+
+	if err != nil {
+		return n, err
+	}
+
+	if flag&OutputFlagFatal != 0 {
+		return n, cw.flush()
+	}
+
+	if cw.timer == nil {
+		cw.timer = time.AfterFunc(cw.interval, cw.elapsed)
+	}
+
+	return n, nil
+}
+
+// Flush flushes the Compressor, so that all logs written so far can be decompressed from the underlying Writer. It
+// does not block on ctx, which is accepted so that CompressingWriter is flushed by the package level Flush
+func (cw *CompressingWriter) Flush(ctx context.Context) error {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if err := cw.err; err != nil {
+		cw.err = nil
+		return err
+	}
+
+	if cw.closed {
+		return nil
+	}
+
+	return cw.flush()
+}
+
+// Close closes the Compressor, completing the compressed stream. Subsequent writes fail with io.ErrClosedPipe.
+// If the underlying Writer is an io.Closer, it is not closed.
+func (cw *CompressingWriter) Close() error {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	if cw.closed {
+		return nil
+	}
+
+	cw.closed = true
+
+	if cw.timer != nil {
+		cw.timer.Stop()
+		cw.timer = nil
+	}
+
+	return cw.c.Close()
+}
+
+// elapsed flushes the Compressor once the interval has elapsed since the first log written after the previous flush
+func (cw *CompressingWriter) elapsed() {
+	cw.mx.Lock()
+	defer cw.mx.Unlock()
+
+	cw.timer = nil
+
+	if cw.closed {
+		return
+	}
+
+	if err := cw.flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+}
+
+// flush flushes the Compressor, the caller must hold mx
+func (cw *CompressingWriter) flush() error {
+	if cw.timer != nil {
+		cw.timer.Stop()
+		cw.timer = nil
+	}
+
+	return cw.c.Flush()
+}
@@ -0,0 +1,86 @@
+package qlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mx sync.Mutex
+	bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(b []byte) (int, error) {
+	sb.mx.Lock()
+	defer sb.mx.Unlock()
+
+	return sb.Buffer.Write(b)
+}
+
+func (sb *syncBuffer) Bytes() []byte {
+	sb.mx.Lock()
+	defer sb.mx.Unlock()
+
+	return append([]byte(nil), sb.Buffer.Bytes()...)
+}
+
+// decompress returns as much of the gzip stream in b as can be decompressed, which is all of it once flushed
+func decompress(t *testing.T, b []byte) string {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+
+	if err != nil {
+		t.Fatalf("expected a gzip stream but got '%v'", err)
+	}
+
+	out := bytes.Buffer{}
+	io.Copy(&out, gz) // an incomplete stream ends with io.ErrUnexpectedEOF, once all flushed data has been read
+
+	return out.String()
+}
+
+func TestGzipWriter(t *testing.T) {
+	sb := &syncBuffer{}
+	cw := NewGzipWriter(sb, 20*time.Millisecond)
+
+	l := New(OutputMaskAll, false)
+	l.Writer = cw
+
+	l.Info(context.Background(), "log1")
+	l.Info(context.Background(), "log2")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if logs := decompress(t, sb.Bytes()); strings.Count(logs, "\n") != 2 || !strings.Contains(logs, `message="log2"`) {
+		t.Fatalf("expected the logs to be flushed once the interval elapsed but got '%v'", logs)
+	}
+
+	l.Info(context.Background(), "log3")
+
+	if err := cw.Flush(context.Background()); err != nil {
+		t.Fatalf("expected no error flushing but got '%v'", err)
+	}
+
+	if logs := decompress(t, sb.Bytes()); !strings.Contains(logs, `message="log3"`) {
+		t.Fatalf("expected the logs to be flushed but got '%v'", logs)
+	}
+
+	if err := cw.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	gz, _ := gzip.NewReader(bytes.NewReader(sb.Bytes()))
+
+	if b, err := io.ReadAll(gz); err != nil || strings.Count(string(b), "\n") != 3 {
+		t.Fatalf("expected a complete gzip stream once closed but got '%v' and '%v'", string(b), err)
+	}
+
+	if _, err := cw.Write([]byte("closed\n")); err == nil {
+		t.Fatalf("expected writes after close to fail")
+	}
+}
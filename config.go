@@ -0,0 +1,104 @@
+package qlog
+
+import (
+	"fmt"
+	"io"
+)
+
+// Config is a snapshot of the effective configuration of a Log, suitable for embedding in support bundles or
+// writing in a start-up banner. It is a copy, so it is unaffected by subsequent changes to the configuration
+type Config struct {
+	// OutputMask is the OutputMask in the format accepted by ParseOutputMask
+	OutputMask string `json:"output_mask"`
+	// Format is the Format in the format accepted by ParseFormat
+	Format string `json:"format"`
+	// Labels are the labels included in all logs, in key, value pairs
+	Labels []any `json:"labels"`
+	// Writer describes the destination of logs
+	Writer string `json:"writer"`
+	// AuditWriter describes the destination of Audit logs
+	AuditWriter string `json:"audit_writer"`
+	// FallbackWriter describes the destination of logs that could not be written
+	FallbackWriter string `json:"fallback_writer"`
+	// TimestampFormat is the effective format of timestamps
+	TimestampFormat string `json:"timestamp_format"`
+	// TraceIDFieldName is the effective key of the Trace-ID
+	TraceIDFieldName string `json:"trace_id_field_name"`
+	// SeverityFieldName, TimestampFieldName and MessageFieldName are the effective keys of the severity, timestamp
+	// and message
+	SeverityFieldName  string `json:"severity_field_name"`
+	TimestampFieldName string `json:"timestamp_field_name"`
+	MessageFieldName   string `json:"message_field_name"`
+	// Hooks is the number of registered Hooks
+	Hooks int `json:"hooks"`
+	// Checksum is whether a checksum label is written on each log
+	Checksum bool `json:"checksum"`
+	// BlobOffloadKeys are the keys of labels whose large values are offloaded to a BlobStore
+	BlobOffloadKeys []string `json:"blob_offload_keys,omitempty"`
+	// BlobOffloadThreshold is the length, in bytes, above which values are offloaded
+	BlobOffloadThreshold int `json:"blob_offload_threshold,omitempty"`
+}
+
+// Config returns a snapshot of the effective configuration of the Log
+func (l *Log) Config() Config {
+	c := Config{
+		OutputMask:       l.OutputMask().String(),
+		Format:           FormatJSON.String(),
+		Labels:           l.Labels(),
+		Writer:           describeWriter(l.Writer),
+		AuditWriter:      describeWriter(l.Writer), // audit logs are written to the Writer unless an AuditWriter is set
+		FallbackWriter:   describeWriter(l.FallbackWriter),
+		TimestampFormat:  TimestampFormat,
+		TraceIDFieldName: TraceIDFieldName,
+		Hooks:            len(l.hooks),
+		Checksum:         l.Checksum,
+	}
+
+	for i := 1; i < len(c.Labels); i += 2 {
+		c.Labels[i] = resolve(c.Labels[i]) // lazy values are snapshotted as their current value
+	}
+
+	if !l.outputJSON {
+		c.Format = FormatLogfmt.String()
+	}
+
+	if l.AuditWriter != nil {
+		c.AuditWriter = describeWriter(l.AuditWriter)
+	}
+
+	if l.TimestampFormat != "" {
+		c.TimestampFormat = l.TimestampFormat
+	}
+
+	if l.TraceIDFieldName != "" {
+		c.TraceIDFieldName = l.TraceIDFieldName
+	}
+
+	c.SeverityFieldName, c.TimestampFieldName, c.MessageFieldName = l.fieldNames()
+
+	if l.BlobOffload != nil {
+		c.BlobOffloadKeys = append([]string(nil), l.BlobOffload.Keys...)
+		c.BlobOffloadThreshold = l.BlobOffload.Threshold
+	}
+
+	return c
+}
+
+// ConfigSnapshot returns a snapshot of the effective configuration of the default logger
+// This operation is safe for concurrent use.
+func ConfigSnapshot() Config {
+	return defaultLog.Load().Config()
+}
+
+// describeWriter returns the type of w along with its name, such as the path of a file, if it has one
+func describeWriter(w io.Writer) string {
+	if w == nil {
+		return "none"
+	}
+
+	if n, ok := w.(interface{ Name() string }); ok {
+		return fmt.Sprintf("%T(%v)", w, n.Name())
+	}
+
+	return fmt.Sprintf("%T", w)
+}
@@ -0,0 +1,50 @@
+package qlog
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestConfigSnapshot(t *testing.T) {
+	SetOutputFormat(FormatLogfmt)
+	SetOutputMask(OutputMaskImportant)
+	SetLabels("app", "test", "version", func() string { return "1.0" })
+
+	defer func() {
+		SetOutputFormat(FormatJSON)
+		SetOutputMask(OutputMaskAll)
+		SetLabels()
+	}()
+
+	c := ConfigSnapshot()
+
+	expected := Config{
+		OutputMask:         "fatal|error|warning|notice",
+		Format:             "logfmt",
+		Labels:             []any{"app", "test", "version", "1.0"},
+		Writer:             "*os.File(" + os.Stderr.Name() + ")",
+		AuditWriter:        "*os.File(" + os.Stderr.Name() + ")",
+		FallbackWriter:     "none",
+		TimestampFormat:    TimestampFormat,
+		TraceIDFieldName:   TraceIDFieldName,
+		SeverityFieldName:  SeverityFieldName,
+		TimestampFieldName: TimestampFieldName,
+		MessageFieldName:   MessageFieldName,
+	}
+
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("expected config '%+v' but got '%+v'", expected, c)
+	}
+
+	SetOutputMask(OutputMaskAll)
+
+	if c.OutputMask != "fatal|error|warning|notice" {
+		t.Fatalf("expected snapshot to be unaffected by subsequent changes but got '%v'", c.OutputMask)
+	}
+
+	if _, err := json.Marshal(c); err != nil {
+		t.Fatalf("expected snapshot to be serialisable but got '%v'", err)
+	}
+}
@@ -0,0 +1,96 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	crashMx   = sync.Mutex{}
+	crashRing *tailBuffer
+	crashing  = atomic.Bool{} // allows the logs that are not written to be discarded without acquiring a lock when no ring is set
+)
+
+// SetCrashContext holds the most recent size logs that are not written, as their severity is not enabled by the
+// OutputMask or level spec, in an in-memory ring. The ring is written when a Fatal log is written, before it, or when a
+// panic is recovered by RecoverCrash. This recovers the detail leading up to a crash without writing Debug logs at all
+// times.
+//
+// As logs of every severity are encoded to be held, lazy label values are evaluated regardless of the verbosity, and
+// the cost of each log is that of writing it, other than the write itself. Pass zero to disable the ring.
+// This operation is safe for concurrent use.
+func SetCrashContext(size int) {
+	crashMx.Lock()
+	defer crashMx.Unlock()
+
+	crashRing = nil
+
+	if size > 0 {
+		crashRing = &tailBuffer{logs: make([]tailLog, size)}
+	}
+
+	crashing.Store(crashRing != nil)
+}
+
+// RecoverCrash recovers a panic, writes the logs held by the crash context ring, followed by an error log describing
+// the panic, then panics again with the recovered value, so that the process crashes, with its stack trace, as it would
+// have otherwise. It must be deferred directly, such as at the top of main, or of a goroutine.
+//
+// For example:
+//
+//	func main() {
+//		qlog.SetCrashContext(1000)
+//		defer qlog.RecoverCrash()
+//		...
+//	}
+func RecoverCrash() {
+	v := recover()
+
+	if v == nil {
+		return
+	}
+
+	dumpCrash()
+	Error(context.Background(), "panic", fmt.Errorf("%v", v))
+
+	panic(v)
+}
+
+// holdCrash encodes a log that is not written and holds it in the crash context ring
+func (l *Log) holdCrash(r Record) {
+	d := *l
+	d.BlobOffload = nil // the log may never be written, so its values are not offloaded
+	r.log = &d
+
+	b, e, hooked := d.encode(nil, r)
+
+	crashMx.Lock()
+	defer crashMx.Unlock()
+
+	if crashRing != nil {
+		crashRing.push(tailLog{l: l, flag: r.Flag, e: e, b: b, hooked: hooked})
+	}
+}
+
+// dumpCrash writes the logs held by the crash context ring, in the order they were held
+func dumpCrash() {
+	if !crashing.Load() {
+		return
+	}
+
+	crashMx.Lock()
+
+	var logs []tailLog
+
+	if crashRing != nil {
+		logs = crashRing.drain()
+	}
+
+	crashMx.Unlock()
+
+	for _, t := range logs {
+		t.l.emit(t.flag, t.e, t.b, t.hooked)
+	}
+}
@@ -0,0 +1,77 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCrashContext(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	defer SetCrashContext(0)
+
+	sb := strings.Builder{}
+	l := New(OutputMaskImportant, false).WithWriter(&sb)
+	l.FatalFunc = func() {}
+	ctx := context.Background()
+
+	SetCrashContext(2)
+
+	for _, message := range []string{"first", "second", "third"} {
+		l.Debug(ctx, message)
+	}
+
+	l.Info(ctx, "fourth")
+	l.Error(ctx, "failed", nil)
+
+	if output := sb.String(); strings.Count(output, "\n") != 1 || !strings.Contains(output, "failed") {
+		t.Fatalf("expected only the error to be written before a crash but got '%v'", output)
+	}
+
+	l.Fatal(ctx, "crashed", nil)
+
+	logs := strings.Split(strings.TrimSpace(sb.String()), "\n")
+
+	for i, expected := range []string{"failed", "third", "fourth", "crashed"} {
+		if len(logs) != 4 || !strings.Contains(logs[i], `message="`+expected+`"`) {
+			t.Fatalf("expected the most recent logs that were not written to precede the fatal log but got '%v'", sb.String())
+		}
+	}
+}
+
+func TestRecoverCrash(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	defer resetDefaultLog()()
+	defer SetCrashContext(0)
+
+	sb := strings.Builder{}
+	SetWriter(&sb)
+	SetOutputFormat(FormatLogfmt)
+	SetCrashContext(10)
+
+	Debug(context.Background(), "before the panic")
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("expected the panic to be raised again but got '%v'", v)
+			}
+		}()
+
+		defer RecoverCrash()
+
+		panic("boom")
+	}()
+
+	logs := strings.Split(strings.TrimSpace(sb.String()), "\n")
+
+	if len(logs) != 2 || !strings.Contains(logs[0], "before the panic") || !strings.Contains(logs[1], `error="boom" message="panic"`) {
+		t.Fatalf("expected the crash context to be written before the panic but got '%v'", sb.String())
+	}
+}
@@ -0,0 +1,72 @@
+package qlog
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Datadog returns an Option that correlates the logs of the derived Log with Datadog APM traces. Each log has
+// `dd.trace_id` and `dd.span_id` labels, holding the Trace-ID and Span-ID in Datadog's unsigned 64-bit decimal format,
+// along with `service`, `env` and `version` labels, so that Datadog links logs to traces and services automatically.
+//
+// Where service, env or version are empty, they default to the DD_SERVICE, DD_ENV and DD_VERSION environment variables,
+// as set by the Datadog agent's unified service tagging, and are omitted if those are also empty. Trace and Span-IDs
+// that cannot be expressed in Datadog's format are omitted
+func Datadog(service, env, version string) Option {
+	return func(l *Log) {
+		labels := []any{}
+
+		for _, tag := range [][3]string{{"service", service, "DD_SERVICE"}, {"env", env, "DD_ENV"}, {"version", version, "DD_VERSION"}} {
+			if tag[1] == "" {
+				tag[1] = os.Getenv(tag[2])
+			}
+
+			if tag[1] != "" {
+				labels = append(labels, tag[0], tag[1])
+			}
+		}
+
+		Labels(labels...)(l)
+		l.extractors = append(l.extractors[:len(l.extractors):len(l.extractors)], datadogIDs)
+	}
+}
+
+// PresetDatadog configures the default logger to correlate its logs with Datadog APM traces, see Datadog.
+// This operation is safe for concurrent use.
+func PresetDatadog(service, env, version string) {
+	configure(func(l *Log) { Datadog(service, env, version)(l) })
+}
+
+// datadogIDs is an extractor that returns the Trace and Span-IDs in Datadog's format, where they can be expressed in it
+func datadogIDs(ctx context.Context, traceID string) []any {
+	labels := make([]any, 0, 4)
+
+	if id, ok := datadogID(traceID); ok {
+		labels = append(labels, "dd.trace_id", id)
+	}
+
+	if id, ok := datadogID(SpanID(ctx)); ok {
+		labels = append(labels, "dd.span_id", id)
+	}
+
+	return labels
+}
+
+// datadogID returns id as an unsigned 64-bit decimal. IDs that are decimal, such as those generated by qlog, are used as
+// they are, without any padding. IDs that are hexadecimal, such as W3C or AWS X-Ray IDs, are truncated to their lower
+// 64 bits, as Datadog does with 128-bit IDs
+func datadogID(id string) (string, bool) {
+	if n, err := strconv.ParseUint(strings.TrimRight(id, "X"), 10, 64); err == nil && id != "" {
+		return strconv.FormatUint(n, 10), true
+	}
+
+	if hex := strings.ReplaceAll(id, "-", ""); len(hex) >= 16 {
+		if n, err := strconv.ParseUint(hex[len(hex)-16:], 16, 64); err == nil {
+			return strconv.FormatUint(n, 10), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,49 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDatadogID(t *testing.T) {
+	type testCase struct {
+		ID       string
+		Expected string
+		OK       bool
+	}
+
+	for name, tc := range map[string]testCase{
+		"decimal":        {ID: "1234567890123456789", Expected: "1234567890123456789", OK: true},
+		"padded decimal": {ID: "12345XXXXXXXXXXXXXX", Expected: "12345", OK: true},
+		"64-bit hex":     {ID: "00f067aa0ba902b7", Expected: "67667974448284343", OK: true},
+		"128-bit hex":    {ID: "4bf92f3577b34da6a3ce929d0e0e4736", Expected: "11803532876627986230", OK: true},
+		"x-ray":          {ID: "1-5759e988-bd862e3fe1be46a994272793", Expected: "16266516598257821587", OK: true},
+		"empty":          {ID: ""},
+		"other":          {ID: "request-1"},
+	} {
+		if actual, ok := datadogID(tc.ID); actual != tc.Expected || ok != tc.OK {
+			t.Fatalf("%v: expected '%v' (%v) but got '%v' (%v)", name, tc.Expected, tc.OK, actual, ok)
+		}
+	}
+}
+
+func TestDatadog(t *testing.T) {
+	t.Setenv("DD_ENV", "staging")
+
+	spanID := SpanID
+	SpanID = func(ctx context.Context) string { return "00f067aa0ba902b7" }
+	defer func() { SpanID = spanID }()
+
+	buf := &bytes.Buffer{}
+	l := New(OutputMaskAll, false).With(Writer(buf), Datadog("checkout", "", ""))
+
+	l.Info(ContextFrom(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736"), "correlated")
+
+	expected := `service="checkout" env="staging" dd.trace_id="11803532876627986230" dd.span_id="67667974448284343" message="correlated"`
+
+	if log := buf.String(); !strings.Contains(log, expected) || strings.Contains(log, "version=") {
+		t.Fatalf("expected log containing '%v' but got '%v'", expected, log)
+	}
+}
@@ -0,0 +1,132 @@
+//go:build !qlog_nodebug
+
+package qlog
+
+import "context"
+
+// DebugEnabled reports whether Debug and Trace logs are compiled into the binary, see the qlog_nodebug build tag.
+// Under the tag, Debug and Trace are empty funcs, but their arguments are still evaluated by the caller. Call sites
+// whose arguments are costly to build can be guarded by DebugEnabled, a constant, so that the compiler removes them:
+//
+//	if qlog.DebugEnabled {
+//		qlog.Debug(ctx, "request", "body", string(body))
+//	}
+const DebugEnabled = true
+
+// Writes a log with debug severity and a label of trace=true to the default log
+// trace is reserved for emitting data about IO within a process's internal activity, such as the content of requests received or generated
+//
+// Any number of labels can be provided but they must be given in key, value pairs
+// where each key is a string. Values may be of any type or expressed as a func() T.
+//
+// For example:
+//
+//	qlog.Trace(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//
+// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
+// is such that the log will be written. Use this to prevent needless evaluation
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func Trace(ctx context.Context, message string, labels ...any) {
+	defaultLog.Load().Trace(ctx, message, labels...)
+}
+
+// Writes a log with debug severity to the default log
+// Debug is reserved for emitting low level detail about a process's internal activity, such as config data or current variable states
+//
+// Any number of labels can be provided but they must be given in key, value pairs
+// where each key is a string. Values may be of any type or expressed as a func() T.
+//
+// For example:
+//
+//	qlog.Debug(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//
+// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
+// is such that the log will be written. Use this to prevent needless evaluation
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func Debug(ctx context.Context, message string, labels ...any) {
+	defaultLog.Load().Debug(ctx, message, labels...)
+}
+
+// Writes a log with debug severity and a label of trace=true
+// Trace is reserved for emitting data about IO within a process's internal activity, such as the content of requests received or generated
+//
+// Any number of labels can be provided but they must be given in key, value pairs
+// where each key is a string. Values may be of any type or expressed as a func() T.
+//
+// For example:
+//
+//	logger.Trace(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//
+// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
+// is such that the log will be written. Use this to prevent needless evaluation
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func (l *Log) Trace(ctx context.Context, message string, labels ...any) {
+	if !l.enabled(ctx, OutputFlagTrace) {
+		return
+	}
+
+	l.log(ctx, OutputFlagTrace, "DEBUG", message, nil, append(labels, "trace", true)...)
+}
+
+// Writes a log with debug severity to the default log
+// Debug is reserved for emitting low level detail about a process's internal activity, such as config data or current variable states
+//
+// Any number of labels can be provided but they must be given in key, value pairs
+// where each key is a string. Values may be of any type or expressed as a func() T.
+//
+// For example:
+//
+//	qlog.Debug(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//
+// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
+// is such that the log will be written. Use this to prevent needless evaluation
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func (l *Log) Debug(ctx context.Context, message string, labels ...any) {
+	if !l.enabled(ctx, OutputFlagDebug) {
+		return
+	}
+
+	l.log(ctx, OutputFlagDebug, "DEBUG", message, nil, labels...)
+}
+
+// Trace writes a log with debug severity and a label of trace=true, see Log.Trace
+func (bl *BoundLog) Trace(message string, labels ...any) {
+	bl.l.Trace(bl.ctx, message, labels...)
+}
+
+// Debug writes a log with debug severity, see Log.Debug
+func (bl *BoundLog) Debug(message string, labels ...any) {
+	bl.l.Debug(bl.ctx, message, labels...)
+}
+
+// Trace adds a log with debug severity and a label of trace=true to the Batch, see Log.Trace
+func (bt *Batch) Trace(message string, labels ...any) {
+	bt.add(OutputFlagTrace, "DEBUG", message, nil, append(labels, "trace", true))
+}
+
+// Debug adds a log with debug severity to the Batch, see Log.Debug
+func (bt *Batch) Debug(message string, labels ...any) {
+	bt.add(OutputFlagDebug, "DEBUG", message, nil, labels)
+}
+
+// Trace adds a log with debug severity and a label of trace=true to the Deferred, see Log.Trace
+func (d *Deferred) Trace(message string, labels ...any) {
+	d.add(OutputFlagTrace, "DEBUG", message, nil, append(labels, "trace", true))
+}
+
+// Debug adds a log with debug severity to the Deferred, see Log.Debug
+func (d *Deferred) Debug(message string, labels ...any) {
+	d.add(OutputFlagDebug, "DEBUG", message, nil, labels)
+}
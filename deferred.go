@@ -0,0 +1,143 @@
+package qlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Deferred holds logs for a speculative operation, writing them only if the operation is not resolved as successful
+// within a timeout. This supports the "log only if this didn't succeed within X" pattern without hand-rolled timers.
+//
+// Logs are held as they are added, and only filtered, sampled, counted against any budgets or quota, and encoded, with
+// any lazy label values evaluated, once they are to be written, so that logs discarded by a successful operation leave
+// no trace. Their timestamps reflect when they were added rather than written. Once the held logs have been written,
+// subsequent logs are written immediately; once resolved as successful, subsequent logs are discarded. A Deferred is
+// safe for concurrent use.
+type Deferred struct {
+	mx    sync.Mutex
+	l     *Log
+	ctx   context.Context
+	held  []deferredLog
+	timer *time.Timer
+	state deferredState
+}
+
+// deferredLog is a log held by a Deferred, as it was added
+type deferredLog struct {
+	at       time.Time
+	flag     OutputMask
+	severity string
+	message  string
+	err      error
+	labels   []any
+}
+
+type deferredState int
+
+const (
+	deferredPending deferredState = iota
+	deferredWritten
+	deferredCancelled
+)
+
+// Defer creates a Deferred that writes the logs added to it, each with the specified ctx, unless Resolve is called
+// with success within the timeout
+//
+// For example:
+//
+//	d := logger.Defer(ctx, 2*time.Second)
+//	d.Warning("payment not yet confirmed", nil, "order", orderID)
+//
+//	err := confirm(ctx, orderID)
+//	d.Resolve(err == nil) // the warning is only written if confirmation failed, or took longer than 2 seconds
+func (l *Log) Defer(ctx context.Context, timeout time.Duration) *Deferred {
+	d := &Deferred{l: l, ctx: ctx}
+	d.timer = time.AfterFunc(timeout, func() { d.Resolve(false) })
+
+	return d
+}
+
+// Defer creates a Deferred that writes the logs added to it with the default logger, each with the specified ctx,
+// unless Resolve is called with success within the timeout
+func Defer(ctx context.Context, timeout time.Duration) *Deferred {
+	return defaultLog.Load().Defer(ctx, timeout)
+}
+
+// Resolve completes the operation. If success is true, and the timeout has not elapsed, the held logs are discarded,
+// otherwise they are written. Once a Deferred has been resolved, subsequent calls to Resolve have no effect
+func (d *Deferred) Resolve(success bool) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if d.state != deferredPending {
+		return
+	}
+
+	d.timer.Stop()
+
+	if success {
+		d.state, d.held = deferredCancelled, nil // release the held logs
+		return
+	}
+
+	d.state = deferredWritten
+	d.write(d.held...)
+	d.held = nil
+}
+
+// Error adds a log with error severity to the Deferred, see Log.Error
+func (d *Deferred) Error(message string, err error, labels ...any) {
+	d.add(OutputFlagError, "ERROR", message, err, labels)
+}
+
+// Warning adds a log with warning severity to the Deferred, see Log.Warning
+func (d *Deferred) Warning(message string, err error, labels ...any) {
+	d.add(OutputFlagWarning, "WARNING", message, err, labels)
+}
+
+// Notice adds a log with notice severity to the Deferred, see Log.Notice
+func (d *Deferred) Notice(message string, labels ...any) {
+	d.add(OutputFlagNotice, "NOTICE", message, nil, labels)
+}
+
+// NoticeError adds a log with notice severity and the specified error to the Deferred, see Log.NoticeError
+func (d *Deferred) NoticeError(message string, err error, labels ...any) {
+	d.add(OutputFlagNotice, "NOTICE", message, err, labels)
+}
+
+// Info adds a log with info severity to the Deferred, see Log.Info
+func (d *Deferred) Info(message string, labels ...any) {
+	d.add(OutputFlagInfo, "INFO", message, nil, labels)
+}
+
+// add adds a log to the held logs, writing it immediately if the held logs have already been written
+func (d *Deferred) add(flag OutputMask, severity, message string, err error, labels []any) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if d.state == deferredCancelled {
+		return
+	}
+
+	// the labels are copied, as the caller may reuse the slice passed as the variadic labels once the log is added
+	dl := deferredLog{at: d.l.now(), flag: flag, severity: severity, message: message, err: err, labels: append([]any(nil), labels...)}
+
+	if d.state == deferredWritten {
+		d.write(dl)
+		return
+	}
+
+	d.held = append(d.held, dl)
+}
+
+// write writes the logs with a Batch, so that they are admitted, and written, as any other logs of the Log
+func (d *Deferred) write(logs ...deferredLog) {
+	bt := d.l.Batch(d.ctx)
+
+	for _, dl := range logs {
+		bt.addAt(dl.at, dl.flag, dl.severity, dl.message, dl.err, dl.labels)
+	}
+
+	bt.Write()
+}
@@ -0,0 +1,78 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefer(t *testing.T) {
+	type testCase struct {
+		Resolve  func(d *Deferred)
+		Expected []string
+	}
+
+	for name, tc := range map[string]testCase{
+		"succeeded in time": {Resolve: func(d *Deferred) { d.Resolve(true) }},
+		"failed":            {Resolve: func(d *Deferred) { d.Resolve(false) }, Expected: []string{"held", "retried", "after"}},
+		"timed out": {Resolve: func(d *Deferred) {
+			time.Sleep(50 * time.Millisecond)
+			d.Resolve(true) // too late to cancel the held logs
+		}, Expected: []string{"held", "retried", "after"}},
+	} {
+		w := &syncBuffer{}
+		l := New(OutputMaskAll, false)
+		l.Writer = w
+
+		d := l.Defer(context.Background(), 20*time.Millisecond)
+		d.Warning("held", nil, "key", "value")
+		d.NoticeError("retried", errors.New("transient"))
+
+		if len(w.Bytes()) != 0 {
+			t.Fatalf("%v: expected no logs to be written before the deferred is resolved but got '%v'", name, string(w.Bytes()))
+		}
+
+		tc.Resolve(d)
+		d.Info("after")
+
+		logs := string(w.Bytes())
+
+		if strings.Count(logs, "\n") != len(tc.Expected) {
+			t.Fatalf("%v: expected %v logs but got '%v'", name, len(tc.Expected), logs)
+		}
+
+		for _, message := range tc.Expected {
+			if !strings.Contains(logs, `message="`+message+`"`) {
+				t.Fatalf("%v: expected log '%v' to be written but got '%v'", name, message, logs)
+			}
+		}
+
+		if len(tc.Expected) > 0 && (!strings.Contains(logs, `severity="NOTICE"`) || !strings.Contains(logs, `error="transient"`)) {
+			t.Fatalf("%v: expected the notice log to be written with its error but got '%v'", name, logs)
+		}
+	}
+}
+
+func TestDeferSucceededLeavesNoTrace(t *testing.T) {
+	SetTraceQuota(1)
+	defer SetTraceQuota(0)
+
+	w := &syncBuffer{}
+	l := New(OutputMaskAll, false).WithWriter(w)
+
+	ctx, end := BufferTrace(context.Background(), 10)
+	defer end()
+
+	d := l.Defer(ctx, time.Minute)
+	d.Info("held")
+	d.Warning("held", nil)
+	d.Resolve(true)
+
+	l.Error(ctx, "failed", nil)
+
+	if logs := string(w.Bytes()); strings.Contains(logs, "held") || !strings.Contains(logs, `message="failed"`) || strings.Contains(logs, "quota") {
+		t.Fatalf("expected the logs of a successful operation not to be buffered or counted against the quota but got '%v'", logs)
+	}
+}
@@ -0,0 +1,171 @@
+// Package ecs provides a qlog writer that encodes logs in the Elastic Common Schema (ECS), for ingestion by Elastic and
+// SIEMs that require strict ECS conformance.
+//
+// The fields of each log are written as their ECS equivalents; `@timestamp`, `log.level`, `message`, `trace.id`, `span.id`,
+// `error.message` and `log.logger`. Labels are nested under the ECS field they are mapped to or, where they have no
+// mapping, under the `labels` namespace. For example:
+//
+//	qlog.SetWriter(ecs.New(os.Stdout, ecs.Config{Fields: map[string]string{"user_id": "user.id", "status": "http.response.status_code"}}))
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Version is the version of ECS that logs conform to
+const Version = "8.11.0"
+
+// Config configures the mapping of labels to ECS fields
+type Config struct {
+	// Fields maps label keys to the ECS field, as a dotted path, that they are written as, such as "user_id" to "user.id"
+	Fields map[string]string
+	// Namespace is the ECS field under which labels without a mapping in Fields are nested, by default "labels". As the
+	// values of ECS labels are keywords, they are written as strings when nested under "labels"
+	Namespace string
+}
+
+// Writer is a qlog.EntryWriter that encodes each log in ECS, as a line of JSON, and writes it to an underlying Writer
+type Writer struct {
+	w      io.Writer
+	config Config
+}
+
+// New creates a Writer that writes logs encoded in ECS to w, with labels mapped to ECS fields according to the Config
+func New(w io.Writer, c Config) *Writer {
+	if c.Namespace == "" {
+		c.Namespace = "labels"
+	}
+
+	return &Writer{w: w, config: c}
+}
+
+// Write writes b, a log encoded by qlog, as the message of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e, encoded in ECS
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	if _, err := w.w.Write(Encode(e, w.config)); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// Unwrap returns the underlying Writer
+func (w *Writer) Unwrap() io.Writer {
+	return w.w
+}
+
+// Encode returns the log described by e encoded in ECS, as a line of JSON, with labels mapped to ECS fields according
+// to c. The `@timestamp`, `log.level` and `message` fields are written first, as the ECS logging specification
+// recommends, followed by the others, nested and in key order
+func Encode(e qlog.Entry, c Config) []byte {
+	if c.Namespace == "" {
+		c.Namespace = "labels"
+	}
+
+	fields := map[string]any{}
+
+	set(fields, "ecs.version", Version)
+
+	if e.Logger != "" {
+		set(fields, "log.logger", e.Logger)
+	}
+
+	if e.TraceID != "" {
+		set(fields, "trace.id", e.TraceID)
+	}
+
+	if e.SpanID != "" {
+		set(fields, "span.id", e.SpanID)
+	}
+
+	if e.Error != nil {
+		set(fields, "error.message", e.Error.Error())
+		set(fields, "error.type", fmt.Sprintf("%T", e.Error))
+	}
+
+	labels := e.AllLabels()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		key := fmt.Sprint(labels[i])
+
+		if field, ok := c.Fields[key]; ok {
+			set(fields, field, value(labels[i+1]))
+			continue
+		}
+
+		if c.Namespace == "labels" {
+			set(fields, "labels."+strings.ReplaceAll(key, ".", "_"), fmt.Sprint(labels[i+1]))
+			continue
+		}
+
+		set(fields, c.Namespace+"."+key, value(labels[i+1]))
+	}
+
+	b := []byte(`{"@timestamp":`)
+	b = appendJSON(b, e.Time.UTC().Format("2006-01-02T15:04:05.000000Z"))
+	b = append(b, `,"log.level":`...)
+	b = appendJSON(b, strings.ToLower(e.Severity))
+	b = append(b, `,"message":`...)
+	b = appendJSON(b, e.Message)
+
+	if rest, err := json.Marshal(fields); err == nil && len(rest) > 2 {
+		b = append(b, ',')
+		b = append(b, rest[1:len(rest)-1]...)
+	}
+
+	return append(b, '}', '\n')
+}
+
+// set sets the field at the dotted path in fields, creating the objects that nest it as required. Where a path
+// traverses an existing field that is not an object, the field is replaced
+func set(fields map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := fields[p].(map[string]any)
+
+		if !ok {
+			child = map[string]any{}
+			fields[p] = child
+		}
+
+		fields = child
+	}
+
+	fields[parts[len(parts)-1]] = v
+}
+
+// value returns v as a value that can be encoded as JSON
+func value(v any) any {
+	switch v := v.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return value(float64(v))
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) { // not representable in JSON
+			return v
+		}
+	case error:
+		return v.Error()
+	}
+
+	return fmt.Sprint(v)
+}
+
+// appendJSON appends s to b as a JSON string
+func appendJSON(b []byte, s string) []byte {
+	q, _ := json.Marshal(s)
+	return append(b, q...)
+}
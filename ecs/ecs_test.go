@@ -0,0 +1,57 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+func TestEncode(t *testing.T) {
+	type testCase struct {
+		Entry    qlog.Entry
+		Config   Config
+		Expected string
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 250000000, time.UTC)
+
+	for name, tc := range map[string]testCase{
+		"fields": {
+			Entry: qlog.Entry{Time: ts, Severity: "ERROR", TraceID: "abc", Logger: "payments", Message: "failed", Error: errors.New("boom")},
+			Expected: `{"@timestamp":"2024-03-01T12:30:00.250000Z","log.level":"error","message":"failed",` +
+				`"ecs":{"version":"` + Version + `"},"error":{"message":"boom","type":"*errors.errorString"},"log":{"logger":"payments"},"trace":{"id":"abc"}}` + "\n",
+		},
+		"labels": {
+			Entry:  qlog.Entry{Time: ts, Severity: "INFO", Message: "m", Labels: []any{"user_id", "u1", "status", 200, "region.zone", "a", "retries", 3}},
+			Config: Config{Fields: map[string]string{"user_id": "user.id", "status": "http.response.status_code"}},
+			Expected: `{"@timestamp":"2024-03-01T12:30:00.250000Z","log.level":"info","message":"m",` +
+				`"ecs":{"version":"` + Version + `"},"http":{"response":{"status_code":200}},"labels":{"region_zone":"a","retries":"3"},"user":{"id":"u1"}}` + "\n",
+		},
+		"custom namespace": {
+			Entry:    qlog.Entry{Time: ts, Severity: "INFO", Message: "m", Labels: []any{"retries", 3}},
+			Config:   Config{Namespace: "app"},
+			Expected: `{"@timestamp":"2024-03-01T12:30:00.250000Z","log.level":"info","message":"m","app":{"retries":3},"ecs":{"version":"` + Version + `"}}` + "\n",
+		},
+	} {
+		if actual := string(Encode(tc.Entry, tc.Config)); actual != tc.Expected {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	l := qlog.New(qlog.OutputMaskAll, true, "service", "checkout")
+	l.Writer = New(buf, Config{Fields: map[string]string{"service": "service.name"}})
+
+	l.Info(context.Background(), "started")
+
+	if expected := []byte(`"service":{"name":"checkout"}`); !bytes.Contains(buf.Bytes(), expected) || !bytes.Contains(buf.Bytes(), []byte(`"message":"started"`)) {
+		t.Fatalf("expected an ECS log with the label mapped to '%s' but got '%v'", expected, buf.String())
+	}
+}
@@ -0,0 +1,185 @@
+package qlog
+
+type (
+	// Encoder appends a Record to b in a log format, returning the extended buffer. The Record must be encoded as a
+	// single line, terminated by a newline, and b must not be retained, as it is reused once the log is written.
+	//
+	// An Encoder allows a Log to write a format other than JSON or logfmt. The labels common to all logs of the Log are
+	// included by the AllLabels method of the Record, rather than its Labels
+	Encoder interface {
+		Encode(b []byte, r Record) []byte
+	}
+	// EncoderConfig configures the field names and timestamp format of the JSONEncoder and LogfmtEncoder. Where a field
+	// is empty, the equivalent package level configuration is used
+	EncoderConfig struct {
+		TraceIDFieldName   string
+		SeverityFieldName  string
+		TimestampFieldName string
+		MessageFieldName   string
+		TimestampFormat    string
+		// Checksum determines whether a `checksum` label, a CRC32 of the log up to the label, is written last on each log
+		Checksum bool
+	}
+	// JSONEncoder is an Encoder that encodes Records as JSON, as a Log does with FormatJSON
+	JSONEncoder struct{ EncoderConfig }
+	// LogfmtEncoder is an Encoder that encodes Records as logfmt, as a Log does with FormatLogfmt
+	LogfmtEncoder struct{ EncoderConfig }
+)
+
+// Sets the Encoder of the default logger, which encodes its logs in place of its Format; pass nil to use the Format
+// This operation is safe for concurrent use.
+func SetEncoder(e Encoder) {
+	configure(func(l *Log) { l.Encoder = e })
+}
+
+// Encode appends r to b as JSON
+func (e JSONEncoder) Encode(b []byte, r Record) []byte {
+	return appendRecord(b, &r, true, e.EncoderConfig)
+}
+
+// Encode appends r to b as logfmt
+func (e LogfmtEncoder) Encode(b []byte, r Record) []byte {
+	return appendRecord(b, &r, false, e.EncoderConfig)
+}
+
+// encoderConfig returns the EncoderConfig of the field names and timestamp format of the Log
+func (l *Log) encoderConfig() EncoderConfig {
+	return EncoderConfig{
+		TraceIDFieldName:   l.TraceIDFieldName,
+		SeverityFieldName:  l.SeverityFieldName,
+		TimestampFieldName: l.TimestampFieldName,
+		MessageFieldName:   l.MessageFieldName,
+		TimestampFormat:    l.TimestampFormat,
+		Checksum:           l.Checksum,
+	}
+}
+
+// appendRecord appends r to b, as JSON or logfmt, with the field names and timestamp format of c
+func appendRecord(b []byte, r *Record, outputJSON bool, c EncoderConfig) []byte {
+	start := len(b)
+
+	openLog, closeLog, openField, closeField := `{ "`, ` }`, `, "`, `": `
+
+	if !outputJSON {
+		openLog, closeLog, openField, closeField = ``, ``, ` `, `=`
+	}
+
+	c = c.defaults()
+
+	// each field is appended directly to the buffer, rather than concatenated first, so that no intermediate strings are allocated
+	b = append(b, openLog...)
+	b = append(b, c.TraceIDFieldName...)
+	b = append(b, closeField...)
+	b = append(b, '"')
+	b = append(b, r.TraceID...)
+	b = append(b, '"')
+
+	if r.SpanID != "" {
+		b = appendField(b, openField, SpanIDFieldName, closeField)
+		b = append(b, '"')
+		b = append(b, r.SpanID...)
+		b = append(b, '"')
+	}
+
+	if r.ParentSpanID != "" {
+		b = appendField(b, openField, ParentSpanIDFieldName, closeField)
+		b = append(b, '"')
+		b = append(b, r.ParentSpanID...)
+		b = append(b, '"')
+	}
+
+	b = appendField(b, openField, c.SeverityFieldName, closeField)
+	b = append(b, '"')
+	b = append(b, r.Severity...)
+	b = append(b, '"')
+	b = appendField(b, openField, c.TimestampFieldName, closeField)
+	b = append(b, '"')
+	b = r.Time.AppendFormat(b, c.TimestampFormat)
+	b = append(b, '"')
+
+	if r.Error != nil {
+		b = appendField(b, openField, "error", closeField)
+		b = append(b, '"')
+		b = appendEscaped(b, r.Error.Error())
+		b = append(b, '"')
+	}
+
+	if r.log != nil {
+		for _, cl := range r.log.commonLabels {
+			if overridden(cl.key, r.Labels) || overridden(cl.key, r.ctxLabels) {
+				continue
+			}
+
+			if r.log.outputJSON == outputJSON { // the label is pre-rendered in the format of its Log
+				b = append(b, cl.text...)
+			} else {
+				b = appendValue(appendField(b, openField, cl.key, closeField), cl.value)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(r.ctxLabels); i += 2 {
+		if key, _ := r.ctxLabels[i].(string); !overridden(key, r.Labels) {
+			b = appendLabel(b, r, openField, closeField, r.ctxLabels[i], r.ctxLabels[i+1])
+		}
+	}
+
+	for i := 0; i+1 < len(r.Labels); i += 2 {
+		b = appendLabel(b, r, openField, closeField, r.Labels[i], r.Labels[i+1])
+	}
+
+	b = appendField(b, openField, c.MessageFieldName, closeField)
+	b = append(b, '"')
+	b = appendEscaped(b, r.Message)
+	b = append(b, '"')
+
+	if c.Checksum {
+		b = appendChecksum(b, b[start:], openField, closeField)
+	}
+
+	b = append(b, closeLog...)
+
+	return append(b, '\n')
+}
+
+// appendLabel appends a label of r to b, offloading or guarding its value according to the Log of r, if it has not been
+func appendLabel(b []byte, r *Record, openField, closeField string, k, value any) []byte {
+	key, ok := labelKey(k)
+
+	if !ok {
+		return b
+	}
+
+	if r.log != nil && !r.prepared {
+		value = r.log.prepareLabel(r.Context, key, value)
+	}
+
+	b = appendField(b, openField, key, closeField)
+
+	return appendValue(b, value)
+}
+
+// defaults returns c with any empty field set to the equivalent package level configuration
+func (c EncoderConfig) defaults() EncoderConfig {
+	if c.TraceIDFieldName == "" {
+		c.TraceIDFieldName = TraceIDFieldName
+	}
+
+	if c.SeverityFieldName == "" {
+		c.SeverityFieldName = SeverityFieldName
+	}
+
+	if c.TimestampFieldName == "" {
+		c.TimestampFieldName = TimestampFieldName
+	}
+
+	if c.MessageFieldName == "" {
+		c.MessageFieldName = MessageFieldName
+	}
+
+	if c.TimestampFormat == "" {
+		c.TimestampFormat = TimestampFormat
+	}
+
+	return c
+}
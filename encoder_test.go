@@ -0,0 +1,73 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoders(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := Record{Time: ts, Severity: "ERROR", TraceID: "abc", Message: "failed", Error: errors.New("boom"), Labels: []any{"id", 1, "lazy", func() string { return "v" }}}
+
+	tcs := []struct {
+		Name     string
+		Encoder  Encoder
+		Expected string
+	}{
+		{
+			Name:     "json",
+			Encoder:  JSONEncoder{},
+			Expected: `{ "trace": "abc", "severity": "ERROR", "timestamp": "2024-01-02T03:04:05Z", "error": "boom", "id": 1, "lazy": "v", "message": "failed" }` + "\n",
+		},
+		{
+			Name:     "logfmt",
+			Encoder:  LogfmtEncoder{},
+			Expected: `trace="abc" severity="ERROR" timestamp="2024-01-02T03:04:05Z" error="boom" id=1 lazy="v" message="failed"` + "\n",
+		},
+		{
+			Name:     "configured",
+			Encoder:  LogfmtEncoder{EncoderConfig{SeverityFieldName: "level", MessageFieldName: "msg", TimestampFormat: time.Kitchen}},
+			Expected: `trace="abc" level="ERROR" timestamp="3:04AM" error="boom" id=1 lazy="v" msg="failed"` + "\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := string(tc.Encoder.Encode(nil, r)); actual != tc.Expected {
+				t.Fatalf("expected '%v' but got '%v'", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+// upperEncoder is a test Encoder that writes the severity and message of a log, followed by all its labels
+type upperEncoder struct{}
+
+func (upperEncoder) Encode(b []byte, r Record) []byte {
+	return fmt.Appendf(b, "%v %v %v\n", r.Severity, strings.ToUpper(r.Message), r.AllLabels())
+}
+
+func TestLogEncoder(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false, "service", "api", "region", "eu", "version", func() string { return "1.2.3" }).WithWriter(&sb)
+	l.Encoder = upperEncoder{}
+
+	ctx := ContextWithLabels(context.Background(), "region", "us")
+	l.Info(ctx, "started", "port", func() int { return 8080 })
+
+	if expected, actual := "INFO STARTED [service api version 1.2.3 region us port 8080]\n", sb.String(); actual != expected {
+		t.Fatalf("expected '%v' but got '%v'", expected, actual)
+	}
+
+	sb.Reset()
+	l.Encoder = JSONEncoder{}
+	l.Info(ctx, "started")
+
+	if output := sb.String(); !strings.Contains(output, `, "service": "api", "version": "1.2.3", "region": "us", "message": "started" }`) {
+		t.Fatalf("expected the common labels of a logfmt Log to be written as JSON by a JSONEncoder but got '%v'", output)
+	}
+}
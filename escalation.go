@@ -0,0 +1,150 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EscalatedMessage is the message of the log written when an EscalationRule is triggered
+const EscalatedMessage = "log escalated"
+
+// EscalationRule converts a chronic, lower severity log into an actionable one. Should a log of the Severity, with the
+// Message, be written more than Threshold times within the Window, a log of the Escalation severity is written that
+// summarises them. Hooks registered for the Escalation severity are called with it as with any other log, so it can,
+// for example, raise an alert.
+type EscalationRule struct {
+	// Severity is that of the logs counted, by default SeverityWarning
+	Severity Severity
+	// Message is that of the logs counted. If it is empty, the logs of each distinct message are counted separately,
+	// for up to 10,000 messages at a time
+	Message string
+	// Threshold is the number of logs within the Window above which the rule is triggered
+	Threshold int
+	// Window is the duration over which the logs are counted, starting with the first
+	Window time.Duration
+	// Escalation is the severity of the log written when the rule is triggered, by default SeverityError
+	Escalation Severity
+}
+
+// escalation is an EscalationRule registered with a Log, along with the counts of the logs it matches. It is shared
+// by the Logs derived from that it was registered with, so that their logs are counted together
+type escalation struct {
+	rule       EscalationRule
+	flag       OutputMask
+	escalation severity
+	mx         sync.Mutex
+	windows    map[string]*escalationWindow
+}
+
+// maxEscalationKeys is the maximum number of distinct messages counted by an EscalationRule without a Message; the logs
+// of any beyond it are not counted, as messages are expected to be constant rather than interpolated
+const maxEscalationKeys = 10000
+
+// escalationWindow counts the logs of a message from the start of the window
+type escalationWindow struct {
+	start time.Time
+	count int
+}
+
+// AddEscalation registers an EscalationRule with the Log. Each time the rule is triggered, a log with the message
+// "log escalated" is written, with labels of the `escalated_message`, `escalated_severity`, the `occurrences` and the
+// `window_ms` of the logs that triggered it, and the context of the last of them. Logs derived from the Log inherit its
+// rules. An error is returned if either severity of the rule is not registered, or its Threshold or Window is not positive.
+//
+// For example:
+//
+//	logger.AddEscalation(qlog.EscalationRule{Message: "cache unavailable", Threshold: 100, Window: time.Minute})
+//
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddEscalation(r EscalationRule) error {
+	if r.Severity == 0 {
+		r.Severity = SeverityWarning
+	}
+
+	if r.Escalation == 0 {
+		r.Escalation = SeverityError
+	}
+
+	sv, ok := severities[r.Severity]
+	esv, eok := severities[r.Escalation]
+
+	if !ok || !eok {
+		return fmt.Errorf("invalid escalation rule: severities %d and %d must be registered", int(r.Severity), int(r.Escalation))
+	}
+
+	if r.Threshold <= 0 || r.Window <= 0 {
+		return fmt.Errorf("invalid escalation rule: threshold and window must be positive")
+	}
+
+	l.escalations = append(l.escalations[:len(l.escalations):len(l.escalations)], &escalation{
+		rule:       r,
+		flag:       sv.flag,
+		escalation: esv,
+		windows:    map[string]*escalationWindow{},
+	})
+
+	return nil
+}
+
+// Registers an EscalationRule with the default logger, see Log.AddEscalation.
+// This operation is safe for concurrent use.
+func AddEscalation(r EscalationRule) error {
+	var err error
+
+	configure(func(l *Log) { err = l.AddEscalation(r) })
+
+	return err
+}
+
+// escalate counts a log with the specified flag and message against the escalation rules of the Log, writing a log for
+// each rule that it triggers
+func (l *Log) escalate(ctx context.Context, flag OutputMask, message string) {
+	for _, e := range l.escalations {
+		if e.flag != flag || (e.rule.Message != "" && e.rule.Message != message) {
+			continue
+		}
+
+		now := l.now()
+
+		e.mx.Lock()
+
+		w, ok := e.windows[message]
+
+		if !ok && len(e.windows) >= maxEscalationKeys {
+			e.sweep(now)
+
+			if len(e.windows) >= maxEscalationKeys {
+				e.mx.Unlock()
+				continue
+			}
+		}
+
+		if !ok || now.Sub(w.start) >= e.rule.Window {
+			w = &escalationWindow{start: now}
+			e.windows[message] = w
+		}
+
+		w.count++
+		count, elapsed := w.count, now.Sub(w.start)
+
+		e.mx.Unlock()
+
+		if count == e.rule.Threshold+1 && l.writes(ctx, e.escalation.flag) {
+			// the escalation summarises logs that may have been dropped, so it is exempted from any budget
+			l.log(context.WithValue(ctx, budgetExemptKey, true), e.escalation.flag, e.escalation.name, EscalatedMessage, nil,
+				"escalated_message", message, "escalated_severity", severities[e.rule.Severity].name, "occurrences", count, "window_ms", int(elapsed.Milliseconds()))
+		}
+	}
+}
+
+// sweep removes the windows that have ended by now, so that they no longer count towards maxEscalationKeys. The caller
+// must hold e.mx
+func (e *escalation) sweep(now time.Time) {
+	for message, w := range e.windows {
+		if now.Sub(w.start) >= e.rule.Window {
+			delete(e.windows, message)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscalation(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() { timeNow = time.Now }()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	escalated := []Entry{}
+
+	l.OnError(func(e Entry) { escalated = append(escalated, e) })
+
+	if err := l.AddEscalation(EscalationRule{Message: "cache unavailable", Threshold: 3, Window: time.Minute}); err != nil {
+		t.Fatalf("expected a valid rule to be added but got '%v'", err)
+	}
+
+	if err := l.AddEscalation(EscalationRule{Threshold: 3}); err == nil {
+		t.Fatalf("expected a rule without a window to be rejected but got nil")
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Warning(context.Background(), "cache unavailable", nil)
+		l.Warning(context.Background(), "other", nil)
+	}
+
+	output := sb.String()
+
+	if strings.Count(output, EscalatedMessage) != 1 || !strings.Contains(output, `escalated_message="cache unavailable" escalated_severity="WARNING" occurrences=4`) {
+		t.Fatalf("expected one escalation once the threshold was exceeded but got '%v'", output)
+	}
+
+	if len(escalated) != 1 || escalated[0].Message != EscalatedMessage {
+		t.Fatalf("expected the error hook to be called with the escalation but got '%v'", escalated)
+	}
+
+	sb.Reset()
+	now = now.Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		l.Warning(context.Background(), "cache unavailable", nil)
+	}
+
+	if output := sb.String(); strings.Contains(output, EscalatedMessage) {
+		t.Fatalf("expected the count to restart with a new window but got '%v'", output)
+	}
+}
+
+func TestEscalationKeys(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() { timeNow = time.Now }()
+
+	l := New(OutputMaskAll, false).WithWriter(io.Discard)
+
+	if err := l.AddEscalation(EscalationRule{Threshold: 1, Window: time.Minute}); err != nil {
+		t.Fatalf("expected a valid rule to be added but got '%v'", err)
+	}
+
+	for i := 0; i < maxEscalationKeys+10; i++ {
+		l.escalate(context.Background(), OutputFlagWarning, strconv.Itoa(i))
+	}
+
+	if n := len(l.escalations[0].windows); n != maxEscalationKeys {
+		t.Fatalf("expected the windows to be capped at %v but got %v", maxEscalationKeys, n)
+	}
+
+	now = now.Add(time.Minute)
+	l.escalate(context.Background(), OutputFlagWarning, "new")
+
+	if n := len(l.escalations[0].windows); n != 1 {
+		t.Fatalf("expected the ended windows to be swept but got %v", n)
+	}
+}
@@ -0,0 +1,108 @@
+package qlog
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a minimal, machine readable representation of a log for analytics pipelines. It carries no message text,
+// so it is unaffected by changes to the wording, or translation, of messages
+type Event struct {
+	Code     string             `json:"code"`
+	Severity string             `json:"severity"`
+	TraceID  string             `json:"trace"`
+	Time     time.Time          `json:"timestamp"`
+	Metrics  map[string]float64 `json:"metrics,omitempty"`
+}
+
+// ExportEvents returns a Hook that writes an Event, as a line of JSON, to w for each log that has a label with the
+// specified codeKey, such as `event`. Logs without the label are not exported. This produces a parallel stream of
+// events from the existing log calls, without instrumenting the code a second time.
+//
+// The Metrics of the Event are the numeric label values with the specified metricKeys. If no metricKeys are
+// specified, all numeric label values are included.
+//
+// For example:
+//
+//	qlog.AddHook(qlog.ExportEvents(analyticsWriter, "event", "dur_ms", "items"))
+//	qlog.Info(ctx, "checkout completed", "event", "checkout.completed", "dur_ms", dur, "items", n)
+func ExportEvents(w io.Writer, codeKey string, metricKeys ...string) Hook {
+	mx := sync.Mutex{} // hooks are called concurrently by the goroutines writing logs
+
+	return func(e Entry) {
+		ev := Event{Severity: e.Severity, TraceID: e.TraceID, Time: e.Time}
+
+		for i := 0; i+1 < len(e.Labels); i += 2 {
+			key, _ := e.Labels[i].(string)
+
+			if key == codeKey {
+				ev.Code, _ = e.Labels[i+1].(string)
+				continue
+			}
+
+			if !included(key, metricKeys) {
+				continue
+			}
+
+			if v, ok := metric(e.Labels[i+1]); ok {
+				if ev.Metrics == nil {
+					ev.Metrics = map[string]float64{}
+				}
+
+				ev.Metrics[key] = v
+			}
+		}
+
+		if ev.Code == "" {
+			return
+		}
+
+		b, err := json.Marshal(ev)
+
+		if err != nil {
+			return
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		w.Write(append(b, '\n'))
+	}
+}
+
+// included reports whether key is one of keys, or keys is empty
+func included(key string, keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metric returns v as a float64 if it is numeric
+func metric(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,32 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExportEvents(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	buf := bytes.Buffer{}
+	l := New(OutputMaskAll, true)
+	l.Writer = io.Discard
+	l.AddHook(ExportEvents(&buf, "event", "dur_ms", "items"))
+
+	ctx := ContextFrom(context.Background(), "abc")
+
+	l.Info(ctx, "checkout completed", "event", "checkout.completed", "dur_ms", 12.5, "items", 3, "user", "bob", "ratio", 0.5)
+	l.Info(ctx, "not an event", "dur_ms", 1)
+	l.Warning(ctx, "payment retried", nil, "event", "payment.retried")
+
+	expected := `{"code":"checkout.completed","severity":"INFO","trace":"abc","timestamp":"2024-01-02T03:04:05Z","metrics":{"dur_ms":12.5,"items":3}}` + "\n" +
+		`{"code":"payment.retried","severity":"WARNING","trace":"abc","timestamp":"2024-01-02T03:04:05Z"}` + "\n"
+
+	if buf.String() != expected {
+		t.Fatalf("expected events '%v' but got '%v'", expected, buf.String())
+	}
+}
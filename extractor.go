@@ -0,0 +1,29 @@
+package qlog
+
+import "context"
+
+// Extractor returns labels derived from the context.Context of a log, such as the authenticated subject, tenant or
+// locale of a request, to be written with it. Extractors are called as each log is written, so they enrich logs
+// consistently without each call site adding the labels, and are not called for logs that are not written.
+//
+// Extracted labels are written as if they were carried by the context, so they take precedence over the labels of the
+// Log but not over those passed to the log call
+type Extractor func(ctx context.Context) []any
+
+// extractor returns labels, derived from the context and Trace-ID of a log, to be written with it
+type extractor func(ctx context.Context, traceID string) []any
+
+// AddExtractor registers an Extractor to be called as each log is written, adding the labels it returns to the log.
+// Logs derived from the Log inherit its extractors.
+//
+// Extractors are called synchronously by the goroutine writing the log, so should be quick to return.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddExtractor(x Extractor) {
+	l.extractors = append(l.extractors[:len(l.extractors):len(l.extractors)], func(ctx context.Context, _ string) []any { return x(ctx) })
+}
+
+// Registers an Extractor to be called as each log is written by the default logger, adding the labels it returns to the log.
+// This operation is safe for concurrent use.
+func AddExtractor(x Extractor) {
+	configure(func(l *Log) { l.AddExtractor(x) })
+}
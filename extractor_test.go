@@ -0,0 +1,41 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAddExtractor(t *testing.T) {
+	type tenantKey struct{}
+
+	w := &strings.Builder{}
+	l := New(OutputMaskAll, false, "tenant", "none").WithWriter(w)
+	calls := 0
+
+	l.AddExtractor(func(ctx context.Context) []any {
+		calls++
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+
+		return []any{"tenant", tenant, "locale"}
+	})
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	l.Info(ctx, "extracted")
+	l.Info(ctx, "overridden", "tenant", "other")
+	l.WithMask(OutputFlagNone).Info(ctx, "unwritten")
+
+	for i, expected := range []string{
+		`tenant="acme" locale="#missing#" message="extracted"`,
+		`locale="#missing#" tenant="other" message="overridden"`,
+	} {
+		if log := strings.Split(w.String(), "\n")[i]; !strings.Contains(log, expected) || strings.Count(log, "tenant=") != 1 {
+			t.Fatalf("expected log %v to contain '%v' but got '%v'", i, expected, log)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected the extractor to be called only for the 2 logs written but was called %v times", calls)
+	}
+}
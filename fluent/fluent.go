@@ -0,0 +1,313 @@
+// Package fluent provides a qlog sink that writes logs as records to a Fluentd, or Fluent Bit, aggregator with the
+// Fluent forward protocol, tagged by the name of the Log that wrote them.
+//
+// Where the aggregator requires it, the shared key handshake authenticates the connection. For example:
+//
+//	w := fluent.New(fluent.Config{Address: "fluentd.example.com:24224", Tag: "checkout", SharedKey: key})
+//	defer w.Close()
+//
+//	qlog.SetWriter(w)
+//	qlog.Get("payments").Info(ctx, "payment taken") // tagged "checkout.payments"
+package fluent
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// ErrHandshake is returned when the shared key handshake with the aggregator fails
+var ErrHandshake = errors.New("fluent: handshake failed")
+
+// Config configures a Fluent forward Writer
+type Config struct {
+	// Network is the network of the aggregator; "tcp", the default, or "unix"
+	Network string
+	// Address is the address of the aggregator, such as "fluentd.example.com:24224"
+	Address string
+	// TLS, if not nil, secures connections to the aggregator
+	TLS *tls.Config
+	// Tag is the tag of each record, suffixed with the name of the Log that wrote it, if it is a named Log, by
+	// default "qlog"
+	Tag string
+	// SharedKey, if not empty, is the key used to authenticate with the aggregator with the shared key handshake
+	SharedKey string
+	// Username and Password, if not empty, are the credentials sent in the shared key handshake when the aggregator
+	// requires user authentication
+	Username, Password string
+	// Hostname is the hostname sent in the shared key handshake, by default the hostname reported by the operating system
+	Hostname string
+	// Timeout limits the time taken to connect, complete the handshake and write each record, by default 5s
+	Timeout time.Duration
+}
+
+// Writer is a qlog.EntryWriter that writes logs as records to a Fluent aggregator in the Message mode of the forward
+// protocol. Should a write fail, the Writer reconnects and retries it once.
+type Writer struct {
+	config Config
+	mx     sync.Mutex
+	conn   net.Conn
+	closed chan struct{} // closed when the aggregator closes conn
+	b      []byte
+}
+
+// New creates a Writer with the specified Config. The connection to the aggregator is made by the first write
+func New(c Config) *Writer {
+	if c.Network == "" {
+		c.Network = "tcp"
+	}
+
+	if c.Tag == "" {
+		c.Tag = "qlog"
+	}
+
+	if c.Hostname == "" {
+		c.Hostname, _ = os.Hostname()
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	return &Writer{config: c}
+}
+
+// Write writes b, a log encoded by qlog, as the message of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e as a record
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.b = appendMessage(w.b[:0], w.config.Tag, e)
+
+	if err := w.send(); err != nil {
+		w.close()
+
+		if errors.Is(err, ErrHandshake) {
+			return 0, err
+		}
+
+		if err = w.send(); err != nil { // reconnect and retry once, as the aggregator may have closed an idle connection
+			w.close()
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Close closes the connection to the aggregator
+func (w *Writer) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.close()
+}
+
+// appendMessage appends the forward protocol message, [tag, time, record], for the log described by e to b
+func appendMessage(b []byte, tag string, e qlog.Entry) []byte {
+	if e.Logger != "" {
+		tag += "." + e.Logger
+	}
+
+	labels := e.AllLabels()
+	fields := 2 + len(labels)/2
+
+	if e.TraceID != "" {
+		fields++
+	}
+
+	if e.Error != nil {
+		fields++
+	}
+
+	b = appendArrayHeader(b, 3)
+	b = appendString(b, tag)
+	b = appendEventTime(b, e.Time)
+	b = appendMapHeader(b, fields)
+	b = appendString(appendString(b, "severity"), e.Severity)
+	b = appendString(appendString(b, "message"), e.Message)
+
+	if e.TraceID != "" {
+		b = appendString(appendString(b, "trace"), e.TraceID)
+	}
+
+	if e.Error != nil {
+		b = appendString(appendString(b, "error"), e.Error.Error())
+	}
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		b = appendValue(appendString(b, fmt.Sprint(labels[i])), labels[i+1])
+	}
+
+	return b
+}
+
+// send writes the message in b, connecting to the aggregator if required, the caller must hold mx
+func (w *Writer) send() error {
+	if w.conn != nil {
+		select {
+		case <-w.closed:
+			w.close()
+		default:
+		}
+	}
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.config.Timeout))
+
+	_, err := w.conn.Write(w.b)
+
+	return err
+}
+
+// connect connects to the aggregator, completing the shared key handshake if configured, the caller must hold mx
+func (w *Writer) connect() error {
+	d := &net.Dialer{Timeout: w.config.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if w.config.TLS != nil {
+		conn, err = tls.DialWithDialer(d, w.config.Network, w.config.Address, w.config.TLS)
+	} else {
+		conn, err = d.Dial(w.config.Network, w.config.Address)
+	}
+
+	if err != nil {
+		return fmt.Errorf("fluent: unable to connect to %v: %w", w.config.Address, err)
+	}
+
+	if w.config.SharedKey != "" {
+		conn.SetDeadline(time.Now().Add(w.config.Timeout))
+
+		if err := w.handshake(conn); err != nil {
+			conn.Close()
+			return err
+		}
+
+		conn.SetDeadline(time.Time{})
+	}
+
+	w.conn, w.closed = conn, make(chan struct{})
+	go watch(conn, w.closed)
+
+	return nil
+}
+
+// handshake completes the shared key handshake on conn; receiving a HELO, replying with a PING and verifying the PONG
+func (w *Writer) handshake(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+
+	helo, err := decode(r)
+
+	if err != nil {
+		return fmt.Errorf("%w: unable to read HELO: %v", ErrHandshake, err)
+	}
+
+	h, _ := helo.([]any)
+
+	if len(h) < 2 || h[0] != "HELO" {
+		return fmt.Errorf("%w: expected HELO but got %v", ErrHandshake, helo)
+	}
+
+	options, _ := h[1].(map[string]any)
+	nonce, _ := options["nonce"].(string)
+	auth, _ := options["auth"].(string)
+
+	salt := make([]byte, 16)
+	rand.Read(salt)
+
+	password := ""
+
+	if auth != "" {
+		password = digest(auth, w.config.Username, w.config.Password)
+	}
+
+	ping := appendArrayHeader(nil, 6)
+	ping = appendString(ping, "PING")
+	ping = appendString(ping, w.config.Hostname)
+	ping = appendString(ping, string(salt))
+	ping = appendString(ping, digest(string(salt), w.config.Hostname, nonce, w.config.SharedKey))
+	ping = appendString(ping, w.config.Username)
+	ping = appendString(ping, password)
+
+	if _, err := conn.Write(ping); err != nil {
+		return fmt.Errorf("%w: unable to write PING: %v", ErrHandshake, err)
+	}
+
+	pong, err := decode(r)
+
+	if err != nil {
+		return fmt.Errorf("%w: unable to read PONG: %v", ErrHandshake, err)
+	}
+
+	p, _ := pong.([]any)
+
+	if len(p) < 5 || p[0] != "PONG" {
+		return fmt.Errorf("%w: expected PONG but got %v", ErrHandshake, pong)
+	}
+
+	if ok, _ := p[1].(bool); !ok {
+		return fmt.Errorf("%w: %v", ErrHandshake, p[2])
+	}
+
+	if hostname, _ := p[3].(string); p[4] != digest(string(salt), hostname, nonce, w.config.SharedKey) {
+		return fmt.Errorf("%w: aggregator did not prove knowledge of the shared key", ErrHandshake)
+	}
+
+	return nil
+}
+
+// digest returns the hex encoded SHA512 digest of the concatenation of parts
+func digest(parts ...string) string {
+	h := sha512.New()
+
+	for _, p := range parts {
+		io.WriteString(h, p)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// watch closes the closed chan when conn is closed. Without acknowledgements, aggregators do not write to clients
+// after the handshake, so a read only returns when the connection is closed
+func watch(conn net.Conn, closed chan struct{}) {
+	io.Copy(io.Discard, conn)
+	close(closed)
+}
+
+// close closes the connection to the aggregator, if there is one, the caller must hold mx
+func (w *Writer) close() error {
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	return err
+}
@@ -0,0 +1,146 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// aggregator accepts a single connection, completing the shared key handshake with key, if not empty, and passes
+// each record it receives to records
+func aggregator(t *testing.T, key string, records chan<- []any) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	go func() {
+		conn, err := ln.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+
+		if key != "" {
+			helo := appendArrayHeader(nil, 2)
+			helo = appendString(helo, "HELO")
+			helo = appendMapHeader(helo, 2)
+			helo = appendString(appendString(helo, "nonce"), "n0nce")
+			helo = appendString(appendString(helo, "auth"), "")
+			conn.Write(helo)
+
+			v, _ := decode(r)
+			ping, _ := v.([]any)
+
+			if len(ping) != 6 || ping[3] != digest(ping[2].(string), ping[1].(string), "n0nce", key) {
+				conn.Write(appendString(appendString(appendValue(appendString(appendArrayHeader(nil, 5), "PONG"), false), "invalid key"), ""))
+				return
+			}
+
+			pong := appendArrayHeader(nil, 5)
+			pong = appendString(pong, "PONG")
+			pong = appendValue(pong, true)
+			pong = appendString(pong, "")
+			pong = appendString(pong, "server")
+			pong = appendString(pong, digest(ping[2].(string), "server", "n0nce", key))
+			conn.Write(pong)
+		}
+
+		for {
+			header := make([]byte, 1)
+
+			if _, err := io.ReadFull(r, header); err != nil || header[0] != 0x93 {
+				return
+			}
+
+			tag, _ := decode(r)
+			eventTime := make([]byte, 10)
+
+			if _, err := io.ReadFull(r, eventTime); err != nil || eventTime[0] != 0xd7 || eventTime[1] != 0x00 {
+				return
+			}
+
+			record, err := decode(r)
+
+			if err != nil {
+				return
+			}
+
+			records <- []any{tag, record}
+		}
+	}()
+
+	return ln
+}
+
+func TestWriter(t *testing.T) {
+	type testCase struct {
+		ClientKey string
+		ServerKey string
+		Err       error
+	}
+
+	for name, tc := range map[string]testCase{
+		"no handshake":  {},
+		"shared key":    {ClientKey: "secret", ServerKey: "secret"},
+		"incorrect key": {ClientKey: "wrong", ServerKey: "secret", Err: ErrHandshake},
+	} {
+		records := make(chan []any, 10)
+		ln := aggregator(t, tc.ServerKey, records)
+
+		w := New(Config{Address: ln.Addr().String(), Tag: "app", SharedKey: tc.ClientKey, Timeout: time.Second})
+
+		if tc.Err != nil {
+			if _, err := w.Write([]byte("log\n")); !errors.Is(err, tc.Err) {
+				t.Fatalf("%v: expected error '%v' but got '%v'", name, tc.Err, err)
+			}
+
+			ln.Close()
+			continue
+		}
+
+		l := qlog.New(qlog.OutputMaskAll, false)
+		l.Writer = w
+
+		l.Error(context.Background(), "failed", errors.New("boom"), "count", 3, "ok", true)
+
+		select {
+		case r := <-records:
+			expected := []any{"app", map[string]any{"severity": "ERROR", "message": "failed", "error": "boom", "count": int64(3), "ok": true}}
+
+			if record := r[1].(map[string]any); record["trace"] != nil {
+				delete(record, "trace")
+			}
+
+			if !reflect.DeepEqual(r, expected) {
+				t.Fatalf("%v: expected record '%v' but got '%v'", name, expected, r)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%v: expected a record but got none", name)
+		}
+
+		w.Close()
+		ln.Close()
+	}
+}
+
+func TestAppendMessageTag(t *testing.T) {
+	b := appendMessage(nil, "app", qlog.Entry{Severity: "INFO", Logger: "payments.gateway"})
+
+	if tag, _ := decode(bufio.NewReader(bytes.NewReader(b[1:]))); tag != "app.payments.gateway" {
+		t.Fatalf("expected the tag to be suffixed with the logger name but got '%v'", tag)
+	}
+}
@@ -0,0 +1,254 @@
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// appendString appends s to b as a msgpack str
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n < 1<<8:
+		b = append(b, 0xd9, byte(n))
+	case n < 1<<16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, s...)
+}
+
+// appendArrayHeader appends the header of a msgpack array of n elements to b
+func appendArrayHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x90|byte(n))
+	}
+
+	if n < 1<<16 {
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+// appendMapHeader appends the header of a msgpack map of n key, value pairs to b
+func appendMapHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x80|byte(n))
+	}
+
+	if n < 1<<16 {
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+}
+
+// appendEventTime appends t to b as a Fluent EventTime, a msgpack ext of type 0 holding the seconds and nanoseconds
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendValue appends v to b as the msgpack type that represents it, values of unsupported types are formatted as a
+// string with fmt
+func appendValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+
+		return append(b, 0xc2)
+	case string:
+		return appendString(b, v)
+	case []byte:
+		return appendString(b, string(v))
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(v))
+	case uint8:
+		return appendInt(b, int64(v))
+	case uint16:
+		return appendInt(b, int64(v))
+	case uint32:
+		return appendInt(b, int64(v))
+	case uint64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	case float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(v))
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+	case error:
+		return appendString(b, v.Error())
+	case fmt.Stringer:
+		return appendString(b, v.String())
+	}
+
+	return appendString(b, fmt.Sprint(v))
+}
+
+// appendInt appends i to b as a msgpack int, in its most compact form
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	}
+
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// errUnsupported is returned when decoding a msgpack type that is not used by the forward protocol handshake
+var errUnsupported = errors.New("unsupported msgpack type")
+
+// decode decodes a single msgpack value from r. Arrays are decoded as []any, maps as map[string]any, str and bin as
+// string and all ints as int64. Only the types used by the forward protocol handshake are supported
+func decode(r *bufio.Reader) (any, error) {
+	t, err := r.ReadByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t < 0x80:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return decodeMap(r, int(t&0x0f))
+	case t&0xf0 == 0x90:
+		return decodeArray(r, int(t&0x0f))
+	case t&0xe0 == 0xa0:
+		return decodeString(r, int(t&0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := decodeUint(r, 1)
+		return decodeThen(n, err, func(n int) (any, error) { return decodeString(r, n) })
+	case 0xc5, 0xda:
+		n, err := decodeUint(r, 2)
+		return decodeThen(n, err, func(n int) (any, error) { return decodeString(r, n) })
+	case 0xc6, 0xdb:
+		n, err := decodeUint(r, 4)
+		return decodeThen(n, err, func(n int) (any, error) { return decodeString(r, n) })
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := decodeUint(r, 1<<(t-0xcc))
+		return int64(n), err
+	case 0xdc:
+		n, err := decodeUint(r, 2)
+		return decodeThen(n, err, func(n int) (any, error) { return decodeArray(r, n) })
+	case 0xde:
+		n, err := decodeUint(r, 2)
+		return decodeThen(n, err, func(n int) (any, error) { return decodeMap(r, n) })
+	}
+
+	return nil, fmt.Errorf("%w: 0x%x", errUnsupported, t)
+}
+
+// decodeThen calls fn with n, unless err is not nil
+func decodeThen(n int, err error, fn func(n int) (any, error)) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return fn(n)
+}
+
+// decodeUint decodes a big endian unsigned int of size bytes from r
+func decodeUint(r *bufio.Reader, size int) (int, error) {
+	b := make([]byte, size)
+
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+
+	return n, nil
+}
+
+// decodeString decodes a str, or bin, of n bytes from r
+func decodeString(r *bufio.Reader, n int) (any, error) {
+	b := make([]byte, n)
+
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// decodeArray decodes an array of n elements from r
+func decodeArray(r *bufio.Reader, n int) (any, error) {
+	a := make([]any, 0, n)
+
+	for i := 0; i < n; i++ {
+		v, err := decode(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		a = append(a, v)
+	}
+
+	return a, nil
+}
+
+// decodeMap decodes a map of n key, value pairs from r, formatting keys that are not strings with fmt
+func decodeMap(r *bufio.Reader, n int) (any, error) {
+	m := make(map[string]any, n)
+
+	for i := 0; i < n; i++ {
+		k, err := decode(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := decode(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		m[fmt.Sprint(k)] = v
+	}
+
+	return m, nil
+}
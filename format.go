@@ -0,0 +1,110 @@
+package qlog
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Format defines the output format of logs
+type Format int
+
+// Supported output formats
+const (
+	FormatJSON Format = iota
+	FormatLogfmt
+)
+
+var formats = map[Format]string{
+	FormatJSON:   "json",
+	FormatLogfmt: "logfmt",
+}
+
+// ParseFormat parses a Format from its name, either "json" or "logfmt". Names are case insensitive.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for f, name := range formats {
+		if name == s {
+			return f, nil
+		}
+	}
+
+	return FormatJSON, fmt.Errorf("invalid format: unknown format '%v'", s)
+}
+
+// String returns the name of the Format. The returned value can be parsed by ParseFormat.
+func (f Format) String() string {
+	if name, ok := formats[f]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("format(%d)", int(f))
+}
+
+type (
+	maskVar   struct{ m *OutputMask }
+	formatVar struct{ f *Format }
+)
+
+// MaskVar returns a flag.Value that parses the flag into the OutputMask pointed to by m, using ParseOutputMask.
+// The current value of m is used as the default.
+//
+// For example:
+//
+//	mask := qlog.OutputMaskDetail
+//	flag.Var(qlog.MaskVar(&mask), "log-level", "the severities of log to write, such as 'important|debug'")
+func MaskVar(m *OutputMask) flag.Value {
+	return maskVar{m: m}
+}
+
+func (v maskVar) String() string {
+	if v.m == nil {
+		return ""
+	}
+
+	return v.m.String()
+}
+
+func (v maskVar) Set(s string) error {
+	m, err := ParseOutputMask(s)
+
+	if err != nil {
+		return err
+	}
+
+	*v.m = m
+
+	return nil
+}
+
+// FormatVar returns a flag.Value that parses the flag into the Format pointed to by f, using ParseFormat.
+// The current value of f is used as the default.
+//
+// For example:
+//
+//	format := qlog.FormatJSON
+//	flag.Var(qlog.FormatVar(&format), "log-format", "the format of the log, either 'json' or 'logfmt'")
+func FormatVar(f *Format) flag.Value {
+	return formatVar{f: f}
+}
+
+func (v formatVar) String() string {
+	if v.f == nil {
+		return ""
+	}
+
+	return v.f.String()
+}
+
+func (v formatVar) Set(s string) error {
+	f, err := ParseFormat(s)
+
+	if err != nil {
+		return err
+	}
+
+	*v.f = f
+
+	return nil
+}
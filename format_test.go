@@ -0,0 +1,28 @@
+package qlog
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestFlagVars(t *testing.T) {
+	mask, format := OutputMaskDetail, FormatJSON
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(MaskVar(&mask), "log-level", "")
+	fs.Var(FormatVar(&format), "log-format", "")
+
+	if err := fs.Parse([]string{"-log-level=important|debug", "-log-format=LOGFMT"}); err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	if mask != OutputMaskImportant|OutputFlagDebug || format != FormatLogfmt {
+		t.Fatalf("expected flags to be parsed but got mask '%v' and format '%v'", mask, format)
+	}
+
+	if err := fs.Parse([]string{"-log-format=xml"}); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,312 @@
+// Package gelf provides a qlog sink that writes logs as GELF 1.1 messages to Graylog over UDP or TCP, removing the
+// need for a sidecar to reformat them.
+//
+// The labels of each log are written as additional fields, prefixed with an underscore. Over UDP, messages are
+// compressed with gzip and, when larger than a datagram, chunked. For example:
+//
+//	w := gelf.New(gelf.Config{Network: "udp", Address: "graylog.example.com:12201"})
+//	defer w.Close()
+//
+//	qlog.SetWriter(w)
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comradequinn/qlog"
+	"github.com/comradequinn/qlog/syslog"
+)
+
+// DefaultChunkSize is the default maximum size of a UDP datagram, chosen to fit within the MTU of most networks
+const DefaultChunkSize = 1420
+
+// maxChunks is the maximum number of chunks a message may be split into, as defined by GELF
+const maxChunks = 128
+
+// chunkHeaderSize is the size of the header of each chunk; the magic bytes, message ID, sequence number and count
+const chunkHeaderSize = 12
+
+// ErrTooLarge is returned when a message is too large to be sent in the maximum number of UDP chunks
+var ErrTooLarge = errors.New("gelf: message too large")
+
+// Config configures a GELF Writer
+type Config struct {
+	// Network is the network of the Graylog input; either "udp" or "tcp"
+	Network string
+	// Address is the address of the Graylog input, such as "graylog.example.com:12201"
+	Address string
+	// Host is the host field of each message, by default the hostname reported by the operating system
+	Host string
+	// Uncompressed, if true, disables gzip compression of messages sent over UDP. Messages sent over TCP are never compressed
+	Uncompressed bool
+	// ChunkSize is the maximum size of each UDP datagram, by default DefaultChunkSize
+	ChunkSize int
+	// Timeout limits the time taken to connect and write each message, by default 5s
+	Timeout time.Duration
+}
+
+// Writer is a qlog.EntryWriter that writes logs as GELF messages to a Graylog input.
+//
+// Messages are written one per datagram, or chunked across several, over "udp", and delimited by a null byte over
+// "tcp". Should a write fail, the Writer reconnects and retries it once.
+type Writer struct {
+	config Config
+	mx     sync.Mutex
+	conn   net.Conn
+	closed chan struct{} // closed when the server closes conn, if it is tcp
+	buf    bytes.Buffer
+	gz     *gzip.Writer
+}
+
+// New creates a Writer with the specified Config. The connection to the Graylog input is made by the first write
+func New(c Config) *Writer {
+	if c.Host == "" {
+		c.Host, _ = os.Hostname()
+	}
+
+	if c.ChunkSize <= chunkHeaderSize {
+		c.ChunkSize = DefaultChunkSize
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	return &Writer{config: c}
+}
+
+// Write writes b, a log encoded by qlog, as the short_message of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e as a GELF message
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	msg, err := Encode(w.config.Host, e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if err := w.send(msg); err != nil {
+		w.close()
+
+		if errors.Is(err, ErrTooLarge) {
+			return 0, err
+		}
+
+		if err = w.send(msg); err != nil { // reconnect and retry once, as the server may have closed an idle connection
+			w.close()
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Close closes the connection to the Graylog input
+func (w *Writer) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.close()
+}
+
+// Encode returns the GELF message, as JSON, for the log described by e, written by the specified host.
+//
+// The labels of the log are written as additional fields, with the characters GELF does not permit in a field name
+// replaced by underscores. The trace ID and error are written as the _trace_id and _error fields
+func Encode(host string, e qlog.Entry) ([]byte, error) {
+	fields := map[string]any{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": e.Message,
+		"timestamp":     float64(e.Time.UnixMicro()) / 1e6,
+		"level":         syslog.Severity(e.Severity),
+		"_severity":     e.Severity,
+	}
+
+	if e.TraceID != "" {
+		fields["_trace_id"] = e.TraceID
+	}
+
+	if e.Error != nil {
+		fields["_error"] = e.Error.Error()
+	}
+
+	labels := e.AllLabels()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		fields[fieldName(fmt.Sprint(labels[i]))] = fieldValue(labels[i+1])
+	}
+
+	return json.Marshal(fields)
+}
+
+// fieldName returns the additional field name for the label key k
+func fieldName(k string) string {
+	if k == "id" { // _id is reserved by GELF
+		k = "id_"
+	}
+
+	return "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+
+		return '_'
+	}, k)
+}
+
+// fieldValue returns v as a GELF field value, which must be a string or a number
+func fieldValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return fieldValue(float64(v))
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) { // not representable in JSON
+			return v
+		}
+	case error:
+		return v.Error()
+	}
+
+	return fmt.Sprint(v)
+}
+
+// send writes msg, connecting to the Graylog input if required, the caller must hold mx
+func (w *Writer) send(msg []byte) error {
+	if w.conn != nil && w.closed != nil {
+		select {
+		case <-w.closed:
+			w.close()
+		default:
+		}
+	}
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.config.Timeout))
+
+	if w.config.Network != "udp" {
+		_, err := w.conn.Write(append(msg, 0))
+		return err
+	}
+
+	if !w.config.Uncompressed {
+		w.buf.Reset()
+
+		if w.gz == nil {
+			w.gz = gzip.NewWriter(&w.buf)
+		} else {
+			w.gz.Reset(&w.buf)
+		}
+
+		w.gz.Write(msg)
+		w.gz.Close()
+		msg = w.buf.Bytes()
+	}
+
+	if len(msg) <= w.config.ChunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+
+	return w.sendChunked(msg)
+}
+
+// sendChunked writes msg as a sequence of chunks, each prefixed with a header identifying the message and its
+// position within it, the caller must hold mx
+func (w *Writer) sendChunked(msg []byte) error {
+	size := w.config.ChunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+
+	if count > maxChunks {
+		return fmt.Errorf("%w: %v bytes requires %v chunks, the maximum is %v", ErrTooLarge, len(msg), count, maxChunks)
+	}
+
+	chunk := make([]byte, chunkHeaderSize, w.config.ChunkSize)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+
+	chunk[11] = byte(count)
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+
+		if end > len(msg) {
+			end = len(msg)
+		}
+
+		chunk[10] = byte(i)
+
+		if _, err := w.conn.Write(append(chunk[:chunkHeaderSize], msg[i*size:end]...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// connect connects to the Graylog input, the caller must hold mx
+func (w *Writer) connect() error {
+	conn, err := net.DialTimeout(w.config.Network, w.config.Address, w.config.Timeout)
+
+	if err != nil {
+		return fmt.Errorf("gelf: unable to connect to %v: %w", w.config.Address, err)
+	}
+
+	w.conn, w.closed = conn, nil
+
+	if w.config.Network != "udp" {
+		w.closed = make(chan struct{})
+		go watch(conn, w.closed)
+	}
+
+	return nil
+}
+
+// watch closes the closed chan when conn is closed. Graylog does not write to clients, so a read only returns when
+// the connection is closed
+func watch(conn net.Conn, closed chan struct{}) {
+	io.Copy(io.Discard, conn)
+	close(closed)
+}
+
+// close closes the connection to the Graylog input, if there is one, the caller must hold mx
+func (w *Writer) close() error {
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	return err
+}
@@ -0,0 +1,180 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+func TestEncode(t *testing.T) {
+	type testCase struct {
+		Entry    qlog.Entry
+		Expected string
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 250000000, time.UTC)
+
+	for name, tc := range map[string]testCase{
+		"error": {
+			Entry:    qlog.Entry{Time: ts, Severity: "ERROR", TraceID: "abc", Message: "failed", Error: errors.New("boom")},
+			Expected: `{"_error":"boom","_severity":"ERROR","_trace_id":"abc","host":"host","level":3,"short_message":"failed","timestamp":1709296200.25,"version":"1.1"}`,
+		},
+		"labels": {
+			Entry:    qlog.Entry{Time: ts, Severity: "INFO", Message: "m", Labels: []any{"id", "1", "bad key!", 2.5, "n", math.NaN(), "ok", true}},
+			Expected: `{"_bad_key_":2.5,"_id_":"1","_n":"NaN","_ok":"true","_severity":"INFO","host":"host","level":6,"short_message":"m","timestamp":1709296200.25,"version":"1.1"}`,
+		},
+	} {
+		b, err := Encode("host", tc.Entry)
+
+		if err != nil {
+			t.Fatalf("%v: expected no error but got '%v'", name, err)
+		}
+
+		if actual := string(b); actual != tc.Expected {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestWriterUDPChunking(t *testing.T) {
+	type testCase struct {
+		Uncompressed bool
+		Message      string
+	}
+
+	for name, tc := range map[string]testCase{
+		"uncompressed": {Uncompressed: true, Message: strings.Repeat("x", 500)},
+		"compressed":   {Uncompressed: false, Message: strings.Repeat("y", 50)},
+	} {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+		if err != nil {
+			t.Fatalf("%v: expected no error listening but got '%v'", name, err)
+		}
+
+		w := New(Config{Network: "udp", Address: conn.LocalAddr().String(), Uncompressed: tc.Uncompressed, ChunkSize: 100})
+		w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "INFO", Message: tc.Message}, nil)
+
+		msg, b := []byte{}, make([]byte, 2048)
+
+		for count, i := 1, 0; i < count; i++ {
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := conn.ReadFrom(b)
+
+			if err != nil {
+				t.Fatalf("%v: expected a datagram but got '%v'", name, err)
+			}
+
+			if n > 100 {
+				t.Fatalf("%v: expected datagrams of at most 100 bytes but got %v", name, n)
+			}
+
+			if b[0] != 0x1e || b[1] != 0x0f {
+				msg = append(msg, b[:n]...)
+				break
+			}
+
+			count = int(b[11])
+
+			if int(b[10]) != i {
+				t.Fatalf("%v: expected chunk %v but got %v", name, i, b[10])
+			}
+
+			msg = append(msg, b[chunkHeaderSize:n]...)
+		}
+
+		if !tc.Uncompressed {
+			r, err := gzip.NewReader(bytes.NewReader(msg))
+
+			if err != nil {
+				t.Fatalf("%v: expected a gzip message but got '%v'", name, err)
+			}
+
+			msg, _ = io.ReadAll(r)
+		}
+
+		if !strings.Contains(string(msg), `"short_message":"`+tc.Message+`"`) {
+			t.Fatalf("%v: expected the reassembled message to contain the short message but got '%s'", name, msg)
+		}
+
+		w.Close()
+		conn.Close()
+	}
+}
+
+func TestWriterTooLarge(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	defer conn.Close()
+
+	w := New(Config{Network: "udp", Address: conn.LocalAddr().String(), Uncompressed: true, ChunkSize: 20})
+	defer w.Close()
+
+	if _, err := w.WriteEntry(qlog.Entry{Severity: "INFO", Message: strings.Repeat("x", 2000)}, nil); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge but got '%v'", err)
+	}
+}
+
+func TestWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		conn, err := ln.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+
+		for {
+			msg, err := r.ReadString(0)
+
+			if err != nil {
+				return
+			}
+
+			msgs <- msg
+		}
+	}()
+
+	w := New(Config{Network: "tcp", Address: ln.Addr().String()})
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	w.Write([]byte("second\n"))
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case msg := <-msgs:
+			if !strings.HasSuffix(msg, "}\x00") || !strings.Contains(msg, `"short_message":"`+expected+`"`) {
+				t.Fatalf("expected null delimited message '%v' but got '%v'", expected, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected message '%v' but got none", expected)
+		}
+	}
+}
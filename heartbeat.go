@@ -0,0 +1,124 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"math/bits"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	heartbeating     = atomic.Bool{}
+	heartbeatWritten = [64]atomic.Int64{} // the logs written since the last heartbeat, indexed by the bit of their OutputFlag
+	heartbeatDropped = atomic.Int64{}     // the logs dropped since the last heartbeat by sampling, Once, EveryN or a budget
+	heartbeatFailed  = atomic.Int64{}     // the logs that failed to be written since the last heartbeat
+)
+
+// StartHeartbeat writes a notice with the default logger at the specified interval, summarising the logs written since
+// the last, such that alerts can be raised should a service fall silent, and the logs suppressed be quantified. It is
+// written regardless of the OutputMask, and any filters, budgets or sampling, of the default logger.
+//
+// The notice has a label of the number of logs written of each severity, such as `error_logs`, along with
+// `dropped_logs`, the number not written due to sampling, Once, EveryN or a budget, and `failed_logs`, the number the
+// Writer failed to write. The health of the writers of the default logger is summarised by `sink_dropped_logs`, the
+// number dropped by writers that count them, such as an AsyncWriter, and `sinks_open`, the number of CircuitBreakers
+// that are open.
+//
+// Counting the logs written adds to the cost of each, so it is disabled by default. The returned func stops the heartbeat.
+func StartHeartbeat(interval time.Duration) func() {
+	resetHeartbeat()
+	heartbeating.Store(true)
+
+	ticker, done := time.NewTicker(interval), make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+
+		last := sinkDropped()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				total := sinkDropped()
+				labels := append(resetHeartbeat(), "sink_dropped_logs", int(total-last), "sinks_open", sinksOpen())
+				last = total
+
+				// the heartbeat must be written for silence to be detected, so it is written regardless of the OutputMask
+				// and is exempt from any budget, sampling or filter
+				defaultLog.Load().log(context.WithValue(context.Background(), budgetExemptKey, true), OutputFlagNotice, "NOTICE", "heartbeat", nil, labels...)
+			}
+		}
+	}()
+
+	once := sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			heartbeating.Store(false)
+			close(done)
+		})
+	}
+}
+
+// resetHeartbeat returns the counts of logs since the last heartbeat, as labels, and resets them
+func resetHeartbeat() []any {
+	labels := []any{}
+
+	for _, sv := range sortedSeverities() {
+		labels = append(labels, strings.ToLower(sv.name)+"_logs", int(heartbeatWritten[bits.TrailingZeros64(uint64(sv.flag))].Swap(0)))
+	}
+
+	return append(labels, "dropped_logs", int(heartbeatDropped.Swap(0)), "failed_logs", int(heartbeatFailed.Swap(0)))
+}
+
+// countWritten counts a log with the severity flag as written, or as failed if err is not nil, for the heartbeat
+func countWritten(flag OutputMask, err error) {
+	if !heartbeating.Load() {
+		return
+	}
+
+	if err != nil {
+		heartbeatFailed.Add(1)
+		return
+	}
+
+	heartbeatWritten[bits.TrailingZeros64(uint64(flag))].Add(1)
+}
+
+// countDropped counts a log as dropped for the heartbeat
+func countDropped() {
+	if heartbeating.Load() {
+		heartbeatDropped.Add(1)
+	}
+}
+
+// sinkDropped returns the total number of logs dropped by the writers of the default logger that count them
+func sinkDropped() uint64 {
+	n := uint64(0)
+
+	defaultLog.Load().walk(func(w io.Writer) {
+		if d, ok := w.(interface{ Dropped() uint64 }); ok {
+			n += d.Dropped()
+		}
+	})
+
+	return n
+}
+
+// sinksOpen returns the number of writers of the default logger that are open circuits
+func sinksOpen() int {
+	n := 0
+
+	defaultLog.Load().walk(func(w io.Writer) {
+		if o, ok := w.(interface{ Open() bool }); ok && o.Open() {
+			n++
+		}
+	})
+
+	return n
+}
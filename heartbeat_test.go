@@ -0,0 +1,74 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	sb := &syncBuffer{}
+	cb := NewCircuitBreaker(&flakyWriter{failures: 1, err: errors.New("unavailable")}, &strings.Builder{})
+	cb.Threshold = 1
+
+	SetWriter(NewRouter(
+		Route{Writer: sb, Mask: ^OutputFlagNone},
+		Route{Writer: cb, Mask: OutputFlagWarning},
+		Route{Writer: &flakyWriter{failures: 1, err: errors.New("unavailable")}, Mask: OutputFlagError},
+	))
+	SetOutputFormat(FormatLogfmt)
+
+	defer func() {
+		SetWriter(os.Stderr)
+		SetOutputFormat(FormatJSON)
+	}()
+
+	stop := StartHeartbeat(50 * time.Millisecond)
+	defer stop()
+
+	ctx := context.Background()
+
+	Info(ctx, "written")
+	Info(ctx, "written")
+	Warning(ctx, "written", nil) // opens the circuit, but is written to its fallback
+	Info(WithSampled(ctx, false), "dropped")
+	Error(ctx, "failed", nil)
+
+	for i := 0; i < 2; i++ {
+		Once(ctx).Notice("once") // suppressed after the first, which is not a drop
+	}
+
+	time.Sleep(75 * time.Millisecond)
+
+	output := string(sb.Bytes())
+
+	for _, expected := range []string{`error_logs=0 warning_logs=1`, `notice_logs=1 info_logs=2`, `dropped_logs=1 failed_logs=1 sink_dropped_logs=0 sinks_open=1 message="heartbeat"`} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected the heartbeat to contain '%v' but got '%v'", expected, output)
+		}
+	}
+}
+
+func TestHeartbeatExempt(t *testing.T) {
+	defer resetDefaultLog()()
+
+	sb := &syncBuffer{}
+	SetWriter(sb)
+	SetOutputMask(OutputFlagFatal | OutputFlagError)
+	AddFilter(func(r Record) bool { return false })
+	SetSampleAfter(1, 0)
+
+	defer SetSampleAfter(0, 0)
+
+	stop := StartHeartbeat(20 * time.Millisecond)
+	defer stop()
+
+	time.Sleep(75 * time.Millisecond)
+
+	if heartbeats := strings.Count(string(sb.Bytes()), `"heartbeat"`); heartbeats < 2 {
+		t.Fatalf("expected the heartbeat to be written regardless of the mask, filters and sampling but got %v", heartbeats)
+	}
+}
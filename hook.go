@@ -0,0 +1,125 @@
+package qlog
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Entry describes a log that has been written. It is passed to any hooks registered on the Log that wrote it
+	Entry struct {
+		// Context is the context.Context passed to the log method, allowing hooks to access
+		// richer data carried by it, such as spans or user identity
+		Context  context.Context
+		Time     time.Time
+		Severity string
+		TraceID  string
+		// SpanID and ParentSpanID identify the span within the trace that wrote the log, and its parent, if known
+		SpanID       string
+		ParentSpanID string
+		Message      string
+		Error        error
+		// Labels are the key, value pairs passed to the log method, preceded by any carried by its context. Any values
+		// expressed as a func() T have been evaluated
+		Labels []any
+		// Flag is the OutputFlag of the severity of the log
+		Flag OutputMask
+		// Logger is the name of the Log that wrote the log, if it was retrieved with Get
+		Logger string
+		log    *Log // the Log that wrote the log, whose labels are included by AllLabels
+	}
+	// Hook is a func that is called with each Entry written by the Log it is registered with
+	Hook func(Entry)
+	// hook is a Hook registered for the severities included in its OutputMask
+	hook struct {
+		outputMask OutputMask
+		fn         Hook
+	}
+)
+
+// AllLabels returns the labels of the Log that wrote the log, other than those overridden by the Entry's Labels,
+// followed by the Entry's Labels. These are all the labels written with the log, such as are required by writers
+// that encode logs in their own format
+func (e Entry) AllLabels() []any {
+	labels := []any{}
+
+	if e.log != nil {
+		for _, cl := range e.log.commonLabels {
+			if !overridden(cl.key, e.Labels) {
+				labels = append(labels, cl.key, cl.value)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(e.Labels); i += 2 {
+		labels = append(labels, e.Labels[i], e.Labels[i+1])
+	}
+
+	for i := 1; i < len(labels); i += 2 {
+		labels[i] = resolve(labels[i])
+	}
+
+	return labels
+}
+
+// AddHook registers a Hook to be called after each log is written. Logs derived from the Log inherit its hooks.
+//
+// Hooks are called synchronously by the goroutine writing the log, so should be quick to return.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddHook(h Hook) {
+	l.OnSeverity(^OutputFlagNone, h)
+}
+
+// OnSeverity registers a Hook to be called after each log is written with a severity included in the specified OutputMask.
+// This allows behaviours to be attached to particular severities, such as incrementing circuit-breaker counters for errors,
+// without filtering inside a generic hook. Logs derived from the Log inherit its hooks.
+//
+// Hooks are called synchronously by the goroutine writing the log, so should be quick to return.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) OnSeverity(m OutputMask, h Hook) {
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], hook{outputMask: m, fn: h}) // never share an appended element with a derived Log
+}
+
+// OnError registers a Hook to be called after each log with error severity is written. It is equivalent to OnSeverity(OutputFlagError, h).
+//
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) OnError(h Hook) {
+	l.OnSeverity(OutputFlagError, h)
+}
+
+// hooked reports whether any hooks are registered for the specified severity flag, or any AfterWrite funcs
+func (l *Log) hooked(flag OutputMask) bool {
+	if len(l.afterWrite) > 0 {
+		return true
+	}
+
+	for _, h := range l.hooks {
+		if h.outputMask&flag != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve evaluates v if it is a lazily evaluated func() T, otherwise v is returned unchanged
+func resolve(v any) any {
+	switch f := v.(type) {
+	case func() string:
+		return f()
+	case func() int:
+		return f()
+	case func() uint:
+		return f()
+	case func() bool:
+		return f()
+	case func() float32:
+		return f()
+	case func() float64:
+		return f()
+	case func() any:
+		return f()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,72 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestHook(t *testing.T) {
+	type userKey struct{}
+
+	l := New(OutputMaskAll, true)
+	l.Writer = io.Discard
+
+	entries := []Entry{}
+	l.AddHook(func(e Entry) { entries = append(entries, e) })
+
+	ctx := context.WithValue(ContextFrom(context.Background(), ""), userKey{}, "user1")
+	testError := fmt.Errorf("test error")
+	labels := []any{"key1", func() string { return "lazyvalue1" }}
+
+	l.WithLabels("common", true).Error(ctx, `test "message"`, testError, labels...)
+	l.Notice(ctx, "notice message")
+	l.SetOutputMask(OutputFlagNone)
+	l.Info(ctx, "not written")
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries but got %v", len(entries))
+	}
+
+	e := entries[0]
+
+	if e.Context.Value(userKey{}) != "user1" || e.TraceID != TraceID(ctx) {
+		t.Fatalf("expected hook to receive originating context but got '%+v'", e)
+	}
+
+	if e.Severity != "ERROR" || e.Message != `test "message"` || e.Error != testError {
+		t.Fatalf("expected hook to receive entry fields but got '%+v'", e)
+	}
+
+	if len(e.Labels) != 2 || e.Labels[1] != "lazyvalue1" {
+		t.Fatalf("expected hook to receive evaluated labels but got '%+v'", e.Labels)
+	}
+
+	if _, ok := labels[1].(func() string); !ok {
+		t.Fatalf("expected caller's labels to be unmodified")
+	}
+}
+
+func TestSeverityHook(t *testing.T) {
+	l := New(OutputMaskAll, true)
+	l.Writer = io.Discard
+
+	errors, warnings, all := 0, 0, 0
+	l.OnError(func(e Entry) { errors++ })
+	l.OnSeverity(OutputFlagWarning|OutputFlagFatal, func(e Entry) { warnings++ })
+
+	d := l.WithLabels("derived", true)
+	d.AddHook(func(e Entry) { all++ })
+	l.AddHook(func(e Entry) {}) // must not replace the hook registered on the derived Log
+
+	ctx := ContextFrom(context.Background(), "")
+
+	d.Error(ctx, "error", nil)
+	d.Warning(ctx, "warning", nil)
+	d.Info(ctx, "info")
+
+	if errors != 1 || warnings != 1 || all != 3 {
+		t.Fatalf("expected hooks to be called for their severities only but got errors=%v warnings=%v all=%v", errors, warnings, all)
+	}
+}
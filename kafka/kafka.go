@@ -0,0 +1,105 @@
+// Package kafka provides a qlog sink that publishes logs to a Kafka topic through a Producer, allowing any client
+// library, such as sarama or franz-go, to be used without qlog depending on it.
+//
+// Each log is published with its trace ID as the key, so that, with a key hashing partitioner, the logs of a request
+// are written to the same partition and read in order. For example:
+//
+//	w := kafka.New(kafka.ProducerFunc(func(m kafka.Message) error {
+//		return client.ProduceSync(ctx, &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value}).FirstErr()
+//	}), "logs")
+//
+//	qlog.SetWriter(w)
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Header is a header of a Message
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a log to be published to Kafka. The Value is the log as encoded by qlog
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []Header
+}
+
+// Producer publishes Messages to Kafka, typically by adapting a client library's producer.
+//
+// The Message is owned by the Producer once passed to Produce, so may be retained, such as by an asynchronous producer
+type Producer interface {
+	Produce(m Message) error
+}
+
+// ProducerFunc is a func that implements Producer
+type ProducerFunc func(m Message) error
+
+// Produce calls fn with m
+func (fn ProducerFunc) Produce(m Message) error {
+	return fn(m)
+}
+
+// Writer is a qlog.EntryWriter that publishes each log to a Kafka topic through a Producer, keyed by its trace ID.
+// Each Message has a `severity` header holding the severity of the log.
+type Writer struct {
+	producer Producer
+	topic    string
+}
+
+// New creates a Writer that publishes logs to the topic through the Producer
+func New(p Producer, topic string) *Writer {
+	return &Writer{producer: p, topic: topic}
+}
+
+// Write publishes b, a log encoded by qlog, without a key, leaving its partition to the Producer
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.produce(nil, nil, b)
+}
+
+// WriteEntry publishes b, the log described by e, keyed by its trace ID
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	var key []byte
+
+	if e.TraceID != "" {
+		key = []byte(e.TraceID)
+	}
+
+	return w.produce(key, []Header{{Key: "severity", Value: []byte(e.Severity)}}, b)
+}
+
+// Flush flushes the Producer, if it has a Flush(context.Context) error method, so that the package level qlog.Flush
+// waits for Messages held by an asynchronous producer to be published
+func (w *Writer) Flush(ctx context.Context) error {
+	if f, ok := w.producer.(interface{ Flush(context.Context) error }); ok {
+		return f.Flush(ctx)
+	}
+
+	return nil
+}
+
+// Close closes the Producer, if it is an io.Closer, flushing any Messages it holds
+func (w *Writer) Close() error {
+	if c, ok := w.producer.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// produce publishes a copy of b, as b is only valid for the duration of the write, with the key and headers
+func (w *Writer) produce(key []byte, headers []Header, b []byte) (int, error) {
+	if err := w.producer.Produce(Message{Topic: w.topic, Key: key, Value: append([]byte(nil), b...), Headers: headers}); err != nil {
+		return 0, fmt.Errorf("kafka: unable to produce message: %w", err)
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/comradequinn/qlog"
+)
+
+type producer struct {
+	messages []Message
+	flushed  bool
+	closed   bool
+}
+
+func (p *producer) Produce(m Message) error {
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+func (p *producer) Flush(ctx context.Context) error {
+	p.flushed = true
+	return nil
+}
+
+func (p *producer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	p := &producer{}
+	w := New(p, "logs")
+
+	l := qlog.New(qlog.OutputMaskAll, true)
+	l.Writer = w
+	l.TraceID = func(ctx context.Context) string { return "trace-1" }
+
+	l.Warning(context.Background(), "low stock", nil)
+	w.Write([]byte("raw\n"))
+
+	if len(p.messages) != 2 {
+		t.Fatalf("expected 2 messages but got %v", len(p.messages))
+	}
+
+	if m := p.messages[0]; m.Topic != "logs" || string(m.Key) != "trace-1" || string(m.Headers[0].Value) != "WARNING" || !strings.Contains(string(m.Value), `"message": "low stock"`) {
+		t.Fatalf("expected a message keyed by trace ID but got topic '%v', key '%s', headers '%v' and value '%s'", m.Topic, m.Key, m.Headers, m.Value)
+	}
+
+	if m := p.messages[1]; m.Key != nil || string(m.Value) != "raw\n" {
+		t.Fatalf("expected an unkeyed message but got key '%s' and value '%s'", m.Key, m.Value)
+	}
+
+	w.Flush(context.Background())
+	w.Close()
+
+	if !p.flushed || !p.closed {
+		t.Fatalf("expected the producer to be flushed and closed")
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	errProduce := errors.New("broker unavailable")
+	w := New(ProducerFunc(func(m Message) error { return errProduce }), "logs")
+
+	if _, err := w.Write([]byte("log\n")); !errors.Is(err, errProduce) {
+		t.Fatalf("expected the producer's error but got '%v'", err)
+	}
+}
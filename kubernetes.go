@@ -0,0 +1,43 @@
+package qlog
+
+import "os"
+
+// Environment variables read by PresetKubernetes, as conventionally populated by the Kubernetes downward API
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+	EnvNodeName     = "NODE_NAME"
+)
+
+// PresetKubernetes configures the default logger for containers run by Kubernetes, in a single call. Logs are written
+// as JSON with `level`, `ts` and `msg` keys for the severity, timestamp and message; Warning, and more severe, logs are
+// written to stderr and all others to stdout; and `pod`, `namespace` and `node` labels are added from the POD_NAME,
+// POD_NAMESPACE and NODE_NAME environment variables, where they are set.
+//
+// The environment variables are populated by the downward API in the container spec, for example:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: { fieldRef: { fieldPath: metadata.name } }
+//	- name: POD_NAMESPACE
+//	  valueFrom: { fieldRef: { fieldPath: metadata.namespace } }
+//	- name: NODE_NAME
+//	  valueFrom: { fieldRef: { fieldPath: spec.nodeName } }
+//
+// This operation is safe for concurrent use.
+func PresetKubernetes() {
+	labels := []any{}
+
+	for _, label := range [][2]string{{"pod", EnvPodName}, {"namespace", EnvPodNamespace}, {"node", EnvNodeName}} {
+		if v := os.Getenv(label[1]); v != "" {
+			labels = append(labels, label[0], v)
+		}
+	}
+
+	configure(func(l *Log) {
+		OutputFormat(FormatJSON)(l)
+		Labels(labels...)(l)
+		l.SeverityFieldName, l.TimestampFieldName, l.MessageFieldName = "level", "ts", "msg"
+		l.Writer = splitOutput()
+	})
+}
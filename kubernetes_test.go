@@ -0,0 +1,30 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPresetKubernetes(t *testing.T) {
+	t.Setenv(EnvPodName, "checkout-7d9f")
+	t.Setenv(EnvPodNamespace, "shop")
+
+	defer resetDefaultLog()()
+
+	PresetKubernetes()
+
+	l := defaultLog.Load()
+	routes := l.Writer.(*Router).Routes()
+
+	if len(routes) != 2 || routes[1].Mask&OutputFlagError == 0 || routes[0].Mask&OutputFlagInfo == 0 {
+		t.Fatalf("expected severities to be split across stdout and stderr but got '%+v'", routes)
+	}
+
+	w := &strings.Builder{}
+	l.WithWriter(w).Info(context.Background(), "ready")
+
+	if log := w.String(); !strings.Contains(log, `"level": "INFO", "ts": "`) || !strings.Contains(log, `"pod": "checkout-7d9f", "namespace": "shop", "msg": "ready"`) || strings.Contains(log, `"node"`) {
+		t.Fatalf("expected a log with kubernetes field names and labels but got '%v'", log)
+	}
+}
@@ -0,0 +1,133 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// LabelPolicy defines how a log call with invalid labels, such as a key without a value, is handled
+type LabelPolicy int
+
+// Supported LabelPolicies
+const (
+	// LabelPolicyPad writes the log with the invalid part of the labels made valid; a key without a value is given
+	// a `#missing#` value and a non-string key is formatted as a string with fmt
+	LabelPolicyPad LabelPolicy = iota
+	// LabelPolicyDrop writes the log without the invalid part of the labels
+	LabelPolicyDrop
+	// LabelPolicyReport writes the log as LabelPolicyPad does and passes an error identifying the call site to ReportError
+	LabelPolicyReport
+	// LabelPolicyPanic panics with an error identifying the call site. This is intended for strict modes, such as under test
+	LabelPolicyPanic
+	// LabelPolicyCoerce is LabelPolicyPad, named for its handling of non-string keys
+	LabelPolicyCoerce = LabelPolicyPad
+)
+
+// Label validation configuration
+//
+// These are intended for configuration during start-up. They are not safe for concurrent use.
+var (
+	// UnbalancedLabelPolicy defines how a log call with labels that are not balanced key, value pairs is handled.
+	//
+	// By default it is LabelPolicyPad, which appends a `#missing#` value to balance them; LabelPolicyDrop drops the dangling key
+	UnbalancedLabelPolicy = LabelPolicyPad
+	// NonStringKeyPolicy defines how a label with a key that is not a string is handled, both in log calls and when
+	// setting the labels of a Log.
+	//
+	// By default it is LabelPolicyCoerce, which formats the key as a string with fmt; LabelPolicyDrop drops the label
+	NonStringKeyPolicy = LabelPolicyCoerce
+	// ReportError is passed the errors reported by qlog, such as those raised by LabelPolicyReport.
+	//
+	// By default it writes them to stderr
+	ReportError = func(err error) { fmt.Fprintf(os.Stderr, "qlog: %v\n", err) }
+)
+
+var unbalancedLabels = atomic.Uint64{}
+
+// UnbalancedLabels returns the number of log calls, and calls to set labels, made with labels that were not balanced key, value pairs.
+// Monitoring this allows teams to find, and fix, offending call sites with LabelPolicyReport
+func UnbalancedLabels() uint64 {
+	return unbalancedLabels.Load()
+}
+
+// balance returns labels as balanced key, value pairs according to the UnbalancedLabelPolicy
+func balance(labels []any) []any {
+	if len(labels)%2 == 0 {
+		return labels
+	}
+
+	unbalancedLabels.Add(1)
+
+	switch UnbalancedLabelPolicy {
+	case LabelPolicyDrop:
+		return labels[:len(labels)-1]
+	case LabelPolicyReport:
+		ReportError(callSiteError(fmt.Sprintf("unbalanced labels, key '%v' has no value", labels[len(labels)-1])))
+	case LabelPolicyPanic:
+		panic(callSiteError(fmt.Sprintf("unbalanced labels, key '%v' has no value", labels[len(labels)-1])))
+	}
+
+	return append(labels[:len(labels):len(labels)], "#missing#") // never append into the caller's backing array
+}
+
+// labelKey returns key as a string according to the NonStringKeyPolicy, or false if the label should be dropped
+func labelKey(key any) (string, bool) {
+	if s, ok := key.(string); ok {
+		return s, true
+	}
+
+	switch NonStringKeyPolicy {
+	case LabelPolicyDrop:
+		return "", false
+	case LabelPolicyReport:
+		ReportError(callSiteError(fmt.Sprintf("non-string label key '%v' of type %T", key, key)))
+	case LabelPolicyPanic:
+		panic(callSiteError(fmt.Sprintf("non-string label key '%v' of type %T", key, key)))
+	}
+
+	return fmt.Sprintf("%v", key), true
+}
+
+// callSiteError returns an error with the message that identifies the call site of the log outside of qlog
+func callSiteError(message string) error {
+	pcs := [16]uintptr{}
+	n := runtime.Callers(3, pcs[:])
+
+	if f, ok := callSite(pcs[:n]); ok {
+		return fmt.Errorf("%v at %v:%v", message, f.File, f.Line)
+	}
+
+	return errors.New(message)
+}
+
+// contextLabelsKey is the type of the context key of the labels added by ContextWithLabels
+type contextLabelsKey struct{}
+
+// ContextWithLabels creates a new context.Context that carries the passed labels, along with any carried by ctx, so that
+// they are included in all logs written with it, or a context derived from it. This allows labels known at the start of
+// an operation, such as a request ID, to be included in all its logs without passing them to each log call.
+//
+// Labels carried by the context override those of the Log with the same key, and are overridden by those passed to the
+// log call with the same key. Where ctx already carries a label with the same key, it is replaced.
+func ContextWithLabels(ctx context.Context, labels ...any) context.Context {
+	labels, existing := balance(labels), contextLabels(ctx)
+	merged := make([]any, 0, len(existing)+len(labels))
+
+	for i := 0; i < len(existing); i += 2 {
+		if key, ok := existing[i].(string); !ok || !overridden(key, labels) {
+			merged = append(merged, existing[i], existing[i+1])
+		}
+	}
+
+	return context.WithValue(ctx, contextLabelsKey{}, append(merged, labels...))
+}
+
+// contextLabels returns the labels carried by ctx, if any
+func contextLabels(ctx context.Context) []any {
+	labels, _ := ctx.Value(contextLabelsKey{}).([]any)
+	return labels
+}
@@ -0,0 +1,139 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnbalancedLabelPolicy(t *testing.T) {
+	defer func(p LabelPolicy, r func(error)) { UnbalancedLabelPolicy, ReportError = p, r }(UnbalancedLabelPolicy, ReportError)
+
+	reported := []error{}
+	ReportError = func(err error) { reported = append(reported, err) }
+
+	type testCase struct {
+		Policy       LabelPolicy
+		Expected     string
+		ExpectReport bool
+		ExpectPanic  bool
+	}
+
+	for name, tc := range map[string]testCase{
+		"pad":    {Policy: LabelPolicyPad, Expected: ` key1="value1" key2="#missing#" message`},
+		"drop":   {Policy: LabelPolicyDrop, Expected: ` key1="value1" message`},
+		"report": {Policy: LabelPolicyReport, Expected: ` key1="value1" key2="#missing#" message`, ExpectReport: true},
+		"panic":  {Policy: LabelPolicyPanic, ExpectPanic: true},
+	} {
+		UnbalancedLabelPolicy, reported = tc.Policy, nil
+		before := UnbalancedLabels()
+
+		buf := bytes.Buffer{}
+		l := New(OutputMaskAll, false)
+		l.Writer = &buf
+
+		func() {
+			defer func() {
+				if r := recover(); (r != nil) != tc.ExpectPanic {
+					t.Fatalf("%v: expected panic to be %v but got '%v'", name, tc.ExpectPanic, r)
+				}
+			}()
+
+			l.Info(context.Background(), "test", "key1", "value1", "key2")
+		}()
+
+		if UnbalancedLabels() != before+1 {
+			t.Fatalf("%v: expected unbalanced labels to be counted", name)
+		}
+
+		if !strings.Contains(buf.String(), tc.Expected) {
+			t.Fatalf("%v: expected log to contain '%v' but got '%v'", name, tc.Expected, buf.String())
+		}
+
+		if tc.ExpectReport && (len(reported) != 1 || !strings.Contains(reported[0].Error(), "labels_test.go")) {
+			t.Fatalf("%v: expected error identifying the call site to be reported but got '%v'", name, reported)
+		}
+	}
+}
+
+func TestNonStringKeyPolicy(t *testing.T) {
+	defer func(p LabelPolicy, r func(error)) { NonStringKeyPolicy, ReportError = p, r }(NonStringKeyPolicy, ReportError)
+
+	reported := []error{}
+	ReportError = func(err error) { reported = append(reported, err) }
+
+	type testCase struct {
+		Policy       LabelPolicy
+		Expected     string
+		ExpectReport bool
+		ExpectPanic  bool
+	}
+
+	for name, tc := range map[string]testCase{
+		"coerce": {Policy: LabelPolicyCoerce, Expected: ` 1="common" key1="value1" 2="value2" message`},
+		"drop":   {Policy: LabelPolicyDrop, Expected: ` key1="value1" message`},
+		"report": {Policy: LabelPolicyReport, Expected: ` 1="common" key1="value1" 2="value2" message`, ExpectReport: true},
+		"panic":  {Policy: LabelPolicyPanic, ExpectPanic: true},
+	} {
+		NonStringKeyPolicy, reported = tc.Policy, nil
+
+		buf := bytes.Buffer{}
+
+		func() {
+			defer func() {
+				if r := recover(); (r != nil) != tc.ExpectPanic {
+					t.Fatalf("%v: expected panic to be %v but got '%v'", name, tc.ExpectPanic, r)
+				}
+			}()
+
+			l := New(OutputMaskAll, false, 1, "common")
+			l.Writer = &buf
+			l.Info(context.Background(), "test", "key1", "value1", 2, "value2")
+		}()
+
+		if !strings.Contains(buf.String(), tc.Expected) {
+			t.Fatalf("%v: expected log to contain '%v' but got '%v'", name, tc.Expected, buf.String())
+		}
+
+		if tc.ExpectReport && (len(reported) != 2 || !strings.Contains(reported[1].Error(), "labels_test.go")) {
+			t.Fatalf("%v: expected errors identifying the call sites to be reported but got '%v'", name, reported)
+		}
+	}
+}
+
+func TestContextWithLabels(t *testing.T) {
+	type testCase struct {
+		Labels   []any
+		Expected string
+	}
+
+	ctx := ContextWithLabels(context.Background(), "request_id", "abc", "region", "eu")
+	ctx = ContextWithLabels(ctx, "region", "us")
+
+	for name, tc := range map[string]testCase{
+		"context labels":         {Expected: `service="api" request_id="abc" region="us" message="test"`},
+		"overridden by log call": {Labels: []any{"request_id", "def"}, Expected: `service="api" region="us" request_id="def" message="test"`},
+		"overriding the log":     {Labels: []any{"other", 1}, Expected: `service="api" request_id="abc" region="us" other=1 message="test"`},
+	} {
+		w := strings.Builder{}
+
+		l := New(OutputMaskAll, false, "service", "api")
+		l.Writer = &w
+		l.Info(ctx, "test", tc.Labels...)
+
+		if !strings.Contains(w.String(), tc.Expected) {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, w.String())
+		}
+	}
+
+	w := strings.Builder{}
+
+	l := New(OutputMaskAll, false, "region", "global")
+	l.Writer = &w
+	l.Info(ctx, "test")
+
+	if expected := `request_id="abc" region="us" message="test"`; !strings.Contains(w.String(), expected) || strings.Contains(w.String(), "global") {
+		t.Fatalf("expected the context labels to override those of the log '%v' but got '%v'", expected, w.String())
+	}
+}
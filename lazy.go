@@ -0,0 +1,33 @@
+package qlog
+
+import "time"
+
+// TimedOut is written in place of the value of a label created with LazyWithTimeout that was not evaluated in time
+const TimedOut = "<timed out>"
+
+// LazyWithTimeout returns a lazy label value that evaluates fn, should the log be written, but waits no longer than d
+// for it to return, writing TimedOut in its place if it does not. This guards the logging call against an expensive
+// value, such as one requiring a database lookup, that may hang.
+//
+// For example:
+//
+//	qlog.Info(ctx, "order placed", "customer", qlog.LazyWithTimeout(50*time.Millisecond, func() any { return lookupCustomer(id) }))
+//
+// Where fn times out, it continues to run to completion in the background, so it should not hold resources indefinitely.
+func LazyWithTimeout(d time.Duration, fn func() any) func() any {
+	return func() any {
+		result := make(chan any, 1) // buffered, so that fn can complete once the wait has been abandoned
+
+		go func() { result <- fn() }()
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case v := <-result:
+			return v
+		case <-timer.C:
+			return TimedOut
+		}
+	}
+}
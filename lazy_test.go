@@ -0,0 +1,39 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLazyWithTimeout(t *testing.T) {
+	type testCase struct {
+		Delay    time.Duration
+		Expected string
+	}
+
+	for name, tc := range map[string]testCase{
+		"in time":   {Expected: `customer="jane"`},
+		"timed out": {Delay: time.Second, Expected: `customer="<timed out>"`},
+	} {
+		w := strings.Builder{}
+		l := New(OutputMaskAll, false)
+		l.Writer = &w
+
+		start := time.Now()
+
+		l.Info(context.Background(), "test", "customer", LazyWithTimeout(20*time.Millisecond, func() any {
+			time.Sleep(tc.Delay)
+			return "jane"
+		}))
+
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Fatalf("%v: expected the log to be written without waiting for the value but it took %v", name, elapsed)
+		}
+
+		if !strings.Contains(w.String(), tc.Expected) {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, w.String())
+		}
+	}
+}
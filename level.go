@@ -0,0 +1,91 @@
+package qlog
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LevelConfig is the representation of the verbosity and format of the default logger used by LevelHandler
+type LevelConfig struct {
+	// Mask is the OutputMask of the default logger in the format accepted by ParseOutputMask
+	Mask string `json:"mask,omitempty"`
+	// Format is the Format of the default logger in the format accepted by ParseFormat
+	Format string `json:"format,omitempty"`
+}
+
+// LevelHandler returns a http.Handler that allows the verbosity of the default logger to be inspected and changed
+// at runtime, such as from an admin port, without a restart.
+//
+// A GET request returns the current LevelConfig as JSON. A PUT request with a LevelConfig as its JSON body changes
+// the mask, the format or both; omitted fields are left unchanged. Each change is recorded with an Audit log.
+//
+// For example:
+//
+//	curl -X PUT -d '{"mask":"all|trace"}' http://localhost:8081/log/level
+//
+// If authorize is not nil, it is called for each request. If it returns false, the request is rejected with a 403
+func LevelHandler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize != nil && !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			rq := LevelConfig{}
+
+			if err := json.NewDecoder(r.Body).Decode(&rq); err != nil {
+				http.Error(w, "invalid level config: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			mask, format := defaultLog.Load().OutputMask(), currentFormat()
+			previous := LevelConfig{Mask: mask.String(), Format: format.String()}
+
+			if rq.Mask != "" {
+				m, err := ParseOutputMask(rq.Mask)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				mask = m
+			}
+
+			if rq.Format != "" {
+				f, err := ParseFormat(rq.Format)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				format = f
+			}
+
+			SetOutputMask(mask)
+			SetOutputFormat(format)
+
+			Audit(r.Context(), "log level changed", "mask", mask.String(), "format", format.String(),
+				"previous_mask", previous.Mask, "previous_format", previous.Format, "remote_addr", r.RemoteAddr)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LevelConfig{Mask: defaultLog.Load().OutputMask().String(), Format: currentFormat().String()})
+	})
+}
+
+func currentFormat() Format {
+	if defaultLog.Load().outputJSON {
+		return FormatJSON
+	}
+
+	return FormatLogfmt
+}
@@ -0,0 +1,72 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	SetWriter(&buf)
+	SetOutputFormat(FormatJSON)
+	SetOutputMask(OutputMaskImportant)
+
+	defer func() {
+		SetWriter(os.Stderr)
+		SetOutputFormat(FormatJSON)
+		SetOutputMask(OutputMaskAll)
+	}()
+
+	h := LevelHandler(func(r *http.Request) bool { return r.Header.Get("Authorization") == "secret" })
+
+	type testCase struct {
+		Method         string
+		Body           string
+		Authorization  string
+		ExpectedStatus int
+		ExpectedConfig LevelConfig
+	}
+
+	for name, tc := range map[string]testCase{
+		"unauthorized":   {Method: http.MethodPut, Body: `{"mask":"all"}`, ExpectedStatus: http.StatusForbidden},
+		"get":            {Method: http.MethodGet, Authorization: "secret", ExpectedStatus: http.StatusOK, ExpectedConfig: LevelConfig{Mask: "fatal|error|warning|notice", Format: "json"}},
+		"invalid mask":   {Method: http.MethodPut, Body: `{"mask":"verbose"}`, Authorization: "secret", ExpectedStatus: http.StatusBadRequest},
+		"invalid method": {Method: http.MethodPost, Authorization: "secret", ExpectedStatus: http.StatusMethodNotAllowed},
+		"put mask":       {Method: http.MethodPut, Body: `{"mask":"error|debug"}`, Authorization: "secret", ExpectedStatus: http.StatusOK, ExpectedConfig: LevelConfig{Mask: "error|debug", Format: "json"}},
+	} {
+		rq := httptest.NewRequest(tc.Method, "/", strings.NewReader(tc.Body))
+		rq.Header.Set("Authorization", tc.Authorization)
+		rs := httptest.NewRecorder()
+
+		h.ServeHTTP(rs, rq)
+
+		if rs.Code != tc.ExpectedStatus {
+			t.Fatalf("%v: expected status %v but got %v", name, tc.ExpectedStatus, rs.Code)
+		}
+
+		if tc.ExpectedStatus != http.StatusOK {
+			continue
+		}
+
+		actual := LevelConfig{}
+
+		if err := json.NewDecoder(rs.Body).Decode(&actual); err != nil || actual != tc.ExpectedConfig {
+			t.Fatalf("%v: expected config '%+v' but got '%+v' (%v)", name, tc.ExpectedConfig, actual, err)
+		}
+
+		SetOutputMask(OutputMaskImportant)
+	}
+
+	if !strings.Contains(buf.String(), `"message": "log level changed"`) || !strings.Contains(buf.String(), `"previous_mask": "fatal|error|warning|notice"`) {
+		t.Fatalf("expected change to be audit logged but got '%v'", buf.String())
+	}
+
+	if strings.Count(buf.String(), "log level changed") != 1 {
+		t.Fatalf("expected only successful changes to be audit logged but got '%v'", buf.String())
+	}
+}
@@ -0,0 +1,211 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelSpec is a parsed level specification, mapping names to the OutputMask applied to them
+type levelSpec struct {
+	masks   map[string]OutputMask
+	callers sync.Map // caches the resolved OutputMask, if any, of each call site
+}
+
+// levelSpecs is the current levelSpec, or nil if none is set or it contains no package entries
+var levelSpecs = atomic.Pointer[levelSpec]{}
+
+// callerResolved reports whether the spec resolves a call site to an OutputMask
+type callerResolved struct {
+	mask OutputMask
+	ok   bool
+}
+
+// SetLevelSpec sets the verbosity of named Logs and of packages from a comma separated list of name=level pairs,
+// such as "github.com/acme/payments=debug,payments.gateway=warning,*=info". This allows targeted debugging of
+// particular components, in the manner of glog and klog, from a single config value or flag.
+//
+// Each level is the name of a severity, such as "warning", which enables that severity and all those more severe, or
+// any other value accepted by ParseOutputMask, such as "error|debug". The name `*` sets the OutputMask of the default logger.
+//
+// Other names apply to the named Log retrieved by Get with that name, including those retrieved after the spec is set,
+// and to logs written directly by the default logger, and Logs derived from it, from code in the package with that
+// import path or its sub-packages. Where several names match a package, the longest applies.
+//
+// A spec replaces any previous spec; named Logs not included in it inherit the verbosity of their parent.
+// Resolving the package of a call site has a cost, which is only incurred while a spec includes package names.
+// This operation is safe for concurrent use.
+func SetLevelSpec(spec string) error {
+	masks := map[string]OutputMask{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		name, level, ok := strings.Cut(entry, "=")
+
+		if !ok {
+			return fmt.Errorf("invalid level spec: entry '%v' is not in the format name=level", entry)
+		}
+
+		m, err := parseLevel(level)
+
+		if err != nil {
+			return fmt.Errorf("invalid level spec: entry '%v': %w", entry, err)
+		}
+
+		masks[strings.TrimSpace(name)] = m
+	}
+
+	namedMx.Lock()
+	defer namedMx.Unlock()
+
+	if m, ok := masks["*"]; ok {
+		SetOutputMask(m)
+		delete(masks, "*")
+	}
+
+	for name, l := range named {
+		if m, ok := masks[name]; ok {
+			l.SetOutputMask(m)
+			continue
+		}
+
+		l.InheritOutputMask()
+	}
+
+	if len(masks) == 0 {
+		levelSpecs.Store(nil)
+		return nil
+	}
+
+	levelSpecs.Store(&levelSpec{masks: masks})
+
+	return nil
+}
+
+// parseLevel parses a level as either a severity name, meaning that severity and above, or an OutputMask
+func parseLevel(level string) (OutputMask, error) {
+	level = strings.TrimSpace(level)
+
+	for s, sv := range severities {
+		if s != SeverityAudit && strings.EqualFold(sv.name, level) {
+			return MinSeverityMask(s), nil
+		}
+	}
+
+	return ParseOutputMask(level)
+}
+
+// enabled reports whether logs with the specified flag are to be encoded by the Log; either because they are written
+// or, where a crash context ring is set with SetCrashContext, to be held by it
+func (l *Log) enabled(ctx context.Context, flag OutputMask) bool {
+	return crashing.Load() || l.writes(ctx, flag)
+}
+
+// writes reports whether logs with the specified flag are written by the Log. Where a level spec sets the
+// verbosity of the package of the call site, it takes precedence over the OutputMask of the Log. Logs for traces
+// marked verbose with WithVerbose are written down to Debug, regardless of either
+func (l *Log) writes(ctx context.Context, flag OutputMask) bool {
+	if flag&OutputMaskAll != 0 && Verbose(ctx) {
+		return true
+	}
+
+	if l.name == "" { // named Logs are configured directly by the spec
+		if spec := levelSpecs.Load(); spec != nil {
+			if m, ok := spec.callerMask(); ok {
+				return m&flag != 0
+			}
+		}
+	}
+
+	return l.OutputMask()&flag != 0
+}
+
+// callerMask returns the OutputMask the spec sets for the package of the first call site outside of qlog, if any
+func (spec *levelSpec) callerMask() (OutputMask, bool) {
+	key := [6]uintptr{}
+	runtime.Callers(3, key[:]) // skips Callers, callerMask and writes
+
+	if r, ok := spec.callers.Load(key); ok {
+		return r.(callerResolved).mask, r.(callerResolved).ok
+	}
+
+	r := callerResolved{}
+
+	if f, ok := callSite(key[:]); ok {
+		r = spec.resolve(packageOf(f.Function))
+	}
+
+	spec.callers.Store(key, r)
+
+	return r.mask, r.ok
+}
+
+// resolve returns the OutputMask of the longest name in the spec that is pkg or a parent of it
+func (spec *levelSpec) resolve(pkg string) callerResolved {
+	r, longest := callerResolved{}, -1
+
+	for name, m := range spec.masks {
+		if (pkg == name || strings.HasPrefix(pkg, name+"/")) && len(name) > longest {
+			r, longest = callerResolved{mask: m, ok: true}, len(name)
+		}
+	}
+
+	return r
+}
+
+// callSite returns the first frame of the pcs outside of qlog, which is the call site of the log
+func callSite(pcs []uintptr) (runtime.Frame, bool) {
+	frames := runtime.CallersFrames(pcs)
+
+	for {
+		f, more := frames.Next()
+
+		if packageOf(f.Function) != "github.com/comradequinn/qlog" || strings.HasSuffix(f.File, "_test.go") {
+			return f, f.Function != ""
+		}
+
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// callSites caches the call site of each stack of callers, as resolving it is relatively expensive
+var callSites = sync.Map{}
+
+// cachedCallSite returns the call site of the stack of callers, see callSite
+func cachedCallSite(c callers) (runtime.Frame, bool) {
+	if f, ok := callSites.Load(c); ok {
+		return f.(runtime.Frame), true
+	}
+
+	f, ok := callSite(c[:])
+
+	if ok {
+		callSites.Store(c, f)
+	}
+
+	return f, ok
+}
+
+// packageOf returns the import path of the package of a fully qualified function name,
+// such as "github.com/acme/payments" for "github.com/acme/payments.(*Gateway).Charge"
+func packageOf(function string) string {
+	dir, name := "", function
+
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		dir, name = function[:i+1], function[i+1:]
+	}
+
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+
+	return dir + name
+}
@@ -0,0 +1,79 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+)
+
+func TestSetLevelSpec(t *testing.T) {
+	buf := bytes.Buffer{}
+	SetWriter(&buf)
+	SetOutputMask(OutputMaskAll)
+
+	defer func() {
+		SetLevelSpec("")
+		SetWriter(os.Stderr)
+		SetOutputMask(OutputMaskAll)
+	}()
+
+	if err := SetLevelSpec("*=warning,jobs=error|debug,worker.queue=debug,github.com/comradequinn/qlog=error"); err != nil {
+		t.Fatalf("expected valid spec to be set but got '%v'", err)
+	}
+
+	jobs, queue := Get("jobs"), Get("worker.queue")
+	ctx := context.Background()
+
+	type testCase struct {
+		Log      *Log
+		Expected OutputMask
+	}
+
+	for name, tc := range map[string]testCase{
+		"named":        {Log: jobs, Expected: OutputFlagError | OutputFlagDebug},
+		"named later":  {Log: queue, Expected: MinSeverityMask(SeverityDebug)},
+		"inherits":     {Log: Get("worker"), Expected: MinSeverityMask(SeverityWarning)},
+		"default mask": {Log: defaultLog.Load(), Expected: MinSeverityMask(SeverityWarning)},
+	} {
+		if m := tc.Log.OutputMask(); m != tc.Expected {
+			t.Fatalf("%v: expected mask '%v' but got '%v'", name, tc.Expected, m)
+		}
+	}
+
+	// the call site of this test is in the qlog package, so the package entry applies to the default logger
+	Warning(ctx, "not written", nil)
+	defaultLog.Load().WithLabels("k", "v").Warning(ctx, "not written", nil)
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected package level to apply to the call site but got '%v'", buf.String())
+	}
+
+	Error(ctx, "written", nil)
+
+	if buf.Len() == 0 {
+		t.Fatalf("expected package level to allow error logs")
+	}
+
+	if err := SetLevelSpec("jobs"); err == nil {
+		t.Fatalf("expected invalid spec to be rejected")
+	}
+
+	SetLevelSpec("")
+
+	if m := jobs.OutputMask(); m != MinSeverityMask(SeverityWarning) {
+		t.Fatalf("expected named Log to inherit once removed from the spec but got '%v'", m)
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	for function, expected := range map[string]string{
+		"github.com/acme/payments.(*Gateway).Charge": "github.com/acme/payments",
+		"github.com/acme/payments.Charge.func1":      "github.com/acme/payments",
+		"main.main":                                  "main",
+	} {
+		if pkg := packageOf(function); pkg != expected {
+			t.Fatalf("%v: expected package '%v' but got '%v'", function, expected, pkg)
+		}
+	}
+}
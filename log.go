@@ -9,41 +9,95 @@
 //
 // Log output verbosity is controlled by configuring the OutputMask; either with the individual OutputFlags
 // required, or by using one of the preset OutputMasks
+//
+// Building with the `qlog_nodebug` tag compiles Debug and Trace to empty funcs, so that
+// latency-critical builds pay no cost for verbose call sites
 package qlog
 
 import (
 	"context"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type (
 	// Log is a individual Log instance carrying its own specific configuration
 	Log struct {
-		commonLabels string
-		outputMask   int
+		commonLabels []commonLabel
+		outputMask   *atomic.Int64 // shared with derived Logs, so that changes in verbosity apply to them
+		parent       *Log          // for named Logs, the Log whose verbosity is inherited while the OutputMask is unset
+		name         string
 		outputJSON   bool
+		hooks        []hook
+		counters     []*atomic.Int64 // counts of logs written, for each Scope the Log belongs to
 		Writer       io.Writer
+		// AuditWriter is the destination of Audit logs; if nil, they are written to Writer
+		AuditWriter io.Writer
+		// TimestampFormat overrides the package level TimestampFormat for this Log, if not empty
+		TimestampFormat string
+		// TraceIDFieldName overrides the package level TraceIDFieldName for this Log, if not empty
+		TraceIDFieldName string
+		// SeverityFieldName overrides the package level SeverityFieldName for this Log, if not empty
+		SeverityFieldName string
+		// TimestampFieldName overrides the package level TimestampFieldName for this Log, if not empty
+		TimestampFieldName string
+		// MessageFieldName overrides the package level MessageFieldName for this Log, if not empty
+		MessageFieldName string
+		// TraceID overrides the package level TraceID for this Log, if not nil
+		TraceID func(ctx context.Context) string
+		// FatalFunc overrides the package level FatalFunc for this Log, if not nil
+		FatalFunc func()
+		// BlobOffload, if not nil, moves large label values to a BlobStore, writing a reference to them in their place
+		BlobOffload *BlobOffload
+		// Clock, if not nil, is the source of the timestamps of logs in place of the wall clock, such as a simulated clock
+		Clock Clock
+		// FallbackWriter, if not nil, receives any log that the Writer, or AuditWriter, fails to write, such as stderr
+		FallbackWriter io.Writer
+		// Checksum determines whether a `checksum` label, a CRC32 of the log up to the label, is written last on each log
+		Checksum bool
+		// Encoder, if not nil, encodes the logs of the Log in place of its Format, such as in a format other than JSON or
+		// logfmt. The field names, timestamp format and Checksum of the Log are then those of the Encoder
+		Encoder      Encoder
+		onWriteError func(err error, record []byte)
+		extractors   []extractor // derive labels from the context of each log, such as those of Datadog
+		bound        *boundSpan  // for the Logs of BoundLogs, the pre-resolved trace of their context
+		suppressed   bool        // for the Logs of BoundLogs returned by Once or EveryN, whether their call site was not due to log
+		escalations  []*escalation
+		filters      []Filter
+		dropFilters  []DropFilter
+		beforeWrite  []func(*Record)
+		afterWrite   []func(Record, int, error)
+	}
+	// OutputMask is a set of OutputFlags that configures which severities of log are written. Masks held in an int, as
+	// they were before this type was introduced, must be converted with OutputMask(m)
+	OutputMask int
+	// commonLabel is a label included in all logs written by a Log. The text is the label pre-rendered in
+	// the Log's format, the key and value are retained so that it can be re-rendered should the format change
+	commonLabel struct {
+		key   string
+		value any
+		text  string
 	}
 	unexportedKey struct{}
 )
 
 // OutputMask flag for configuring output verbosity
 const (
-	OutputFlagNone    = 0b00000000
-	OutputFlagFatal   = 0b00000001
-	OutputFlagError   = 0b00000010
-	OutputFlagWarning = 0b00000100
-	OutputFlagNotice  = 0b00001000
-	OutputFlagInfo    = 0b00010000
-	OutputFlagTrace   = 0b00100000
-	OutputFlagDebug   = 0b01000000
+	OutputFlagNone    OutputMask = 0b00000000
+	OutputFlagFatal   OutputMask = 0b00000001
+	OutputFlagError   OutputMask = 0b00000010
+	OutputFlagWarning OutputMask = 0b00000100
+	OutputFlagNotice  OutputMask = 0b00001000
+	OutputFlagInfo    OutputMask = 0b00010000
+	OutputFlagTrace   OutputMask = 0b00100000
+	OutputFlagDebug   OutputMask = 0b01000000
+	OutputFlagAudit   OutputMask = 0b10000000
 )
 
 // Predefined OutputMask for configuring output verbosity
@@ -54,6 +108,8 @@ const (
 )
 
 // Exported configuration fields
+//
+// These act as defaults for all Logs, each may be overridden for an individual Log by setting the equivalent field on it
 var (
 	// TimestampFormat defines the format that will be used timestamps in logs
 	TimestampFormat = time.RFC3339Nano
@@ -62,17 +118,24 @@ var (
 	// By default it is, `trace`; override this, if required, to align with
 	//  conventions or tooling that supports a similar feature by uses a different field name
 	TraceIDFieldName = "trace"
+	// SeverityFieldName, TimestampFieldName and MessageFieldName define the keys assigned to the severity, timestamp
+	// and message in the log
+	//
+	// By default they are `severity`, `timestamp` and `message`; override these, if required, to align with the
+	// conventions of a log backend, such as `level`, `ts` and `msg`
+	SeverityFieldName  = "severity"
+	TimestampFieldName = "timestamp"
+	MessageFieldName   = "message"
 	// TraceID returns the Trace-ID associated with the passed ctx.
 	// This allows it to be passed across process boundaries, for example
 	// as a HTTP Header in a downstream API call
 	//
 	// By default it returns the unique key assigned to the ctx by the conventional
-	// call to qlog.ContextFrom(); override this, if required, this to read a diffferent value
+	// call to qlog.ContextFrom(), or the Trace-ID of the ExternalSpan active on ctx, if a SpanSource is set and has one;
+	// override this, if required, this to read a diffferent value
 	// written by existing conventions or tooling that supports a similar feature
 	TraceID = func(ctx context.Context) string {
-		traceID, _ := ctx.Value(traceIDKey).(string)
-
-		return traceID
+		return currentSpan(ctx).traceID
 	}
 	// FatalFunc defines the function called by Fatal after writing the log
 	//
@@ -80,60 +143,122 @@ var (
 	FatalFunc = func() { os.Exit(1) }
 )
 
+// maxPooledBuffer is the capacity above which a buffer is not returned to the pool, so that a rare, large log does not
+// cause the pool to retain an unusually large amount of memory
+const maxPooledBuffer = 64 << 10
+
 var (
+	buffers = sync.Pool{New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	}}
 	mx         = sync.Mutex{} // outside of testing, all loggers are likely to be writing to the same destination (stderr), so they all share the same write lock
 	timeNow    = time.Now
 	traceIDKey = unexportedKey{}
-	newSpanID  = func() func() string {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		pad := "XXXXXXXXXXXXXXXXXXX" // padding is to keep Trace-IDs the same length
-
-		return func() string {
-			return (strconv.Itoa(r.Int()) + pad)[:len(pad)]
-		}
-	}()
 )
 
-// ContextFrom creates a new context.Context with the passed Trace-IDs or a new, unique Trace-ID
-// if traceID is an empty string
+// ContextFrom creates a new context.Context with the passed Trace-ID and a new, unique, Span-ID. If traceID is an empty
+// string, the Trace-ID of ctx is used or, if it has none, a new, unique Trace-ID
 //
 // This will cause logs generated from method calls that are passed the returned
-// context.Context to share a common Trace-ID field value in the log output
+// context.Context to share a common Trace-ID field value in the log output.
+//
+// Where the Trace-ID is that of ctx, the returned context.Context is a child span of ctx, so its logs also have a
+// `parent_span` field of the Span-ID of ctx. This allows the hierarchy of calls within a request to be reconstructed
+//
+// The passed Trace-ID is often read from a header, so is untrusted. Should it be longer than MaxTraceIDLength, or
+// contain characters other than letters, digits, `-`, `_`, `.` and `:`, it is handled according to the TraceIDPolicy
 func ContextFrom(ctx context.Context, traceID string) context.Context {
 	if ctx == nil {
 		panic("nil context passed to context-from")
 	}
 
-	if traceID == "" {
-		traceID = newSpanID()
+	if traceID != "" && !validTraceID(traceID) {
+		ctx, traceID = invalidTraceID(ctx, traceID)
 	}
 
-	return context.WithValue(ctx, traceIDKey, traceID)
+	parent := currentSpan(ctx)
+	sc := spanContext{traceID: traceID, spanID: newSpanID()}
+
+	if sc.traceID == "" {
+		sc.traceID = parent.traceID
+	}
+
+	if sc.traceID == "" {
+		sc.traceID = newTraceID()
+	}
+
+	if sc.traceID == parent.traceID {
+		sc.parentSpanID, sc.unsampled, sc.tracestate = parent.spanID, parent.unsampled, parent.tracestate
+	}
+
+	return context.WithValue(ctx, traceIDKey, sc)
 }
 
 // New creates a new Log with the specified output verbosity, common labels and
 // whether JSON or logfmt output is required
-func New(outputMask int, outputJSON bool, labels ...any) *Log {
-	sb := strings.Builder{}
-	sb.Grow(1000)
+func New(outputMask OutputMask, outputJSON bool, labels ...any) *Log {
+	l := &Log{outputMask: &atomic.Int64{}, outputJSON: outputJSON, commonLabels: writeLabels(nil, outputJSON, labels), Writer: os.Stderr}
+	l.outputMask.Store(int64(outputMask))
+
+	return l
+}
+
+// OutputMask returns the OutputMask that configures the verbosity of the Log.
+// For a named Log without its own OutputMask, this is the OutputMask of its nearest ancestor that has one.
+func (l *Log) OutputMask() OutputMask {
+	m := l.outputMask.Load()
+
+	for m == inheritMask && l.parent != nil {
+		l = l.parent
+		m = l.outputMask.Load()
+	}
 
-	writeLabels(&sb, outputJSON, labels)
+	return OutputMask(m)
+}
 
-	return &Log{outputMask: outputMask, outputJSON: outputJSON, commonLabels: sb.String(), Writer: os.Stderr}
+// SetOutputMask sets the OutputMask that configures the verbosity of the Log and any Logs derived from it.
+// This operation is safe for concurrent use, allowing verbosity to be changed at runtime.
+func (l *Log) SetOutputMask(m OutputMask) {
+	l.outputMask.Store(int64(m))
 }
 
-// WithLabels creates a new Log with the same labels as the receiver Log
-// but with the specified labels added to any output.
+// Labels returns the labels included in all logs written by the Log, as key, value pairs
+func (l *Log) Labels() []any {
+	labels := make([]any, 0, len(l.commonLabels)*2)
+
+	for _, cl := range l.commonLabels {
+		labels = append(labels, cl.key, cl.value)
+	}
+
+	return labels
+}
+
+// WithLabels creates a new Log with the same configuration and labels as the receiver Log
+// but with the specified labels added to any output. Where a specified label has the same key
+// as an existing label, it replaces it. The derived Log shares the OutputMask of the receiver Log,
+// so a change in verbosity of either applies to both.
 //
 // Use to create Logs specific to a particular lib or section of logic where
 // the addtional labels can be used to identify that section in the logs
 func (l *Log) WithLabels(labels ...any) *Log {
-	sb := strings.Builder{}
-	sb.Grow(1000)
+	return l.With(Labels(labels...))
+}
 
-	writeLabels(&sb, l.outputJSON, labels)
+// WithMask creates a new Log with the same configuration and labels as the receiver Log but with its own,
+// independent, OutputMask. Changes in the verbosity of the receiver Log do not apply to the derived Log, nor vice versa.
+//
+// Use to quieten, or make more verbose, a particular subsystem without affecting the rest of the system
+func (l *Log) WithMask(m OutputMask) *Log {
+	return l.With(Mask(m))
+}
 
-	return &Log{outputMask: l.outputMask, commonLabels: l.commonLabels + sb.String(), Writer: l.Writer}
+// WithWriter creates a new Log with the same configuration and labels as the receiver Log but that writes
+// its logs to w. The derived Log shares the OutputMask of the receiver Log, so a change in verbosity of either applies to both.
+//
+// Use to send the logs of a particular subsystem to a dedicated destination
+func (l *Log) WithWriter(w io.Writer) *Log {
+	return l.With(Writer(w))
 }
 
 // Writes a log with fatal severity and terminates the process
@@ -147,16 +272,24 @@ func (l *Log) WithLabels(labels ...any) *Log {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func (l *Log) Fatal(ctx context.Context, message string, err error, labels ...any) {
-	if l.outputMask&OutputFlagFatal == 0 {
+	if !l.writes(ctx, OutputFlagFatal) { // a Fatal log that is not written does not terminate the process
+		return
+	}
+
+	l.log(ctx, OutputFlagFatal, "FATAL", message, err, labels...)
+
+	l.Sync() // the process is expected to exit, so any held logs must be written first
+
+	if l.FatalFunc != nil {
+		l.FatalFunc()
 		return
 	}
 
-	l.log(ctx, "FATAL", message, err, labels...)
 	FatalFunc()
 }
 
@@ -172,16 +305,16 @@ func (l *Log) Fatal(ctx context.Context, message string, err error, labels ...an
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func (l *Log) Error(ctx context.Context, message string, err error, labels ...any) {
-	if l.outputMask&OutputFlagError == 0 {
+	if !l.enabled(ctx, OutputFlagError) {
 		return
 	}
 
-	l.log(ctx, "ERROR", message, err, labels...)
+	l.log(ctx, OutputFlagError, "ERROR", message, err, labels...)
 }
 
 // Writes a log with warning severity
@@ -197,21 +330,24 @@ func (l *Log) Error(ctx context.Context, message string, err error, labels ...an
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func (l *Log) Warning(ctx context.Context, message string, err error, labels ...any) {
-	if l.outputMask&OutputFlagWarning == 0 {
+	if !l.enabled(ctx, OutputFlagWarning) {
 		return
 	}
 
-	l.log(ctx, "WARNING", message, err, labels...)
+	l.log(ctx, OutputFlagWarning, "WARNING", message, err, labels...)
 }
 
 // Writes a log with notice severity
 // Notice is reserved for events that are expected but important, such as sytem start-up or shut-down
 //
+// Notice does not accept an error parameter. Where an error is relevant, use NoticeError, so that it is written as the
+// `error` field, as it is by Warning, Error and Fatal, rather than as a label
+//
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
@@ -221,16 +357,26 @@ func (l *Log) Warning(ctx context.Context, message string, err error, labels ...
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func (l *Log) Notice(ctx context.Context, message string, labels ...any) {
-	if l.outputMask&OutputFlagNotice == 0 {
+	if !l.enabled(ctx, OutputFlagNotice) {
+		return
+	}
+
+	l.log(ctx, OutputFlagNotice, "NOTICE", message, nil, labels...)
+}
+
+// Writes a log with notice severity and the specified error, see Log.Notice. The error, if not nil, is written as the
+// `error` field, and is passed to hooks and Encoders as the Error of the Entry or Record, as with Warning, Error and Fatal
+func (l *Log) NoticeError(ctx context.Context, message string, err error, labels ...any) {
+	if !l.enabled(ctx, OutputFlagNotice) {
 		return
 	}
 
-	l.log(ctx, "NOTICE", message, nil, labels...)
+	l.log(ctx, OutputFlagNotice, "NOTICE", message, err, labels...)
 }
 
 // Writes a log with info severity
@@ -245,193 +391,438 @@ func (l *Log) Notice(ctx context.Context, message string, labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool)
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any)
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func (l *Log) Info(ctx context.Context, message string, labels ...any) {
-	if l.outputMask&OutputFlagInfo == 0 {
+	if !l.enabled(ctx, OutputFlagInfo) {
 		return
 	}
 
-	l.log(ctx, "INFO", message, nil, labels...)
+	l.log(ctx, OutputFlagInfo, "INFO", message, nil, labels...)
 }
 
-// Writes a log with debug severity and a label of trace=true
-// Trace is reserved for emitting data about IO within a process's internal activity, such as the content of requests received or generated
+// Writes a log with audit severity
+// Audit is reserved for recording security or compliance relevant events, such as changes to permissions or access to sensitive data.
+// Audit logs are always written, regardless of the OutputMask, and are written to the AuditWriter if one is set
 //
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
 // For example:
 //
-//	logger.Trace(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
-//
-// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
-// is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+//	logger.Audit(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
-func (l *Log) Trace(ctx context.Context, message string, labels ...any) {
-	if l.outputMask&OutputFlagTrace == 0 {
-		return
-	}
-
-	l.log(ctx, "DEBUG", message, nil, append(labels, "trace", true)...)
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func (l *Log) Audit(ctx context.Context, message string, labels ...any) {
+	l.log(ctx, OutputFlagAudit, "AUDIT", message, nil, labels...)
 }
 
-// Writes a log with debug severity to the default log
-// Debug is reserved for emitting low level detail about a process's internal activity, such as config data or current variable states
+// Writes a log with a custom Severity registered with RegisterSeverity
+// Custom severities allow logs to be categorised beyond the built-in severities, such as for audit or compliance purposes
 //
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
 // For example:
 //
-//	qlog.Debug(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//	logger.Custom(ctx, SeverityAudit, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the Severity has not been registered, the log is not written. Logs with SeverityAudit are written regardless of
+// the OutputMask, as they are by Audit.
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
-func (l *Log) Debug(ctx context.Context, message string, labels ...any) {
-	if l.outputMask&OutputFlagDebug == 0 {
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func (l *Log) Custom(ctx context.Context, s Severity, message string, labels ...any) {
+	sv, ok := severities[s]
+
+	if !ok || (sv.flag != OutputFlagAudit && !l.enabled(ctx, sv.flag)) { // audit logs are written regardless of the OutputMask, as they are by Audit
 		return
 	}
 
-	l.log(ctx, "DEBUG", message, nil, labels...)
+	l.log(ctx, sv.flag, sv.name, message, nil, labels...)
 }
 
-func (l *Log) log(ctx context.Context, severity, message string, err error, labels ...any) {
-	b := make([]byte, 0, 500)
+func (l *Log) log(ctx context.Context, flag OutputMask, severity, message string, err error, labels ...any) {
+	r, ok := l.filter(ctx, flag, severity, message, err, labels)
 
-	openLog, closeLog, openField, closeField := `{ "`, ` }`, `, "`, `": `
+	if !ok {
+		return
+	}
 
-	if !l.outputJSON {
-		openLog, closeLog, openField, closeField = ``, ``, ` `, `=`
+	if len(l.escalations) > 0 { // the escalation is written after the log that triggered it
+		defer l.escalate(ctx, flag, message)
 	}
 
-	b = append(b, []byte(openLog+TraceIDFieldName+closeField+`"`+TraceID(ctx))...)
-	b = append(b, []byte(`"`+openField+"severity"+closeField+`"`+severity)...)
-	b = append(b, []byte(`"`+openField+"timestamp"+closeField+`"`)...)
-	b = timeNow().UTC().AppendFormat(b, TimestampFormat)
-	b = append(b, []byte(`"`)...)
+	if !l.admit(&r) {
+		return
+	}
 
-	if err != nil {
-		s := err.Error()
+	bp := buffers.Get().(*[]byte)
+	b, e, hooked := l.encode((*bp)[:0], r)
 
-		if strings.Contains(err.Error(), `"`) {
-			s = strings.ReplaceAll(s, `"`, `\"`)
+	if !l.withhold(ctx, flag, e, b, hooked) {
+		if flag&TailFlushMask != 0 {
+			flushTail(e.TraceID)
 		}
 
-		b = append(b, []byte(`"`+openField+"error"+closeField+`"`+s+`"`)...)
+		if flag == OutputFlagFatal {
+			dumpCrash()
+		}
+
+		l.emit(flag, e, b, hooked)
+	}
+
+	if cap(b) <= maxPooledBuffer { // writers must not retain b, so it can be reused, unless it is unusually large
+		*bp = b
+		buffers.Put(bp)
 	}
+}
 
-	b = append(b, []byte(l.commonLabels)...)
+// admit reports whether the log of r, which has passed the filters of the Log, is to be encoded, adding a sample rate
+// label to r should it be sampled. Logs that are not admitted are counted as dropped or, where the process is crashing
+// and the log is not enabled, held in the crash context ring
+func (l *Log) admit(r *Record) bool {
+	ctx, flag := r.Context, r.Flag
 
-	// this is similar code to that in writeLabels(...) however it works on a []byte rather a strings.Builder
-	// it is redefined inline to minimise the conditions when []byte must be allocated on the heap
-	if len(labels)%2 != 0 {
-		labels = append(labels, "#missing#")
+	if crashing.Load() && !l.writes(ctx, flag) {
+		l.holdCrash(*r)
+		return false
 	}
 
-	for i := 0; i < len(labels); i += 2 {
-		key, ok := labels[i].(string)
+	if exempt(ctx) {
+		return true
+	}
 
-		if !ok {
-			key = fmt.Sprintf("%v", labels[i])
-		}
+	if l.suppresses(flag) { // the call site of Once or EveryN was not due to log, which is not a drop
+		return false
+	}
 
-		val := ""
-		switch v := labels[i+1].(type) {
-		case string:
-			val = `"` + v + `"`
-		case int:
-			val = strconv.Itoa(v)
-		case uint:
-			val = strconv.FormatUint(uint64(v), 10)
-		case bool:
-			val = strconv.FormatBool(v)
-		case float32:
-			val = strconv.FormatFloat(float64(v), 'f', 2, 64)
-		case float64:
-			val = strconv.FormatFloat(v, 'f', 2, 64)
-		case fmt.Stringer:
-			val = v.String()
-		case func() string:
-			val = `"` + v() + `"`
-		case func() int:
-			val = strconv.Itoa(v())
-		case func() uint:
-			val = strconv.FormatUint(uint64(v()), 10)
-		case func() bool:
-			val = strconv.FormatBool(v())
-		case func() float32:
-			val = strconv.FormatFloat(float64(v()), 'f', 2, 64)
-		case func() float64:
-			val = strconv.FormatFloat(v(), 'f', 2, 64)
-		default: // handle the common primitives explicitly, accept an allocation or so for the rest and let fmt work its magic
-			val = fmt.Sprintf("%v", labels[i+1])
-		}
+	if sampledOut(ctx, flag) || !l.withinBudget(ctx, flag) || !l.withinQuota(ctx, flag) {
+		countDropped()
+		return false
+	}
+
+	rate, sampled := adaptiveSample(ctx, flag)
 
-		b = append(b, []byte(openField+key+closeField+val)...)
+	if sampled {
+		after, s := sampleAfterFirst(ctx, flag, r.Message)
+		rate, sampled = rate*after, s
 	}
 
-	b = append(b, []byte(openField+"message"+closeField+`"`)...)
+	if !sampled {
+		countDropped()
+		return false
+	}
 
-	if strings.Contains(message, `"`) {
-		message = strings.ReplaceAll(message, `"`, `\"`)
+	if rate < 1 {
+		r.Labels = append(r.Labels[:len(r.Labels):len(r.Labels)], SampleRateLabel, sampleRate(rate)) // never append into the caller's array
 	}
 
-	b = append(b, []byte(message)...)
-	b = append(b, []byte(`"`+closeLog+"\n")...)
+	return true
+}
+
+// withhold reports whether a log, encoded as b, is withheld rather than written; either held for its trace by
+// SetTailSampling, or dropped by the byte budget
+func (l *Log) withhold(ctx context.Context, flag OutputMask, e Entry, b []byte, hooked bool) bool {
+	switch {
+	case tail(l, flag, e, b, hooked):
+		return true
+	case !l.withinByteBudget(ctx, flag, len(b)):
+		countDropped()
+		return true
+	}
+
+	return false
+}
 
+// emit writes the log described by e, and encoded as b, and calls any hooks registered for its severity, and AfterWrite funcs
+func (l *Log) emit(flag OutputMask, e Entry, b []byte, hooked bool) {
 	mx.Lock()
-	defer mx.Unlock()
-	l.Writer.Write(b)
+
+	n, werr := l.write(flag, e, b)
+
+	countWritten(flag, werr)
+	publish(flag, b)
+	capture(e.Context, b)
+	mx.Unlock()
+
+	l.count()
+
+	if werr != nil && l.onWriteError != nil {
+		l.onWriteError(werr, b)
+	}
+
+	if hooked {
+		l.callHooks(flag, e, n, werr)
+	}
 }
 
-func writeLabels(sb *strings.Builder, outputJSON bool, labels []any) {
-	if len(labels)%2 != 0 {
-		labels = append(labels, "#missing#")
+// encode completes r and appends it to b, in the format of the Log, or with its Encoder, returning it along with the Entry that
+// describes it and whether any hooks are registered for its severity. The labels of the Entry are only evaluated where
+// there are hooks, its destination is an EntryWriter or the Log has an Encoder or BeforeWrite funcs
+func (l *Log) encode(b []byte, r Record) ([]byte, Entry, bool) {
+	hooked := l.hooked(r.Flag)
+
+	l.complete(&r)
+
+	if hooked || wantsEntry(l.destination(r.Flag)) || l.Encoder != nil || len(l.beforeWrite) > 0 {
+		// hooks, and Encoders, receive the evaluated labels, including those of the context, so evaluate any lazy values
+		// once here
+		r.merge()
 	}
 
-	openField, closeField := `, "`, `": `
+	if len(l.beforeWrite) > 0 {
+		r = l.beforeWriting(r)
+	}
 
-	if !outputJSON {
-		openField, closeField = ` `, `=`
+	if l.Encoder != nil {
+		b = l.Encoder.Encode(b, l.prepare(r))
+	} else {
+		b = appendRecord(b, &r, l.outputJSON, l.encoderConfig())
+	}
+
+	profile(len(b))
+
+	return b, r.entry(), hooked
+}
+
+// traceID returns the Trace-ID of the logs written by the Log with ctx
+func (l *Log) traceID(ctx context.Context) string {
+	switch {
+	case l.bound != nil:
+		return l.bound.traceID
+	case l.TraceID != nil:
+		return l.TraceID(ctx)
+	}
+
+	return TraceID(ctx)
+}
+
+// fieldNames returns the effective keys of the severity, timestamp and message of the logs of the Log
+func (l *Log) fieldNames() (severity, timestamp, message string) {
+	severity, timestamp, message = SeverityFieldName, TimestampFieldName, MessageFieldName
+
+	if l.SeverityFieldName != "" {
+		severity = l.SeverityFieldName
+	}
+
+	if l.TimestampFieldName != "" {
+		timestamp = l.TimestampFieldName
+	}
+
+	if l.MessageFieldName != "" {
+		message = l.MessageFieldName
+	}
+
+	return severity, timestamp, message
+}
+
+// extract returns the labels carried by the context, ctxLabels, followed by those of the extractors of the Log
+func (l *Log) extract(ctx context.Context, traceID string, ctxLabels []any) []any {
+	all := ctxLabels[:len(ctxLabels):len(ctxLabels)] // never append into the context's backing array
+
+	for _, x := range l.extractors {
+		all = append(all, balance(x(ctx, traceID))...)
+	}
+
+	return all
+}
+
+// destination returns the Writer for logs with the severity flag
+func (l *Log) destination(flag OutputMask) io.Writer {
+	if flag == OutputFlagAudit && l.AuditWriter != nil {
+		return l.AuditWriter
+	}
+
+	return l.Writer
+}
+
+// write writes the log described by e, and encoded as b, to the destination for its severity, and to the
+// FallbackWriter should that fail, returning the bytes written to the destination. The caller must hold mx
+func (l *Log) write(flag OutputMask, e Entry, b []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+
+	switch w := l.destination(flag).(type) {
+	case EntryWriter:
+		n, err = w.WriteEntry(e, b)
+	case SeverityWriter:
+		n, err = w.WriteSeverity(flag, b)
+	default:
+		n, err = w.Write(b)
+	}
+
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+
+	if err != nil && l.FallbackWriter != nil {
+		l.FallbackWriter.Write(b)
+	}
+
+	return n, err
+}
+
+// callHooks calls the hooks registered for the severity of the log described by e, then any AfterWrite funcs with the
+// bytes written and the error, if any, of writing it
+func (l *Log) callHooks(flag OutputMask, e Entry, n int, err error) {
+	for _, h := range l.hooks {
+		if h.outputMask&flag != 0 {
+			h.fn(e)
+		}
+	}
+
+	if len(l.afterWrite) > 0 {
+		r := e.record()
+
+		for _, f := range l.afterWrite {
+			f(r, n, err)
+		}
 	}
+}
+
+// writeLabels merges labels into cls, rendered in the specified format, replacing any existing commonLabels with the same key
+func writeLabels(cls []commonLabel, outputJSON bool, labels []any) []commonLabel {
+	labels = balance(labels)
 
 	for i := 0; i < len(labels); i += 2 {
-		key, ok := labels[i].(string)
+		key, ok := labelKey(labels[i])
 
 		if !ok {
 			continue
 		}
 
-		val := ""
-		switch v := labels[i+1].(type) {
-		case string:
-			val = `"` + v + `"`
-		case int:
-			val = strconv.Itoa(v)
-		case uint:
-			val = strconv.FormatUint(uint64(v), 10)
-		case bool:
-			val = strconv.FormatBool(v)
-		case float32:
-			val = strconv.FormatFloat(float64(v), 'f', 2, 64)
-		case float64:
-			val = strconv.FormatFloat(v, 'f', 2, 64)
-		case fmt.Stringer:
-			val = v.String()
-		default: // handle the common primitives explicitly, accept an allocation or so for the rest and let fmt work its magic
-			val = fmt.Sprintf("%v", labels[i+1])
+		cl := commonLabel{key: key, value: labels[i+1], text: renderLabel(outputJSON, key, labels[i+1])}
+
+		if i := indexOfLabel(cls, key); i >= 0 {
+			cls[i] = cl
+			continue
+		}
+
+		cls = append(cls, cl)
+	}
+
+	return cls
+}
+
+// renderLabels returns a copy of cls re-rendered in the specified format
+func renderLabels(cls []commonLabel, outputJSON bool) []commonLabel {
+	rendered := make([]commonLabel, 0, len(cls))
+
+	for _, cl := range cls {
+		rendered = append(rendered, commonLabel{key: cl.key, value: cl.value, text: renderLabel(outputJSON, cl.key, cl.value)})
+	}
+
+	return rendered
+}
+
+// renderLabel renders the key, value pair as a field in the specified format
+func renderLabel(outputJSON bool, key string, value any) string {
+	openField, closeField := `, "`, `": `
+
+	if !outputJSON {
+		openField, closeField = ` `, `=`
+	}
+
+	return string(appendValue(appendField(nil, openField, key, closeField), value))
+}
+
+// appendField appends the key of a field, along with the syntax that opens and closes it, to b
+func appendField(b []byte, openField, key, closeField string) []byte {
+	b = append(b, openField...)
+	b = append(b, key...)
+
+	return append(b, closeField...)
+}
+
+// appendValue appends the value of a label to b. The common types are handled explicitly, so that they are appended
+// without allocation, for the rest accept an allocation or so and let fmt work its magic
+func appendValue(b []byte, value any) []byte {
+	switch v := value.(type) {
+	case string:
+		b = append(b, '"')
+		b = append(b, v...)
+		return append(b, '"')
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case float32:
+		return strconv.AppendFloat(b, float64(v), 'f', 2, 64)
+	case float64:
+		return strconv.AppendFloat(b, v, 'f', 2, 64)
+	case error:
+		b = append(b, '"')
+		b = appendEscaped(b, v.Error())
+		return append(b, '"')
+	case fmt.Stringer:
+		return append(b, v.String()...)
+	case func() string:
+		b = append(b, '"')
+		b = append(b, v()...)
+		return append(b, '"')
+	case func() int:
+		return strconv.AppendInt(b, int64(v()), 10)
+	case func() uint:
+		return strconv.AppendUint(b, uint64(v()), 10)
+	case func() bool:
+		return strconv.AppendBool(b, v())
+	case func() float32:
+		return strconv.AppendFloat(b, float64(v()), 'f', 2, 64)
+	case func() float64:
+		return strconv.AppendFloat(b, v(), 'f', 2, 64)
+	case func() any:
+		return appendValue(b, v())
+	default:
+		return fmt.Appendf(b, "%v", value)
+	}
+}
+
+// indexOfLabel returns the index of the commonLabel in cls with the specified key, or -1 if there is none
+func indexOfLabel(cls []commonLabel, key string) int {
+	for i := range cls {
+		if cls[i].key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// overridden reports whether key is specified in labels, in which case the common label with that key is
+// overridden by the value in labels for that log
+func overridden(key string, labels []any) bool {
+	for i := 0; i < len(labels); i += 2 {
+		if k, ok := labels[i].(string); ok && k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendEscaped appends s to b, escaping any quotes so that it can be written as a quoted value
+func appendEscaped(b []byte, s string) []byte {
+	for {
+		i := strings.IndexByte(s, '"')
+
+		if i < 0 {
+			return append(b, s...)
 		}
 
-		sb.WriteString(openField + key + closeField + val)
+		b = append(b, s[:i]...)
+		b = append(b, '\\', '"')
+		s = s[i+1:]
 	}
 }
@@ -0,0 +1,66 @@
+package qlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// outputMasks are the names of the preset OutputMasks
+var outputMasks = map[string]OutputMask{
+	"none":      OutputFlagNone,
+	"important": OutputMaskImportant,
+	"detail":    OutputMaskDetail,
+	"all":       OutputMaskAll,
+}
+
+// ParseOutputMask parses an OutputMask from a `|` separated list of severities and preset names, such as
+// "error|warning|notice" or "important|debug". This allows verbosity to be driven from config files and flags.
+//
+// Recognised severities are fatal, error, warning, notice, info, trace and debug, along with the names of any
+// custom severities registered with RegisterSeverity. Recognised presets are none, important, detail and all.
+// Names are case insensitive.
+func ParseOutputMask(s string) (OutputMask, error) {
+	m := OutputFlagNone
+
+	for _, name := range strings.Split(s, "|") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if preset, ok := outputMasks[name]; ok {
+			m |= preset
+			continue
+		}
+
+		found := false
+
+		for _, sv := range severities {
+			if strings.ToLower(sv.name) == name {
+				m, found = m|sv.flag, true
+				break
+			}
+		}
+
+		if !found {
+			return OutputFlagNone, fmt.Errorf("invalid output mask: unknown severity '%v'", name)
+		}
+	}
+
+	return m, nil
+}
+
+// String returns the OutputMask as a `|` separated list of the severities it includes, such as "error|warning|notice".
+// The returned value can be parsed by ParseOutputMask.
+func (m OutputMask) String() string {
+	if m == OutputFlagNone {
+		return "none"
+	}
+
+	names := make([]string, 0, len(severities))
+
+	for _, sv := range sortedSeverities() {
+		if m&sv.flag != 0 {
+			names = append(names, strings.ToLower(sv.name))
+		}
+	}
+
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,36 @@
+package qlog
+
+import "testing"
+
+func TestParseOutputMask(t *testing.T) {
+	tcs := []struct {
+		Input    string
+		Expected OutputMask
+		String   string
+	}{
+		{Input: "error|warning|notice", Expected: OutputFlagError | OutputFlagWarning | OutputFlagNotice, String: "error|warning|notice"},
+		{Input: " Important | DEBUG ", Expected: OutputMaskImportant | OutputFlagDebug, String: "fatal|error|warning|notice|debug"},
+		{Input: "all|trace", Expected: OutputMaskAll | OutputFlagTrace, String: "fatal|error|warning|notice|info|trace|debug"},
+		{Input: "none", Expected: OutputFlagNone, String: "none"},
+	}
+
+	for _, tc := range tcs {
+		m, err := ParseOutputMask(tc.Input)
+
+		if err != nil || m != tc.Expected {
+			t.Fatalf("%v: expected mask %b but got %b, %v", tc.Input, tc.Expected, m, err)
+		}
+
+		if m.String() != tc.String {
+			t.Fatalf("%v: expected string '%v' but got '%v'", tc.Input, tc.String, m.String())
+		}
+
+		if rt, _ := ParseOutputMask(m.String()); rt != m {
+			t.Fatalf("%v: expected string to parse to the same mask but got %b", tc.Input, rt)
+		}
+	}
+
+	if _, err := ParseOutputMask("error|loud"); err == nil {
+		t.Fatalf("expected error for unknown severity")
+	}
+}
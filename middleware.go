@@ -0,0 +1,240 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProxyRequestIDHeader maps a request ID header, set by an upstream proxy or edge provider, to the key of the label it
+// is recorded as
+type ProxyRequestIDHeader struct {
+	Header string
+	Key    string
+}
+
+// ProxyRequestIDHeaders are the request ID headers recorded as labels by RequestContext, so that logs can be joined
+// with those of the edge provider during incident response. Modify it during start-up to record others.
+// It is not safe for concurrent use.
+var ProxyRequestIDHeaders = []ProxyRequestIDHeader{
+	{Header: "X-Amzn-Trace-Id", Key: "amzn_trace_id"},
+	{Header: "CF-Ray", Key: "cf_ray"},
+	{Header: "X-Cloud-Trace-Context", Key: "cloud_trace_context"},
+}
+
+// captureKey is the type of the context key of the buffer that captures the logs of a request for DevMiddleware
+type captureKey struct{}
+
+var (
+	capturesMx = sync.Mutex{}
+	captures   = map[*bytes.Buffer]struct{}{} // the buffers of the requests being captured, keyed by a token unique to each
+	capturing  = atomic.Int32{}               // allows capture to return without acquiring a lock when nothing is being captured
+)
+
+// DevMiddleware returns a http.Handler, intended for use in development only, that captures the logs written for
+// each request and, should the handler fail with a 5xx status or a panic, appends them to the error response.
+// This puts the detail needed to debug a failure alongside it, rather than in a separate log stream.
+//
+// The context of each request is created with RequestContext, so the handler must log with the request's context, or
+// one derived from it, for its logs to be captured. The response is buffered until the handler
+// returns, so DevMiddleware is not suitable for streaming responses.
+func DevMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs := &bytes.Buffer{}
+		ctx := context.WithValue(RequestContext(r), captureKey{}, logs) // the Trace-ID is set by the client, so cannot identify the request
+		id := TraceID(ctx)
+
+		capturesMx.Lock()
+		captures[logs] = struct{}{}
+		capturing.Add(1)
+		capturesMx.Unlock()
+
+		rw := &bufferedResponseWriter{header: http.Header{}}
+
+		defer func() {
+			if v := recover(); v != nil {
+				Error(ctx, "handler panicked", fmt.Errorf("%v", v))
+				rw.status = http.StatusInternalServerError
+			}
+
+			capturesMx.Lock()
+			delete(captures, logs)
+			capturing.Add(-1)
+			capturesMx.Unlock()
+
+			for k, v := range rw.header {
+				w.Header()[k] = v
+			}
+
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+
+			if rw.status >= 500 {
+				w.Header().Del("Content-Length") // the captured logs extend the body
+				rw.body.WriteString("\n\n--- qlog trace " + id + " ---\n")
+				rw.body.Write(logs.Bytes())
+			}
+
+			w.WriteHeader(rw.status)
+			w.Write(rw.body.Bytes())
+		}()
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// RequestContext returns the context of the request with a Trace-ID, if it does not already carry one, and labels for
+// any of the ProxyRequestIDHeaders present on the request, so that all logs written with it can be correlated with
+// those of upstream proxies. The trace is extracted from the request's headers by the Propagator set with
+// SetPropagator, by default from its W3C trace context traceparent header, if it has a valid one. The trace is marked as
+// verbose if the request has a VerboseHeader of "true"
+func RequestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+
+	if TraceID(ctx) == "" {
+		ctx = propagator().Extract(ctx, r.Header)
+	}
+
+	ctx = ExtractVerbose(ctx, r.Header)
+
+	labels := []any{}
+
+	for _, h := range ProxyRequestIDHeaders {
+		if v := r.Header.Get(h.Header); v != "" {
+			labels = append(labels, h.Key, v)
+		}
+	}
+
+	if len(labels) == 0 {
+		return ctx
+	}
+
+	return ContextWithLabels(ctx, labels...)
+}
+
+// bufferedResponseWriter holds a response until the handler has returned
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *bufferedResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *bufferedResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+}
+
+func (rw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	return rw.body.Write(b)
+}
+
+// capture appends b to the captured logs of the request of ctx, if they are being captured
+func capture(ctx context.Context, b []byte) {
+	if capturing.Load() == 0 || ctx == nil {
+		return
+	}
+
+	logs, ok := ctx.Value(captureKey{}).(*bytes.Buffer)
+
+	if !ok {
+		return
+	}
+
+	capturesMx.Lock()
+	defer capturesMx.Unlock()
+
+	if _, ok := captures[logs]; ok {
+		logs.Write(b)
+	}
+}
+
+// Middleware returns a http.Handler that continues, or starts, the trace of each request, with the Propagator set with
+// SetPropagator, before passing it to next with a context created by RequestContext. The trace is set in the headers of
+// the response, so that clients may link their own logs, and the start and completion of each request are logged.
+//
+// The start is logged at Debug, with the method and path, and the completion at Info, or Error for a 5xx status, along
+// with the status, the bytes written and the duration. The context of each request carries a CanonicalLine, whose
+// labels are added to the log of its completion, so that it is a canonical log line for the request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, start := WithCanonical(RequestContext(r)), time.Now()
+
+		Inject(ctx, w.Header())
+		Debug(ctx, "http request started", "method", r.Method, "path", r.URL.Path)
+
+		sw := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		labels := []any{"method", r.Method, "path", r.URL.Path, "status", sw.status, "bytes", sw.bytes, "duration_ms", int(time.Since(start).Milliseconds())}
+		canonical := Canonical(ctx).Labels()
+
+		for i := 0; i+1 < len(canonical); i += 2 {
+			if key, _ := canonical[i].(string); !overridden(key, labels) {
+				labels = append(labels, canonical[i], canonical[i+1])
+			}
+		}
+
+		if sw.status >= 500 {
+			Error(ctx, "http request failed", nil, labels...)
+			return
+		}
+
+		Info(ctx, "http request completed", labels...)
+	})
+}
+
+// statusResponseWriter records the status and number of bytes of a response as it is written
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sw *statusResponseWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusResponseWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+
+	return n, err
+}
+
+// Flush flushes the underlying ResponseWriter, if it supports it, so that streaming responses are not buffered
+func (sw *statusResponseWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, so that a http.ResponseController can reach its other capabilities
+func (sw *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
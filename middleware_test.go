@@ -0,0 +1,162 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDevMiddleware(t *testing.T) {
+	defer resetDefaultLog()()
+
+	SetWriter(io.Discard)
+	SetOutputFormat(FormatLogfmt)
+
+	h := DevMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Info(r.Context(), "handling "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "failed", http.StatusInternalServerError)
+		case "/panic":
+			panic("boom")
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	type testCase struct {
+		ExpectedStatus int
+		ExpectedBody   []string
+	}
+
+	for path, tc := range map[string]testCase{
+		"/ok":    {ExpectedStatus: http.StatusOK, ExpectedBody: []string{"ok"}},
+		"/fail":  {ExpectedStatus: http.StatusInternalServerError, ExpectedBody: []string{"failed\n", "--- qlog trace ", `message="handling /fail"`}},
+		"/panic": {ExpectedStatus: http.StatusInternalServerError, ExpectedBody: []string{`message="handling /panic"`, `error="boom" message="handler panicked"`}},
+	} {
+		rs := httptest.NewRecorder()
+		h.ServeHTTP(rs, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rs.Code != tc.ExpectedStatus {
+			t.Fatalf("%v: expected status %v but got %v", path, tc.ExpectedStatus, rs.Code)
+		}
+
+		for _, expected := range tc.ExpectedBody {
+			if !strings.Contains(rs.Body.String(), expected) {
+				t.Fatalf("%v: expected body to contain '%v' but got '%v'", path, expected, rs.Body.String())
+			}
+		}
+
+		if path == "/ok" && rs.Body.String() != "ok" {
+			t.Fatalf("%v: expected logs to be omitted from successful responses but got '%v'", path, rs.Body.String())
+		}
+	}
+
+	if capturing.Load() != 0 || len(captures) != 0 {
+		t.Fatalf("expected captures to be removed once requests complete")
+	}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	h = DevMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Info(ContextFromTraceparent(context.Background(), traceparent, ""), "another request of the trace")
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}))
+
+	rq, rs := httptest.NewRequest(http.MethodGet, "/fail", nil), httptest.NewRecorder()
+	rq.Header.Set("Traceparent", traceparent)
+	h.ServeHTTP(rs, rq)
+
+	if strings.Contains(rs.Body.String(), "another request of the trace") {
+		t.Fatalf("expected only the logs of the request to be captured, rather than all those of its Trace-ID, but got '%v'", rs.Body.String())
+	}
+}
+
+func TestProxyRequestIDHeaders(t *testing.T) {
+	defer resetDefaultLog()()
+
+	SetWriter(io.Discard)
+	SetOutputFormat(FormatLogfmt)
+
+	h := DevMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("CF-Ray", "7d1c2b3a4e5f6789-LHR")
+	r.Header.Set("X-Amzn-Trace-Id", "Root=1-67891233-abcdef012345678912345678")
+
+	rs := httptest.NewRecorder()
+	h.ServeHTTP(rs, r)
+
+	expected := `error="boom" amzn_trace_id="Root=1-67891233-abcdef012345678912345678" cf_ray="7d1c2b3a4e5f6789-LHR" message="handler panicked"`
+
+	if !strings.Contains(rs.Body.String(), expected) {
+		t.Fatalf("expected the panic log to include the proxy request IDs '%v' but got '%v'", expected, rs.Body.String())
+	}
+}
+
+func TestRequestContextTraceparent(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	if ctx := RequestContext(r); TraceID(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" || ParentSpanID(ctx) != "00f067aa0ba902b7" {
+		t.Fatalf("expected the request context to continue the trace of the traceparent header but got trace '%v' and parent '%v'", TraceID(ctx), ParentSpanID(ctx))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	defer resetDefaultLog()()
+
+	w := &strings.Builder{}
+
+	SetWriter(w)
+	SetOutputFormat(FormatLogfmt)
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ParentSpanID(r.Context()) != "00f067aa0ba902b7" {
+			t.Fatalf("expected the handler's context to continue the trace of the request but got parent '%v'", ParentSpanID(r.Context()))
+		}
+
+		Canonical(r.Context()).Set("db_ms", 12)
+		Canonical(r.Context()).Set("status", "overridden")
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("accepted"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/orders?id=1", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rs := httptest.NewRecorder()
+	h.ServeHTTP(rs, r)
+
+	if traceID, parentSpanID, _, _ := ParseTraceparent(rs.Header().Get("traceparent")); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || parentSpanID == "00f067aa0ba902b7" {
+		t.Fatalf("expected the response to carry the request's span of the trace but got '%v'", rs.Header().Get("traceparent"))
+	}
+
+	logs, expectedLogs := strings.Split(strings.TrimSpace(w.String()), "\n"), [][]string{
+		{`trace="4bf92f3577b34da6a3ce929d0e0e4736"`, `severity="DEBUG"`, `method="POST" path="/orders" message="http request started"`},
+		{`trace="4bf92f3577b34da6a3ce929d0e0e4736"`, `severity="INFO"`, `method="POST" path="/orders" status=202 bytes=8 duration_ms=`, `db_ms=12 message="http request completed"`},
+	}
+
+	if !DebugEnabled { // the start is logged at Debug, which is compiled out by the qlog_nodebug build tag
+		expectedLogs = expectedLogs[1:]
+	}
+
+	if len(logs) != len(expectedLogs) {
+		t.Fatalf("expected %v logs but got '%v'", len(expectedLogs), logs)
+	}
+
+	for i, expected := range expectedLogs {
+		for _, e := range expected {
+			if !strings.Contains(logs[i], e) {
+				t.Fatalf("expected log %v to contain '%v' but got '%v'", i, e, logs[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package qlog
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// inheritMask is stored as the OutputMask of a named Log to indicate that it inherits the verbosity of its parent
+const inheritMask = -1
+
+var (
+	namedMx = sync.Mutex{}
+	named   = map[string]*Log{}
+)
+
+// Get returns the named Log for a component of the system, such as "payments.gateway", creating it on first use.
+// Subsequent calls with the same name return the same Log. Named Logs write the name in a `logger` label.
+//
+// Names are hierarchical, with each dot separated segment identifying a child of the preceding name. Until its own
+// OutputMask is set, a named Log inherits the verbosity of its nearest ancestor that has one, and ultimately that of
+// the default logger. This allows the verbosity of a whole component to be changed in one place.
+//
+// For example:
+//
+//	qlog.Get("payments").SetOutputMask(qlog.OutputMaskAll) // also applies to "payments.gateway" and "payments.ledger"
+//
+// A named Log is derived from the default logger when it is first retrieved, so the default logger should be
+// configured before then. Only its verbosity continues to follow subsequent changes to the default logger.
+// This operation is safe for concurrent use.
+func Get(name string) *Log {
+	namedMx.Lock()
+	defer namedMx.Unlock()
+
+	return get(name)
+}
+
+func get(name string) *Log {
+	if name == "" {
+		return defaultLog.Load()
+	}
+
+	if l, ok := named[name]; ok {
+		return l
+	}
+
+	parent := defaultLog.Load()
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		parent = get(name[:i])
+	}
+
+	l := defaultLog.Load().WithLabels("logger", name)
+	l.name, l.parent, l.outputMask = name, parent, &atomic.Int64{}
+	l.outputMask.Store(inheritMask)
+
+	if spec := levelSpecs.Load(); spec != nil {
+		if m, ok := spec.masks[name]; ok {
+			l.SetOutputMask(m)
+		}
+	}
+
+	named[name] = l
+
+	return l
+}
+
+// Name returns the name of the Log, if it was retrieved with Get, or an empty string
+func (l *Log) Name() string {
+	return l.name
+}
+
+// InheritOutputMask clears the OutputMask of a named Log, so that it once again inherits the verbosity of its parent.
+// It has no effect on a Log that was not retrieved with Get.
+// This operation is safe for concurrent use.
+func (l *Log) InheritOutputMask() {
+	if l.parent != nil {
+		l.outputMask.Store(inheritMask)
+	}
+}
@@ -0,0 +1,70 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// resetNamed forgets the named Logs, so that tests can retrieve them afresh
+func resetNamed() {
+	namedMx.Lock()
+	defer namedMx.Unlock()
+
+	named = map[string]*Log{}
+}
+
+func TestGet(t *testing.T) {
+	resetNamed()
+	defer resetNamed()
+
+	buf := bytes.Buffer{}
+	SetWriter(&buf)
+	SetOutputFormat(FormatLogfmt)
+	SetOutputMask(OutputMaskImportant)
+
+	defer func() {
+		SetWriter(os.Stderr)
+		SetOutputFormat(FormatJSON)
+		SetOutputMask(OutputMaskAll)
+	}()
+
+	gateway, payments := Get("payments.gateway"), Get("payments")
+
+	if Get("payments.gateway") != gateway || gateway.Name() != "payments.gateway" {
+		t.Fatalf("expected the same named Log to be returned for the same name")
+	}
+
+	ctx := context.Background()
+
+	type testCase struct {
+		Name     string
+		Setup    func()
+		Expected bool
+	}
+
+	for _, tc := range []testCase{ // applied in order, each building on the last
+		{Name: "inherits default", Setup: func() {}, Expected: false},
+		{Name: "default changed", Setup: func() { SetOutputMask(OutputMaskAll) }, Expected: true},
+		{Name: "parent set", Setup: func() { payments.SetOutputMask(OutputMaskImportant) }, Expected: false},
+		{Name: "own set", Setup: func() { gateway.SetOutputMask(OutputMaskDetail) }, Expected: true},
+		{Name: "own cleared", Setup: func() { gateway.InheritOutputMask() }, Expected: false},
+		{Name: "parent cleared", Setup: func() { payments.InheritOutputMask() }, Expected: true},
+		{Name: "sibling unchanged", Setup: func() { Get("payments.ledger").SetOutputMask(OutputFlagNone) }, Expected: true},
+	} {
+		buf.Reset()
+		tc.Setup()
+
+		gateway.Info(ctx, "test")
+
+		if written := buf.Len() > 0; written != tc.Expected {
+			t.Fatalf("%v: expected written to be %v but got %v", tc.Name, tc.Expected, written)
+		}
+	}
+
+	if !strings.Contains(buf.String(), ` logger="payments.gateway" `) {
+		t.Fatalf("expected logger label but got '%v'", buf.String())
+	}
+}
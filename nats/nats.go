@@ -0,0 +1,76 @@
+// Package nats provides a qlog sink that publishes logs to a NATS subject through a Publisher, such as a *nats.Conn,
+// or, for persistence, a JetStream context, without qlog depending on the NATS client.
+//
+// For example, publishing to core NATS:
+//
+//	qlog.SetWriter(nats.NewAsync(nc, "logs.checkout", 4096))
+//
+// Or to a JetStream stream, which acknowledges that each log has been persisted:
+//
+//	qlog.SetWriter(nats.NewAsync(nats.PublisherFunc(func(subject string, data []byte) error {
+//		_, err := js.Publish(subject, data)
+//		return err
+//	}), "logs.checkout", 4096))
+package nats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Publisher publishes data to a NATS subject. It is implemented by *nats.Conn
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// PublisherFunc is a func that implements Publisher
+type PublisherFunc func(subject string, data []byte) error
+
+// Publish calls fn with the subject and data
+func (fn PublisherFunc) Publish(subject string, data []byte) error {
+	return fn(subject, data)
+}
+
+// Writer is an io.Writer that publishes each log to a NATS subject through a Publisher
+type Writer struct {
+	publisher Publisher
+	subject   string
+}
+
+// New creates a Writer that publishes each log to the subject, synchronously, through the Publisher
+func New(p Publisher, subject string) *Writer {
+	return &Writer{publisher: p, subject: subject}
+}
+
+// NewAsync creates a qlog.AsyncWriter that publishes logs to the subject through the Publisher from a background
+// goroutine, so that writing a log never waits on NATS, such as for a JetStream acknowledgement. Up to pending logs are
+// held while waiting to be published; beyond that the oldest are dropped and a summary of those dropped is published
+// each minute.
+//
+// Call qlog.Close before the process exits, such as with a defer in main, so that pending logs are not lost
+func NewAsync(p Publisher, subject string, pending int) *qlog.AsyncWriter {
+	return qlog.NewNonBlockingWriter(New(p, subject), pending, qlog.DropOldest, time.Minute)
+}
+
+// Write publishes b to the subject. The Publisher must not retain b, which is only valid for the duration of the call;
+// *nats.Conn copies it into its own buffer
+func (w *Writer) Write(b []byte) (int, error) {
+	if err := w.publisher.Publish(w.subject, b); err != nil {
+		return 0, fmt.Errorf("nats: unable to publish to %v: %w", w.subject, err)
+	}
+
+	return len(b), nil
+}
+
+// Flush waits until the Publisher has sent all published logs to the server, if it has a
+// FlushWithContext(context.Context) error method, as *nats.Conn does, so that qlog.Flush does not return before them
+func (w *Writer) Flush(ctx context.Context) error {
+	if f, ok := w.publisher.(interface{ FlushWithContext(context.Context) error }); ok {
+		return f.FlushWithContext(ctx)
+	}
+
+	return nil
+}
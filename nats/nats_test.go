@@ -0,0 +1,80 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comradequinn/qlog"
+)
+
+type publisher struct {
+	mx       sync.Mutex
+	gate     chan struct{}
+	messages []string
+	flushed  bool
+}
+
+func (p *publisher) Publish(subject string, data []byte) error {
+	<-p.gate
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.messages = append(p.messages, subject+" "+string(data))
+
+	return nil
+}
+
+func (p *publisher) FlushWithContext(ctx context.Context) error {
+	p.flushed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	p := &publisher{gate: make(chan struct{})}
+	close(p.gate)
+
+	w := New(p, "logs")
+	w.Write([]byte("log\n"))
+	w.Flush(context.Background())
+
+	if len(p.messages) != 1 || p.messages[0] != "logs log\n" || !p.flushed {
+		t.Fatalf("expected a flushed message on the subject but got '%v'", p.messages)
+	}
+
+	errPublish := errors.New("no responders")
+	w = New(PublisherFunc(func(subject string, data []byte) error { return errPublish }), "logs")
+
+	if _, err := w.Write([]byte("log\n")); !errors.Is(err, errPublish) {
+		t.Fatalf("expected the publisher's error but got '%v'", err)
+	}
+}
+
+func TestNewAsync(t *testing.T) {
+	p := &publisher{gate: make(chan struct{})}
+	aw := NewAsync(p, "logs", 2)
+
+	l := qlog.New(qlog.OutputMaskAll, false)
+	l.Writer = aw
+
+	for _, message := range []string{"first", "second", "third", "fourth"} {
+		l.Info(context.Background(), message) // first is taken by the background goroutine and blocked on the gate
+	}
+
+	if aw.Dropped() == 0 {
+		t.Fatalf("expected logs beyond the pending limit to be dropped")
+	}
+
+	close(p.gate)
+
+	if err := aw.Close(); err != nil {
+		t.Fatalf("expected close to publish pending logs but got '%v'", err)
+	}
+
+	if messages := strings.Join(p.messages, ""); !strings.Contains(messages, `message="fourth"`) {
+		t.Fatalf("expected the newest logs to be published but got '%v'", messages)
+	}
+}
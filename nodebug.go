@@ -0,0 +1,44 @@
+//go:build qlog_nodebug
+
+package qlog
+
+import "context"
+
+// DebugEnabled reports whether Debug and Trace logs are compiled into the binary, see the qlog_nodebug build tag.
+// Under the tag, Debug and Trace are empty funcs, but their arguments are still evaluated by the caller. Call sites
+// whose arguments are costly to build can be guarded by DebugEnabled, a constant, so that the compiler removes them:
+//
+//	if qlog.DebugEnabled {
+//		qlog.Debug(ctx, "request", "body", string(body))
+//	}
+const DebugEnabled = false
+
+// Trace is compiled to an empty func under the qlog_nodebug build tag, so that trace call sites write nothing, see DebugEnabled
+func Trace(ctx context.Context, message string, labels ...any) {}
+
+// Debug is compiled to an empty func under the qlog_nodebug build tag, so that debug call sites write nothing, see DebugEnabled
+func Debug(ctx context.Context, message string, labels ...any) {}
+
+// Trace is compiled to an empty func under the qlog_nodebug build tag, so that trace call sites write nothing, see DebugEnabled
+func (l *Log) Trace(ctx context.Context, message string, labels ...any) {}
+
+// Debug is compiled to an empty func under the qlog_nodebug build tag, so that debug call sites write nothing, see DebugEnabled
+func (l *Log) Debug(ctx context.Context, message string, labels ...any) {}
+
+// Trace is compiled to an empty func under the qlog_nodebug build tag, so that trace call sites write nothing, see DebugEnabled
+func (bl *BoundLog) Trace(message string, labels ...any) {}
+
+// Debug is compiled to an empty func under the qlog_nodebug build tag, so that debug call sites write nothing, see DebugEnabled
+func (bl *BoundLog) Debug(message string, labels ...any) {}
+
+// Trace is compiled to an empty func under the qlog_nodebug build tag, so that trace call sites write nothing, see DebugEnabled
+func (bt *Batch) Trace(message string, labels ...any) {}
+
+// Debug is compiled to an empty func under the qlog_nodebug build tag, so that debug call sites write nothing, see DebugEnabled
+func (bt *Batch) Debug(message string, labels ...any) {}
+
+// Trace is compiled to an empty func under the qlog_nodebug build tag, so that trace call sites write nothing, see DebugEnabled
+func (d *Deferred) Trace(message string, labels ...any) {}
+
+// Debug is compiled to an empty func under the qlog_nodebug build tag, so that debug call sites write nothing, see DebugEnabled
+func (d *Deferred) Debug(message string, labels ...any) {}
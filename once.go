@@ -0,0 +1,79 @@
+package qlog
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var callSiteCounts = sync.Map{} // the number of calls to Once or EveryN made by each call site, keyed by its pc
+
+// Once returns a BoundLog for ctx that writes logs only for the first call to Once made by its call site, such that a
+// deprecation warning, for example, is written once per process rather than once per call. Fatal and Audit logs are
+// always written.
+//
+// For example:
+//
+//	logger.Once(ctx).Warning("the v1 API is deprecated", nil, "client", clientID)
+func (l *Log) Once(ctx context.Context) *BoundLog {
+	return l.everyN(ctx, 0, callerPC())
+}
+
+// Once returns a BoundLog for ctx that writes logs with the default logger only for the first call to Once made by its
+// call site, see Log.Once
+// This operation is safe for concurrent use.
+func Once(ctx context.Context) *BoundLog {
+	return defaultLog.Load().everyN(ctx, 0, callerPC())
+}
+
+// EveryN returns a BoundLog for ctx that writes logs only for every nth call to EveryN made by its call site, starting
+// with the first, such that a diagnostic written on each iteration of a loop, for example, is sampled rather than flood
+// the output. Fatal and Audit logs are always written.
+//
+// For example:
+//
+//	for i, item := range items {
+//		logger.EveryN(ctx, 1000).Debug("processing items", "processed", i)
+//	}
+func (l *Log) EveryN(ctx context.Context, n int) *BoundLog {
+	return l.everyN(ctx, n, callerPC())
+}
+
+// EveryN returns a BoundLog for ctx that writes logs with the default logger only for every nth call to EveryN made by
+// its call site, see Log.EveryN
+// This operation is safe for concurrent use.
+func EveryN(ctx context.Context, n int) *BoundLog {
+	return defaultLog.Load().everyN(ctx, n, callerPC())
+}
+
+// everyN returns a BoundLog for ctx whose logs are suppressed unless this is the first, or an nth, call made by the call
+// site with the specified pc. Where n is less than one, only the first call is not suppressed. The suppression is held by
+// the BoundLog, rather than ctx, so that logs written with its Context are unaffected
+func (l *Log) everyN(ctx context.Context, n int, pc uintptr) *BoundLog {
+	c, ok := callSiteCounts.Load(pc)
+
+	if !ok {
+		c, _ = callSiteCounts.LoadOrStore(pc, &atomic.Uint64{})
+	}
+
+	i := c.(*atomic.Uint64).Add(1) - 1
+	bl := l.For(ctx)
+	bl.l.suppressed = (n < 1 && i > 0) || (n >= 1 && i%uint64(n) != 0)
+
+	return bl
+}
+
+// callerPC returns the pc of the call to the func that called callerPC, which identifies its call site
+func callerPC() uintptr {
+	pc := [1]uintptr{}
+	runtime.Callers(3, pc[:]) // skips Callers, callerPC and its caller
+
+	return pc[0]
+}
+
+// suppresses reports whether a log with the specified flag is not written because the Log is that of a BoundLog
+// returned by Once or EveryN whose call site was not due to log
+func (l *Log) suppresses(flag OutputMask) bool {
+	return l.suppressed && flag&(OutputFlagFatal|OutputFlagAudit) == 0
+}
@@ -0,0 +1,55 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// resetCallSites forgets the calls made by each call site to Once and EveryN, so that tests can be repeated
+func resetCallSites() {
+	callSiteCounts.Range(func(pc, _ any) bool {
+		callSiteCounts.Delete(pc)
+		return true
+	})
+}
+
+func TestOnceEveryN(t *testing.T) {
+	resetCallSites()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		l.Once(ctx).Warning("deprecated", nil)
+		l.EveryN(ctx, 2).Info("iterated", "i", i)
+		l.EveryN(ctx, 2).Info("iterated elsewhere", "i", i) // a separate call site, so counted separately
+		l.Once(ctx).Audit("audited")
+	}
+
+	for expected, count := range map[string]int{
+		`message="deprecated"`:         1,
+		`i=0 message="iterated"`:       1,
+		`i=2 message="iterated"`:       1,
+		`i=4 message="iterated"`:       1,
+		`message="iterated"`:           3,
+		`message="iterated elsewhere"`: 3,
+		`message="audited"`:            5,
+	} {
+		if actual := strings.Count(sb.String(), expected); actual != count {
+			t.Fatalf("expected '%v' to be written %v times but got %v: %v", expected, count, actual, sb.String())
+		}
+	}
+
+	sb.Reset()
+
+	for i := 0; i < 3; i++ {
+		bl := l.Once(ctx)
+		l.Error(bl.Context(), "failed", nil)
+	}
+
+	if actual := strings.Count(sb.String(), `message="failed"`); actual != 3 {
+		t.Fatalf("expected logs written with the context of a suppressed BoundLog to be written but got %v: %v", actual, sb.String())
+	}
+}
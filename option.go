@@ -0,0 +1,64 @@
+package qlog
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Option overrides an element of the configuration of a Log derived with With
+type Option func(l *Log)
+
+// With creates a new Log that inherits all the configuration and labels of the receiver Log, other than
+// that overridden by the specified Options, which are applied in order. Unless overridden with Mask, the derived Log
+// shares the OutputMask of the receiver Log, so a change in verbosity of either applies to both.
+//
+// For example:
+//
+//	cache := logger.With(qlog.Labels("component", "cache"), qlog.Mask(qlog.OutputMaskImportant), qlog.Writer(cacheFile))
+func (l *Log) With(opts ...Option) *Log {
+	d := *l // carry all configuration to the derived Log
+
+	for _, o := range opts {
+		o(&d)
+	}
+
+	return &d
+}
+
+// With creates a new Log that inherits all the configuration and labels of the default logger, other than
+// that overridden by the specified Options, which are applied in order.
+// This operation is safe for concurrent use.
+func With(opts ...Option) *Log {
+	return defaultLog.Load().With(opts...)
+}
+
+// Labels returns an Option that adds the specified labels to those of the derived Log. Where a specified label has
+// the same key as an existing label, it replaces it
+func Labels(labels ...any) Option {
+	return func(l *Log) {
+		l.commonLabels = writeLabels(append([]commonLabel(nil), l.commonLabels...), l.outputJSON, labels)
+	}
+}
+
+// Mask returns an Option that gives the derived Log its own, independent, OutputMask
+func Mask(m OutputMask) Option {
+	return func(l *Log) {
+		l.outputMask, l.parent = &atomic.Int64{}, nil
+		l.outputMask.Store(int64(m))
+	}
+}
+
+// Writer returns an Option that sets the destination of the logs of the derived Log
+func Writer(w io.Writer) Option {
+	return func(l *Log) {
+		l.Writer = w
+	}
+}
+
+// OutputFormat returns an Option that sets the Format of the derived Log. Its labels are retained and written in the new Format
+func OutputFormat(f Format) Option {
+	return func(l *Log) {
+		l.outputJSON = f == FormatJSON
+		l.commonLabels = renderLabels(l.commonLabels, l.outputJSON)
+	}
+}
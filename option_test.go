@@ -0,0 +1,33 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	sb, derived := strings.Builder{}, strings.Builder{}
+	l := New(OutputMaskImportant, true, "app", "test")
+	l.Writer, l.TraceIDFieldName = &sb, "trace_id"
+
+	d := l.With(Labels("component", "cache"), OutputFormat(FormatLogfmt), Mask(OutputMaskAll), Writer(&derived), Labels("region", "eu"))
+	ctx := ContextFrom(context.Background(), "abc")
+
+	d.Info(ctx, "derived")
+	l.Info(ctx, "parent")
+
+	if output := derived.String(); !strings.HasPrefix(output, `trace_id="abc"`) || !strings.Contains(output, ` app="test" component="cache" region="eu" message="derived"`) {
+		t.Fatalf("expected derived Log to inherit configuration with overrides applied but got '%s'", output)
+	}
+
+	if output := sb.String(); output != "" {
+		t.Fatalf("expected parent Log to be unmodified but got '%s'", output)
+	}
+
+	l.Warning(ctx, "parent", nil)
+
+	if output := sb.String(); !strings.Contains(output, `"app": "test", "message": "parent"`) {
+		t.Fatalf("expected parent Log to retain its format and labels but got '%s'", output)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/comradequinn/qlog/otel
+
+go 1.20
+
+require (
+	github.com/comradequinn/qlog v0.0.0-20261016145609-6704240d2445
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require go.opentelemetry.io/otel v1.24.0 // indirect
+
+// builds and tests the module against the qlog module of this repository; dependents resolve the version required above
+replace github.com/comradequinn/qlog => ../
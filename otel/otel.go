@@ -0,0 +1,38 @@
+// Package otel correlates qlog logs with OpenTelemetry traces. It is a separate module so that qlog does not depend on
+// OpenTelemetry.
+//
+// Once enabled, logs written with a context.Context on which an OpenTelemetry span is active have its Trace-ID and
+// Span-ID, rather than those minted by qlog, so that logs written within instrumented code correlate with its traces.
+// For example:
+//
+//	otel.Enable()
+//
+//	ctx, span := tracer.Start(r.Context(), "checkout")
+//	defer span.End()
+//
+//	qlog.Info(ctx, "checking out") // trace="<otel trace id>" span="<otel span id>"
+package otel
+
+import (
+	"context"
+
+	"github.com/comradequinn/qlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Enable sets Span as the qlog.SpanSource, so that the spans of OpenTelemetry are read by qlog.
+// This operation is safe for concurrent use.
+func Enable() {
+	qlog.SetSpanSource(Span)
+}
+
+// Span is a qlog.SpanSource that returns the OpenTelemetry span active on ctx, if there is one with a valid span context
+func Span(ctx context.Context) (qlog.ExternalSpan, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+
+	if !sc.IsValid() {
+		return qlog.ExternalSpan{}, false
+	}
+
+	return qlog.ExternalSpan{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
@@ -0,0 +1,41 @@
+package otel
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/comradequinn/qlog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEnable(t *testing.T) {
+	Enable()
+	defer qlog.SetSpanSource(nil)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	w := &strings.Builder{}
+	l := qlog.New(qlog.OutputMaskAll, false).WithWriter(w)
+
+	l.Info(ctx, "instrumented")
+
+	if expected := `trace="4bf92f3577b34da6a3ce929d0e0e4736" span="00f067aa0ba902b7" severity="INFO"`; !strings.HasPrefix(w.String(), expected) {
+		t.Fatalf("expected the log to start '%v' but got '%v'", expected, w.String())
+	}
+
+	if expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; qlog.Traceparent(ctx) != expected {
+		t.Fatalf("expected traceparent '%v' but got '%v'", expected, qlog.Traceparent(ctx))
+	}
+
+	if uninstrumented := qlog.ContextFrom(context.Background(), ""); qlog.TraceID(uninstrumented) == "4bf92f3577b34da6a3ce929d0e0e4736" || qlog.TraceID(uninstrumented) == "" {
+		t.Fatalf("expected a context without a span to have a trace minted by qlog but got '%v'", qlog.TraceID(uninstrumented))
+	}
+}
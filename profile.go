@@ -0,0 +1,158 @@
+package qlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CallSiteStats is the volume of logs written by a call site during a profiling window
+type CallSiteStats struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Entries  uint64 `json:"entries"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+type (
+	// callers identifies a call site by the stack of pcs from the log method, it is resolved to a CallSiteStats when reported
+	callers     [6]uintptr
+	callerCount struct{ entries, bytes uint64 }
+)
+
+var (
+	profiling     = atomic.Bool{} // allows profile to return without acquiring a lock when profiling is not enabled
+	profileMx     = sync.Mutex{}
+	profileWindow time.Duration
+	profileStart  time.Time
+	profileCounts = map[callers]*callerCount{}
+	profileLast   map[callers]*callerCount // the counts of the last complete window
+)
+
+// StartProfiling attributes the number of logs and bytes written to the call sites that wrote them, over consecutive
+// windows of the specified duration. The busiest call sites can then be reported with TopCallSites or ProfileHandler,
+// identifying the log statements that dominate the volume, and so the cost, of ingestion.
+//
+// Profiling adds the cost of resolving the call site to each log written, so it is disabled by default.
+// The returned func stops profiling.
+func StartProfiling(window time.Duration) func() {
+	profileMx.Lock()
+	profileWindow, profileStart, profileCounts, profileLast = window, timeNow(), map[callers]*callerCount{}, nil
+	profileMx.Unlock()
+
+	profiling.Store(true)
+
+	return func() { profiling.Store(false) }
+}
+
+// TopCallSites returns the n call sites that wrote the most bytes in the last complete profiling window, or in the
+// current window if none has completed, in descending order of bytes
+func TopCallSites(n int) []CallSiteStats {
+	profileMx.Lock()
+	counts := profileLast
+
+	if counts == nil || timeNow().Sub(profileStart) >= 2*profileWindow { // the last window is stale if no logs have been written since
+		counts = profileCounts
+	}
+
+	sites := map[string]*CallSiteStats{}
+
+	for c, cc := range counts {
+		f, ok := callSite(c[:])
+
+		if !ok {
+			continue
+		}
+
+		key := f.File + ":" + strconv.Itoa(f.Line) // the same call site may be reached by more than one path through qlog
+
+		if _, ok := sites[key]; !ok {
+			sites[key] = &CallSiteStats{Function: f.Function, File: f.File, Line: f.Line}
+		}
+
+		sites[key].Entries += cc.entries
+		sites[key].Bytes += cc.bytes
+	}
+
+	profileMx.Unlock()
+
+	top := make([]CallSiteStats, 0, len(sites))
+
+	for _, s := range sites {
+		top = append(top, *s)
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Bytes != top[j].Bytes {
+			return top[i].Bytes > top[j].Bytes
+		}
+
+		return top[i].File+strconv.Itoa(top[i].Line) < top[j].File+strconv.Itoa(top[j].Line)
+	})
+
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// ProfileHandler returns a http.Handler that serves the call sites reported by TopCallSites as JSON, such as on an
+// admin port. The number of call sites can be specified in the `n` query parameter; by default it is 10.
+//
+// If authorize is not nil, it is called for each request. If it returns false, the request is rejected with a 403
+func ProfileHandler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize != nil && !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		n := 10
+
+		if v := r.URL.Query().Get("n"); v != "" {
+			var err error
+
+			if n, err = strconv.Atoi(v); err != nil || n < 1 {
+				http.Error(w, "invalid n: must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TopCallSites(n))
+	})
+}
+
+// profile attributes a log of the specified size to its call site, if profiling is enabled
+func profile(size int) {
+	if !profiling.Load() {
+		return
+	}
+
+	c := callers{}
+	runtime.Callers(3, c[:]) // skips Callers, profile and log
+
+	profileMx.Lock()
+	defer profileMx.Unlock()
+
+	if now := timeNow(); now.Sub(profileStart) >= profileWindow {
+		profileLast, profileCounts, profileStart = profileCounts, map[callers]*callerCount{}, now
+	}
+
+	cc, ok := profileCounts[c]
+
+	if !ok {
+		cc = &callerCount{}
+		profileCounts[c] = cc
+	}
+
+	cc.entries++
+	cc.bytes += uint64(size)
+}
@@ -0,0 +1,60 @@
+package qlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfiling(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	l := New(OutputMaskAll, false)
+	l.Writer = io.Discard
+	ctx := context.Background()
+
+	l.Info(ctx, "not profiled")
+
+	stop := StartProfiling(time.Minute)
+	defer stop()
+
+	for i := 0; i < 3; i++ {
+		l.Info(ctx, "small")
+	}
+
+	l.Info(ctx, strings.Repeat("large", 100))
+
+	top := TopCallSites(1)
+
+	if len(top) != 1 || top[0].Entries != 1 || !strings.HasSuffix(top[0].File, "profile_test.go") || top[0].Line != 32 {
+		t.Fatalf("expected the call site with the most bytes but got '%+v'", top)
+	}
+
+	now = now.Add(time.Minute)
+	l.Info(ctx, "next window")
+
+	if top = TopCallSites(10); len(top) != 2 || top[1].Entries != 3 || top[1].Line != 29 {
+		t.Fatalf("expected the call sites of the last complete window but got '%+v'", top)
+	}
+
+	rs := httptest.NewRecorder()
+	ProfileHandler(nil).ServeHTTP(rs, httptest.NewRequest(http.MethodGet, "/?n=1", nil))
+
+	if err := json.NewDecoder(rs.Body).Decode(&top); err != nil || len(top) != 1 || top[0].Line != 32 {
+		t.Fatalf("expected the handler to serve the top call sites but got '%+v' (%v)", top, err)
+	}
+
+	stop()
+	l.Info(ctx, "not profiled")
+
+	if top = TopCallSites(10); len(top) != 2 {
+		t.Fatalf("expected logs written once profiling is stopped to be excluded but got '%+v'", top)
+	}
+}
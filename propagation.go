@@ -0,0 +1,163 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Propagator continues traces across service boundaries by extracting them from, and injecting them into, the headers
+// of HTTP requests and responses in a particular propagation format
+type Propagator interface {
+	// Extract creates a new context.Context, derived from ctx, in the trace carried by h, with a new, unique, Span-ID
+	// whose parent is the remote span that sent it. Should h carry no valid trace, the result is that of
+	// ContextFrom(ctx, "")
+	Extract(ctx context.Context, h http.Header) context.Context
+	// Inject sets the headers that carry the trace and span of ctx in h, if ctx has a trace that can be represented in
+	// the propagation format
+	Inject(ctx context.Context, h http.Header)
+}
+
+// Supported Propagators
+var (
+	// W3CPropagator propagates traces in W3C trace context traceparent and tracestate headers
+	W3CPropagator Propagator = w3cPropagator{}
+	// B3Propagator propagates traces in a Zipkin B3 single header, `b3`, and extracts them from either B3 single or B3
+	// multi headers
+	B3Propagator Propagator = b3Propagator{}
+	// B3MultiPropagator propagates traces in Zipkin B3 multi headers, such as `X-B3-TraceId`, and extracts them from
+	// either B3 single or B3 multi headers
+	B3MultiPropagator Propagator = b3Propagator{multi: true}
+	// XRayPropagator propagates traces in the AWS X-Ray XRayHeader, so that the Trace-IDs of logs line up with X-Ray
+	// segments
+	XRayPropagator Propagator = xrayPropagator{}
+)
+
+// propagatorValue wraps a Propagator so that those of differing types can be stored in the same atomic.Value
+type propagatorValue struct {
+	Propagator
+}
+
+var propagation = func() *atomic.Value {
+	v := &atomic.Value{}
+	v.Store(propagatorValue{W3CPropagator})
+
+	return v
+}()
+
+// SetPropagator sets the Propagator used by RequestContext, and so DevMiddleware, to continue the traces of requests.
+// By default it is W3CPropagator; pass nil to restore the default.
+// This operation is safe for concurrent use.
+func SetPropagator(p Propagator) {
+	if p == nil {
+		p = W3CPropagator
+	}
+
+	propagation.Store(propagatorValue{p})
+}
+
+// Inject sets the headers that carry the trace and span of ctx in h, such as those of a request to a downstream service,
+// in the format of the Propagator set with SetPropagator, along with the VerboseHeader if the trace is verbose
+func Inject(ctx context.Context, h http.Header) {
+	propagator().Inject(ctx, h)
+	InjectVerbose(ctx, h)
+}
+
+// Extract returns the Trace-ID and Span-ID of the remote span carried by h, such as the headers of a request from an
+// upstream service, in the format of the Propagator set with SetPropagator. Empty strings are returned if h carries no
+// valid trace. Pass them to ContextFromRemote to continue the trace
+func Extract(h http.Header) (traceID, spanID string) {
+	_, sc, ok := remoteSpan(propagator(), h)
+
+	if !ok {
+		return "", ""
+	}
+
+	return sc.traceID, sc.parentSpanID
+}
+
+// ContextFromRemote creates a new context.Context in the passed trace, with a new, unique, Span-ID whose parent is the
+// passed remote span, such as those returned by Extract. This allows a Propagator to be implemented for any propagation
+// format.
+//
+// Should traceID or spanID be empty, the result is that of ContextFrom(ctx, traceID). Invalid Trace-IDs are handled
+// according to the TraceIDPolicy, as they are by ContextFrom, and invalid Span-IDs are discarded
+func ContextFromRemote(ctx context.Context, traceID, spanID string) context.Context {
+	if traceID == "" || spanID == "" || !validTraceID(spanID) {
+		return ContextFrom(ctx, traceID)
+	}
+
+	if !validTraceID(traceID) {
+		if ctx, traceID = invalidTraceID(ctx, traceID); traceID == "" {
+			return ContextFrom(ctx, "")
+		}
+	}
+
+	return contextFromRemote(ctx, traceID, spanID, true, "")
+}
+
+// CompositePropagator returns a Propagator that extracts traces with the first of the passed Propagators to find a
+// valid trace in the headers, and injects them with all of them. This allows services to accept, and send, several
+// propagation formats while migrating from one to another
+func CompositePropagator(propagators ...Propagator) Propagator {
+	return compositePropagator(propagators)
+}
+
+// compositePropagator is a Propagator that delegates to several Propagators
+type compositePropagator []Propagator
+
+func (c compositePropagator) Extract(ctx context.Context, h http.Header) context.Context {
+	for _, p := range c {
+		if remote, sc, ok := remoteSpan(p, h); ok {
+			if sampled, decided := Sampled(remote); decided { // the sampling decision of the remote parent is carried apart from its span
+				ctx = WithSampled(ctx, sampled)
+			}
+
+			return context.WithValue(ctx, traceIDKey, sc)
+		}
+	}
+
+	return ContextFrom(ctx, "")
+}
+
+func (c compositePropagator) Inject(ctx context.Context, h http.Header) {
+	for _, p := range c {
+		p.Inject(ctx, h)
+	}
+}
+
+// remoteSpan returns the context.Context, and span, created by p to continue the trace carried by h, if h carries a
+// valid trace. It is extracted with a context.Context that has no trace, so that a span with a parent must have
+// continued a remote trace
+func remoteSpan(p Propagator, h http.Header) (context.Context, spanContext, bool) {
+	remote := p.Extract(context.Background(), h)
+	sc, _ := remote.Value(traceIDKey).(spanContext)
+
+	return remote, sc, sc.traceID != "" && sc.parentSpanID != ""
+}
+
+// propagator returns the Propagator set with SetPropagator
+func propagator() Propagator {
+	return propagation.Load().(propagatorValue).Propagator
+}
+
+// w3cPropagator is a Propagator for W3C trace context
+type w3cPropagator struct{}
+
+func (w3cPropagator) Extract(ctx context.Context, h http.Header) context.Context {
+	return ContextFromTraceparent(ctx, h.Get("traceparent"), h.Get("tracestate"))
+}
+
+func (w3cPropagator) Inject(ctx context.Context, h http.Header) {
+	traceparent := Traceparent(ctx)
+
+	if traceparent == "" {
+		return
+	}
+
+	h.Set("traceparent", traceparent)
+
+	if tracestate := Tracestate(ctx); tracestate != "" {
+		h.Set("tracestate", tracestate)
+	}
+}
@@ -0,0 +1,72 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetPropagator(t *testing.T) {
+	SetPropagator(B3Propagator)
+	defer SetPropagator(nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+
+	if ctx := RequestContext(r); TraceID(ctx) != "80f198ee56343ba864fe8b2a57d3eff7" || ParentSpanID(ctx) != "e457b5a2e4d86bd1" {
+		t.Fatalf("expected the request context to continue the trace of the b3 header but got trace '%v' and parent '%v'", TraceID(ctx), ParentSpanID(ctx))
+	}
+
+	SetPropagator(nil)
+
+	if ctx := RequestContext(r); TraceID(ctx) == "80f198ee56343ba864fe8b2a57d3eff7" {
+		t.Fatalf("expected the default propagator to ignore the b3 header")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	SetPropagator(CompositePropagator(W3CPropagator, B3Propagator))
+	defer SetPropagator(nil)
+
+	for name, h := range map[string]http.Header{
+		"traceparent": {"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		"b3":          {"B3": {"4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"}},
+	} {
+		traceID, spanID := Extract(h)
+
+		if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7" {
+			t.Fatalf("%v: expected the trace and span of the headers but got '%v' and '%v'", name, traceID, spanID)
+		}
+
+		ctx := ContextFromRemote(context.Background(), traceID, spanID)
+
+		if TraceID(ctx) != traceID || ParentSpanID(ctx) != spanID || SpanID(ctx) == spanID {
+			t.Fatalf("%v: expected a child of the remote span but got trace '%v', span '%v' and parent '%v'", name, TraceID(ctx), SpanID(ctx), ParentSpanID(ctx))
+		}
+
+		injected := http.Header{}
+		Inject(ctx, injected)
+
+		if injected.Get("traceparent") != Traceparent(ctx) || injected.Get("b3") != traceID+"-"+SpanID(ctx)+"-1" {
+			t.Fatalf("%v: expected both traceparent and b3 headers to be injected but got '%v'", name, injected)
+		}
+	}
+
+	unsampled := propagator().Extract(context.Background(), http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}})
+
+	if sampled, _ := Sampled(unsampled); sampled || !strings.HasSuffix(Traceparent(unsampled), "-00") {
+		t.Fatalf("expected the sampling decision of the remote parent to be extracted but got '%v'", Traceparent(unsampled))
+	}
+
+	if traceID, spanID := Extract(http.Header{"Traceparent": {"invalid"}}); traceID != "" || spanID != "" {
+		t.Fatalf("expected headers without a valid trace to return empty ids but got '%v' and '%v'", traceID, spanID)
+	}
+
+	parent := ContextFrom(context.Background(), "")
+
+	if ctx := ContextFromRemote(parent, "", ""); TraceID(ctx) != TraceID(parent) || ParentSpanID(ctx) != SpanID(parent) {
+		t.Fatalf("expected empty ids to derive a child of the context but got trace '%v' and parent '%v'", TraceID(ctx), ParentSpanID(ctx))
+	}
+}
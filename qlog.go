@@ -3,37 +3,110 @@ package qlog
 import (
 	"context"
 	"io"
+	"os"
+	"sync"
+	"sync/atomic"
 )
 
-var defaultLog = New(OutputMaskAll, true)
+var (
+	defaultLog = func() *atomic.Pointer[Log] {
+		p := &atomic.Pointer[Log]{}
+		p.Store(New(OutputMaskAll, true))
+
+		return p
+	}()
+	defaultMx = sync.Mutex{} // serialises changes to the configuration of the default logger
+)
+
+// configure applies fn to a copy of the default logger which then replaces it. This allows the default logger to
+// be reconfigured whilst in use, as concurrent log calls never observe a partially configured Log
+func configure(fn func(l *Log)) {
+	defaultMx.Lock()
+	defer defaultMx.Unlock()
+
+	l := *defaultLog.Load()
+	fn(&l)
+	defaultLog.Store(&l)
+}
 
 // Sets the Writer used by the default logger
-// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+// This operation is safe for concurrent use.
 func SetWriter(w io.Writer) {
-	defaultLog.Writer = w
+	configure(func(l *Log) { l.Writer = w })
+}
+
+// Sets the Writer used by the default logger to a Router that writes Warning, and more severe, logs to stderr and
+// all other logs to stdout. Many container platforms treat the two streams differently, such as by colouring or alerting on stderr
+// This operation is safe for concurrent use.
+func SetSplitOutput() {
+	SetWriter(splitOutput())
+}
+
+// splitOutput returns a Router that writes Warning, and more severe, logs to stderr and all other logs to stdout
+func splitOutput() *Router {
+	stderr := MinSeverityMask(SeverityWarning) | OutputFlagAudit
+
+	return NewRouter(Route{Writer: os.Stdout, Mask: ^stderr}, Route{Writer: os.Stderr, Mask: stderr})
+}
+
+// Sets the Writer used by the default logger for Audit logs. If nil, Audit logs are written to the Writer
+// This operation is safe for concurrent use.
+func SetAuditWriter(w io.Writer) {
+	configure(func(l *Log) { l.AuditWriter = w })
 }
 
 // Sets the outputmask used by the default logger
-// This operation is intended for configuration during start-up. It is not safe for concurrent use.
-func SetOutputMask(m int) {
-	defaultLog.outputMask = m
+// This operation is safe for concurrent use, allowing verbosity to be changed at runtime.
+func SetOutputMask(m OutputMask) {
+	defaultLog.Load().SetOutputMask(m)
+}
+
+// Sets the outputmask used by the default logger to include all severities at or above the specified Severity
+// This is an alternative to SetOutputMask for the common case of requiring everything above a threshold to be written.
+// This operation is safe for concurrent use, allowing verbosity to be changed at runtime.
+func SetMinSeverity(s Severity) {
+	SetOutputMask(MinSeverityMask(s))
 }
 
 // Sets whether the output of the default logger is JSON or logfmt
-// This operation is intended for configuration during start-up. It is not safe for concurrent use.
-// If this operation should be called before any call to SetLabels. If it is called after, those previously labels will be discarded
+// This operation is safe for concurrent use.
+// Any labels previously set with SetLabels are retained and written in the new format
 func SetOutputJSON(v bool) {
-	l := New(defaultLog.outputMask, v)
-	l.Writer = defaultLog.Writer
-	defaultLog = l
+	configure(func(l *Log) {
+		l.outputJSON = v
+		l.commonLabels = renderLabels(l.commonLabels, v)
+	})
 }
 
-// Sets labels to be included all logs written by the default logger
-// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+// Sets the Format of the output of the default logger
+// This operation is safe for concurrent use.
+// Any labels previously set with SetLabels are retained and written in the new format
+func SetOutputFormat(f Format) {
+	SetOutputJSON(f == FormatJSON)
+}
+
+// Sets labels to be included all logs written by the default logger, replacing any previously set
+// This operation is safe for concurrent use.
 func SetLabels(labels ...any) {
-	l := New(defaultLog.outputMask, defaultLog.outputJSON, labels...)
-	l.Writer = defaultLog.Writer
-	defaultLog = l
+	configure(func(l *Log) { l.commonLabels = writeLabels(nil, l.outputJSON, labels) })
+}
+
+// Registers a Hook to be called after each log is written by the default logger
+// This operation is safe for concurrent use.
+func AddHook(h Hook) {
+	configure(func(l *Log) { l.AddHook(h) })
+}
+
+// Registers a Hook to be called after each log with a severity included in the specified OutputMask is written by the default logger
+// This operation is safe for concurrent use.
+func OnSeverity(m OutputMask, h Hook) {
+	configure(func(l *Log) { l.OnSeverity(m, h) })
+}
+
+// Registers a Hook to be called after each log with error severity is written by the default logger
+// This operation is safe for concurrent use.
+func OnError(h Hook) {
+	configure(func(l *Log) { l.OnError(h) })
 }
 
 // Writes a log with fatal severity to the default log and terminates the process
@@ -47,12 +120,12 @@ func SetLabels(labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func Fatal(ctx context.Context, message string, err error, labels ...any) {
-	defaultLog.Fatal(ctx, message, err, labels...)
+	defaultLog.Load().Fatal(ctx, message, err, labels...)
 }
 
 // Writes a log with error severity to the default log
@@ -67,12 +140,12 @@ func Fatal(ctx context.Context, message string, err error, labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func Error(ctx context.Context, message string, err error, labels ...any) {
-	defaultLog.Error(ctx, message, err, labels...)
+	defaultLog.Load().Error(ctx, message, err, labels...)
 }
 
 // Writes a log with warning severity to the default log
@@ -88,17 +161,20 @@ func Error(ctx context.Context, message string, err error, labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func Warning(ctx context.Context, message string, err error, labels ...any) {
-	defaultLog.Warning(ctx, message, err, labels...)
+	defaultLog.Load().Warning(ctx, message, err, labels...)
 }
 
 // Writes a log with notice severity to the default log
 // Notice is reserved for events that are expected but important, such as sytem start-up or shut-down
 //
+// Notice does not accept an error parameter. Where an error is relevant, use NoticeError, so that it is written as the
+// `error` field, as it is by Warning, Error and Fatal, rather than as a label
+//
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
@@ -108,12 +184,17 @@ func Warning(ctx context.Context, message string, err error, labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func Notice(ctx context.Context, message string, labels ...any) {
-	defaultLog.Notice(ctx, message, labels...)
+	defaultLog.Load().Notice(ctx, message, labels...)
+}
+
+// Writes a log with notice severity and the specified error to the default log, see Log.NoticeError
+func NoticeError(ctx context.Context, message string, err error, labels ...any) {
+	defaultLog.Load().NoticeError(ctx, message, err, labels...)
 }
 
 // Writes a log with info severity to the default log
@@ -128,50 +209,50 @@ func Notice(ctx context.Context, message string, labels ...any) {
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
 func Info(ctx context.Context, message string, labels ...any) {
-	defaultLog.Info(ctx, message, labels...)
+	defaultLog.Load().Info(ctx, message, labels...)
 }
 
-// Writes a log with debug severity and a label of trace=true to the default log
-// trace is reserved for emitting data about IO within a process's internal activity, such as the content of requests received or generated
+// Writes a log with audit severity to the default log
+// Audit is reserved for recording security or compliance relevant events, such as changes to permissions or access to sensitive data.
+// Audit logs are always written, regardless of the OutputMask, and are written to the AuditWriter if one is set
 //
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
 // For example:
 //
-//	qlog.Trace(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
-//
-// Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
-// is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+//	qlog.Audit(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
-func Trace(ctx context.Context, message string, labels ...any) {
-	defaultLog.Trace(ctx, message, labels...)
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func Audit(ctx context.Context, message string, labels ...any) {
+	defaultLog.Load().Audit(ctx, message, labels...)
 }
 
-// Writes a log with debug severity to the default log
-// Debug is reserved for emitting low level detail about a process's internal activity, such as config data or current variable states
+// Writes a log with a custom Severity registered with RegisterSeverity to the default log
+// Custom severities allow logs to be categorised beyond the built-in severities, such as for audit or compliance purposes
 //
 // Any number of labels can be provided but they must be given in key, value pairs
 // where each key is a string. Values may be of any type or expressed as a func() T.
 //
 // For example:
 //
-//	qlog.Debug(ctx, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
+//	qlog.Custom(ctx, SeverityAudit, "some helpful information", "key1", "value1", "key2", 2, "key3", func() string { return "lazy_value3" } )
 //
 // Where the value is a `func() T`, the func will not be evaluated unless the output verbosity
 // is such that the log will be written. Use this to prevent needless evaluation
-// of expensive expressions (supports func T where T is string, int, uint, floats and bool).
+// of expensive expressions (supports func T where T is string, int, uint, floats, bool and any).
+//
+// If the Severity has not been registered, the log is not written. Logs with SeverityAudit are written regardless of
+// the OutputMask, as they are by Audit.
 //
 // If the variadic labels argument cannot be be interpretted as balanced key, value pairs, then
-// a `#missing#` value will be silently appended to balance them and provide some opportunity for discovery
-func Debug(ctx context.Context, message string, labels ...any) {
-	defaultLog.Debug(ctx, message, labels...)
+// they are handled according to the UnbalancedLabelPolicy; by default a `#missing#` value is appended to balance them
+func Custom(ctx context.Context, s Severity, message string, labels ...any) {
+	defaultLog.Load().Custom(ctx, s, message, labels...)
 }
@@ -3,17 +3,31 @@ package qlog
 import (
 	"context"
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+// resetDefaultLog replaces the default logger with one as it is at start-up, returning a func that restores the
+// default logger it replaced, so that tests that configure the default logger neither depend on, nor affect, others
+func resetDefaultLog() func() {
+	original := defaultLog.Load()
+	defaultLog.Store(New(OutputMaskAll, true))
+
+	return func() { defaultLog.Store(original) }
+}
+
 func TestOutput(t *testing.T) {
+	defer resetDefaultLog()()
+
 	testError := fmt.Errorf("test error")
 	tcs := []struct {
 		Desc        string
 		Severity    string
-		OutputMask  int
+		OutputMask  OutputMask
 		TargetFunc  func(context.Context, string, ...any)
 		ExpectEmpty bool
 		ExtraLabels []any
@@ -69,6 +83,19 @@ func TestOutput(t *testing.T) {
 			TargetFunc:  Notice,
 			ExpectEmpty: true,
 		},
+		{
+			Desc:        "TestNoticeError",
+			Severity:    "NOTICE",
+			OutputMask:  OutputFlagNotice,
+			TargetFunc:  func(ctx context.Context, s string, a ...any) { NoticeError(ctx, s, testError, a...) },
+			ExtraLabels: []any{"error", `"` + testError.Error() + `"`},
+		},
+		{
+			Desc:        "TestNoticeErrorDisabled",
+			OutputMask:  OutputFlagNone,
+			TargetFunc:  func(ctx context.Context, s string, a ...any) { NoticeError(ctx, s, testError, a...) },
+			ExpectEmpty: true,
+		},
 		{
 			Desc:        "TestTrace",
 			Severity:    "DEBUG",
@@ -137,13 +164,14 @@ func TestOutput(t *testing.T) {
 				"boolfunckey", func() bool { return false },
 				"float32funckey", func() float32 { return 81.3 },
 				"float64funckey", func() float64 { return 6.14 },
+				"errorkey", fmt.Errorf(`label "error"`),
 			}
 
 			tc.TargetFunc(ctx, msg, labels...)
 
 			output := sb.String()
 
-			if tc.ExpectEmpty {
+			if tc.ExpectEmpty || (!DebugEnabled && tc.Severity == "DEBUG") {
 				if output != "" {
 					t.Fatalf("%v: expected empty output but got '%s'", tc.Desc, output)
 				}
@@ -162,6 +190,7 @@ func TestOutput(t *testing.T) {
 			labels[19] = `false`
 			labels[21] = `81.3`
 			labels[23] = `6.14`
+			labels[25] = `"label \"error\""`
 
 			expectedLabels := append(labels, "common", "true", "severity", `"`+tc.Severity+`"`, "trace", `"`+TraceID(ctx)+`"`, "message", `"`+msg+`"`, "timestamp", `"`+expectedTime.UTC().Format(time.RFC3339Nano)+`"`)
 			expectedLabels = append(expectedLabels, tc.ExtraLabels...)
@@ -180,3 +209,194 @@ func TestOutput(t *testing.T) {
 	SetOutputJSON(false)
 	assert(func(k, v any) string { return fmt.Sprintf(`%s=%s`, k, v) })
 }
+
+func TestCommonLabelOverride(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false, "component", "server", "app", "test").WithLabels("region", "eu")
+	l.Writer = &sb
+
+	ctx := ContextFrom(context.Background(), "")
+
+	l.Info(ctx, "overridden", "component", "cache")
+
+	if output := sb.String(); strings.Count(output, "component=") != 1 || !strings.Contains(output, `component="cache"`) || !strings.Contains(output, `app="test" region="eu"`) {
+		t.Fatalf("expected only the overriding component label but got '%s'", output)
+	}
+
+	sb.Reset()
+	l.Info(ctx, "not overridden")
+
+	if output := sb.String(); !strings.Contains(output, `component="server"`) {
+		t.Fatalf("expected common component label but got '%s'", output)
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, true, "app", "test", "component", "server", "component", "api")
+	l.Writer = &sb
+
+	d := l.WithLabels("component", "cache", "region", "eu").WithLabels("region", "us")
+
+	d.Info(ContextFrom(context.Background(), ""), "derived")
+
+	if output := sb.String(); !strings.Contains(output, `, "app": "test", "component": "cache", "region": "us", "message"`) {
+		t.Fatalf("expected json output with deduplicated labels but got '%s'", output)
+	}
+
+	sb.Reset()
+	l.Info(ContextFrom(context.Background(), ""), "parent")
+
+	if output := sb.String(); !strings.Contains(output, `, "app": "test", "component": "api", "message"`) {
+		t.Fatalf("expected parent labels to be unmodified but got '%s'", output)
+	}
+}
+
+func TestWithMaskAndWriter(t *testing.T) {
+	sb, dedicated := strings.Builder{}, strings.Builder{}
+	l := New(OutputMaskImportant, false, "app", "test")
+	l.Writer = &sb
+
+	quiet, routed := l.WithMask(OutputFlagError), l.WithWriter(&dedicated)
+	ctx := ContextFrom(context.Background(), "")
+
+	l.SetOutputMask(OutputMaskAll)
+	quiet.Warning(ctx, "quietened", nil)
+	quiet.Error(ctx, "quiet error", nil)
+	routed.Info(ctx, "routed")
+
+	if output := sb.String(); strings.Contains(output, "quietened") || !strings.Contains(output, ` app="test" message="quiet error"`) {
+		t.Fatalf("expected derived Log to have an independent mask but got '%s'", output)
+	}
+
+	if output := dedicated.String(); !strings.Contains(output, ` app="test" message="routed"`) {
+		t.Fatalf("expected derived Log to write to its writer with the shared mask but got '%s'", output)
+	}
+
+	if output := sb.String(); strings.Contains(output, "routed") {
+		t.Fatalf("expected parent writer to be unmodified but got '%s'", output)
+	}
+}
+
+func TestFormatSwitchRetainsLabels(t *testing.T) {
+	defer resetDefaultLog()()
+
+	sb := strings.Builder{}
+
+	SetOutputJSON(true)
+	SetOutputMask(OutputMaskAll)
+	SetWriter(&sb)
+	SetLabels("app", "test", "port", 8080)
+	SetOutputJSON(false)
+
+	Info(ContextFrom(context.Background(), ""), "switched")
+
+	if output := sb.String(); !strings.Contains(output, ` app="test" port=8080 message="switched"`) {
+		t.Fatalf("expected labels to be written as logfmt but got '%s'", output)
+	}
+
+	if labels := defaultLog.Load().Labels(); len(labels) != 4 || labels[0] != "app" || labels[3] != 8080 {
+		t.Fatalf("expected labels to be retained as key, value pairs but got '%v'", labels)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	sb, audit := strings.Builder{}, strings.Builder{}
+	l := New(OutputFlagNone, false)
+	l.Writer = &sb
+
+	ctx := ContextFrom(context.Background(), "")
+
+	l.Audit(ctx, "audited to writer")
+
+	if output := sb.String(); !strings.Contains(output, `severity="AUDIT"`) {
+		t.Fatalf("expected audit log to be written regardless of mask but got '%s'", output)
+	}
+
+	l.AuditWriter = &audit
+	l.WithLabels("key", "value").Audit(ctx, "audited to audit writer")
+	l.Error(ctx, "not written", nil)
+
+	if output := audit.String(); !strings.Contains(output, `message="audited to audit writer"`) || strings.Count(sb.String(), "\n") != 1 {
+		t.Fatalf("expected audit log to be written to the audit writer but got '%s'", output)
+	}
+}
+
+func TestLogOverrides(t *testing.T) {
+	sb, fatal := strings.Builder{}, false
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+	l.TimestampFormat = time.DateOnly
+	l.TraceIDFieldName = "span"
+	l.TraceID = func(ctx context.Context) string { return "custom-trace" }
+	l.FatalFunc = func() { fatal = true }
+
+	l.WithLabels("derived", true).Fatal(context.Background(), "overridden", nil)
+
+	if output := sb.String(); !strings.HasPrefix(output, `span="custom-trace" severity="FATAL" timestamp="`+timeNow().UTC().Format(time.DateOnly)+`"`) || !fatal {
+		t.Fatalf("expected log specific configuration but got '%s'", output)
+	}
+
+	if TraceIDFieldName != "trace" || TimestampFormat != time.RFC3339Nano {
+		t.Fatalf("expected package level configuration to be unmodified")
+	}
+}
+
+func TestConcurrentConfiguration(t *testing.T) {
+	defer resetDefaultLog()()
+
+	SetWriter(io.Discard)
+
+	wg := sync.WaitGroup{}
+	ctx := ContextFrom(context.Background(), "")
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				Info(ctx, "concurrent", "key", j)
+			}
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				SetOutputMask(OutputMaskAll)
+				SetOutputJSON(j%2 == 0)
+				SetLabels("goroutine", i)
+				SetWriter(io.Discard)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestErrorField(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+
+	l.Error(ContextFrom(context.Background(), ""), "failed", fmt.Errorf(`test "error"`))
+
+	if output := sb.String(); !regexp.MustCompile(`timestamp="[^"]+" error="test \\"error\\"" message="failed"`).MatchString(output) {
+		t.Fatalf("expected escaped error field to follow the timestamp but got '%s'", output)
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	l := New(OutputMaskAll, true, "common", true)
+	l.Writer = io.Discard
+
+	ctx := ContextFrom(context.Background(), "")
+	err := fmt.Errorf("test error")
+	labels := []any{"key1", "value1", "key2", 2, "key3", func() string { return "lazyvalue3" }, "key4", 3.14159, "key5", true}
+
+	if n := testing.AllocsPerRun(100, func() { l.Error(ctx, "test message", err, labels...) }); n != 0 {
+		t.Fatalf("expected no allocations for a log with primitive labels but got %v", n)
+	}
+}
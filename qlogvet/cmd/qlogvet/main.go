@@ -0,0 +1,13 @@
+// Command qlogvet runs the qlogvet analyzer as a go vet tool
+//
+//	go vet -vettool=$(which qlogvet) ./...
+package main
+
+import (
+	"github.com/comradequinn/qlog/qlogvet"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(qlogvet.Analyzer)
+}
@@ -0,0 +1,159 @@
+// Package qlogvet provides an analysis.Analyzer that statically checks calls to qlog.
+//
+// The analyzer reports label lists that cannot be interpretted as balanced key, value pairs,
+// label keys that are not strings and label values that are eagerly evaluated by a call
+// where a lazily evaluated func() T would avoid the cost for logs that are not written.
+//
+// It can be run as part of go vet by building the qlogvet command and passing it as the vet tool:
+//
+//	go install github.com/comradequinn/qlog/qlogvet/cmd/qlogvet@latest
+//	go vet -vettool=$(which qlogvet) ./...
+package qlogvet
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// QlogPath is the import path of the package whose calls are checked
+const QlogPath = "github.com/comradequinn/qlog"
+
+// Analyzer checks the labels passed to qlog funcs and methods
+var Analyzer = &analysis.Analyzer{
+	Name:     "qlogvet",
+	Doc:      "check qlog calls for unbalanced labels, non-string keys and eagerly evaluated label values",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// lazyTypes are the types that qlog can evaluate lazily when expressed as a func() T
+var lazyTypes = map[types.BasicKind]bool{
+	types.String:  true,
+	types.Int:     true,
+	types.Uint:    true,
+	types.Bool:    true,
+	types.Float32: true,
+	types.Float64: true,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		sig, name, ok := qlogCallee(pass, call)
+
+		if !ok || call.Ellipsis.IsValid() { // labels passed as a slice cannot be checked statically
+			return
+		}
+
+		params := sig.Params()
+		first := params.Len() - 1
+
+		if len(call.Args) <= first {
+			return
+		}
+
+		labels := call.Args[first:]
+
+		if len(labels)%2 != 0 {
+			pass.Reportf(labels[len(labels)-1].Pos(), "qlog.%s has an odd number of label arguments; a #missing# value will be appended", name)
+		}
+
+		lazy := params.Len() > 0 && isContext(params.At(0).Type())
+
+		for i := 0; i < len(labels); i += 2 {
+			if t := pass.TypesInfo.TypeOf(labels[i]); t != nil && !isString(t) {
+				pass.Reportf(labels[i].Pos(), "qlog.%s label key should be a string, got %s", name, t)
+			}
+
+			if lazy && i+1 < len(labels) {
+				checkEager(pass, name, labels[i+1])
+			}
+		}
+	})
+
+	return nil, nil
+}
+
+// qlogCallee returns the signature and name of the qlog func or method called, if the call accepts a variadic `labels ...any`
+func qlogCallee(pass *analysis.Pass, call *ast.CallExpr) (*types.Signature, string, bool) {
+	var id *ast.Ident
+
+	switch fn := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return nil, "", false
+	}
+
+	f, ok := pass.TypesInfo.Uses[id].(*types.Func)
+
+	if !ok || f.Pkg() == nil || f.Pkg().Path() != QlogPath {
+		return nil, "", false
+	}
+
+	sig := f.Type().(*types.Signature)
+
+	if !sig.Variadic() || sig.Params().At(sig.Params().Len()-1).Name() != "labels" {
+		return nil, "", false
+	}
+
+	return sig, f.Name(), true
+}
+
+// checkEager reports label values evaluated by a call that could instead be deferred with a func() T
+func checkEager(pass *analysis.Pass, name string, v ast.Expr) {
+	call, ok := ast.Unparen(v).(*ast.CallExpr)
+
+	if !ok {
+		return
+	}
+
+	if tv, ok := pass.TypesInfo.Types[call.Fun]; !ok || tv.IsType() || tv.IsBuiltin() { // conversions and builtins are cheap
+		return
+	}
+
+	t, ok := pass.TypesInfo.TypeOf(call).(*types.Basic)
+
+	if !ok || !lazyTypes[t.Kind()] {
+		return
+	}
+
+	buf := bytes.Buffer{}
+
+	if err := format.Node(&buf, pass.Fset, call); err != nil {
+		return
+	}
+
+	lazy := "func() " + t.Name() + " { return " + buf.String() + " }"
+
+	pass.Report(analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: "qlog." + name + " label value is evaluated even if the log is not written; consider " + lazy,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "Defer evaluation with a func() " + t.Name(),
+			TextEdits: []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(lazy)}},
+		}},
+	})
+}
+
+// isString reports whether t is string itself; a named string type is not a string key once boxed in an any
+func isString(t types.Type) bool {
+	return types.Identical(t, types.Typ[types.String]) || types.Identical(t, types.Typ[types.UntypedString])
+}
+
+func isContext(t types.Type) bool {
+	n, ok := t.(*types.Named)
+
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "context" && n.Obj().Name() == "Context"
+}
@@ -0,0 +1,24 @@
+package a
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/comradequinn/qlog"
+)
+
+type key string
+
+func calls(ctx context.Context, s string, labels []any) {
+	qlog.Info(ctx, "balanced", "key1", "value1", "key2", 2)
+	qlog.Info(ctx, "unbalanced", "key1", "value1", "key2") // want `qlog.Info has an odd number of label arguments`
+	qlog.Info(ctx, "spread", labels...)
+	qlog.Error(ctx, "non-string key", nil, 1, "value1")       // want `qlog.Error label key should be a string, got int`
+	qlog.Info(ctx, "named string key", key("key1"), "value1") // want `qlog.Info label key should be a string, got a.key`
+	qlog.Info(ctx, "eager", "key1", strings.ToUpper(s))       // want `qlog.Info label value is evaluated even if the log is not written; consider func\(\) string { return strings.ToUpper\(s\) }`
+	qlog.Info(ctx, "lazy", "key1", func() string { return strings.ToUpper(s) })
+	qlog.Info(ctx, "cheap", "key1", len(s), "key2", string(key("value2")))
+	qlog.Info(ctx, "unsupported lazy type", "key1", fmt.Errorf("err"))
+	qlog.New(0, false, "key1", strings.ToUpper(s)).Info(ctx, "odd", "key1") // want `qlog.Info has an odd number of label arguments`
+}
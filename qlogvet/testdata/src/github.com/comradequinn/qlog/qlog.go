@@ -0,0 +1,13 @@
+package qlog
+
+import "context"
+
+type Log struct{}
+
+func New(outputMask int, outputJSON bool, labels ...any) *Log { return &Log{} }
+
+func (l *Log) Info(ctx context.Context, message string, labels ...any) {}
+
+func Info(ctx context.Context, message string, labels ...any) {}
+
+func Error(ctx context.Context, message string, err error, labels ...any) {}
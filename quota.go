@@ -0,0 +1,76 @@
+package qlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QuotaExceededLabel is the key of the label written on the log that reports that a trace has exceeded its quota
+const QuotaExceededLabel = "quota_exceeded"
+
+// traceQuotaRetention is the interval after which the count of a trace that has written no logs is discarded
+const traceQuotaRetention = time.Minute
+
+var (
+	quota         = atomic.Int64{} // the maximum logs of each trace, or zero if there is no maximum
+	quotaMx       = sync.Mutex{}
+	quotaCounts   = map[string]int64{}
+	quotaPrevious = map[string]int64{} // the counts of the previous retention interval, retained while their traces write logs
+	quotaRotated  time.Time
+)
+
+// SetTraceQuota sets the maximum number of logs that may be written for each trace, such that a pathological request,
+// such as one that logs in a tight loop, cannot starve the logs of others. Once a trace exceeds its quota, a warning
+// with a `quota_exceeded` label is written, and its subsequent logs are dropped. Fatal and Audit logs are never dropped.
+//
+// The count of each trace is retained until it has written no logs for a minute. Pass zero to disable the quota.
+// This operation is safe for concurrent use.
+func SetTraceQuota(perTrace int) {
+	quotaMx.Lock()
+	quotaCounts, quotaPrevious, quotaRotated = map[string]int64{}, map[string]int64{}, timeNow()
+	quotaMx.Unlock()
+
+	quota.Store(int64(perTrace))
+}
+
+// withinQuota reports whether the trace of the log is within its quota, writing a warning if it has newly exceeded it
+func (l *Log) withinQuota(ctx context.Context, flag OutputMask) bool {
+	max := quota.Load()
+
+	if max <= 0 || flag&(OutputFlagFatal|OutputFlagAudit) != 0 || exempt(ctx) {
+		return true
+	}
+
+	id := l.traceID(ctx)
+
+	if id == "" {
+		return true
+	}
+
+	quotaMx.Lock()
+
+	if now := timeNow(); now.Sub(quotaRotated) >= traceQuotaRetention {
+		quotaCounts, quotaPrevious, quotaRotated = map[string]int64{}, quotaCounts, now
+	}
+
+	n, ok := quotaCounts[id]
+
+	if !ok {
+		n = quotaPrevious[id]
+		delete(quotaPrevious, id)
+	}
+
+	n++
+	quotaCounts[id] = n
+
+	quotaMx.Unlock()
+
+	if n == max+1 && l.writes(ctx, OutputFlagWarning) {
+		// the warning is the last log of the trace, so it is exempted from the quota
+		l.log(context.WithValue(ctx, budgetExemptKey, true), OutputFlagWarning, "WARNING", "trace log quota exceeded", nil, QuotaExceededLabel, true, "trace_quota", int(max))
+	}
+
+	return n <= max
+}
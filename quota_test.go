@@ -0,0 +1,45 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceQuota(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	defer func() {
+		SetTraceQuota(0)
+		timeNow = time.Now
+	}()
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	pathological, other := ContextFrom(context.Background(), ""), ContextFrom(context.Background(), "")
+
+	SetTraceQuota(3)
+
+	for i := 0; i < 10; i++ {
+		l.Info(pathological, "looping")
+	}
+
+	l.Audit(pathological, "audited")
+	l.Info(other, "other")
+
+	if output := sb.String(); strings.Count(output, "looping") != 3 || strings.Count(output, QuotaExceededLabel+"=true trace_quota=3") != 1 || !strings.Contains(output, "audited") || !strings.Contains(output, "other") {
+		t.Fatalf("expected the logs of the trace to be dropped once it exceeded its quota but got '%v'", output)
+	}
+
+	sb.Reset()
+	now = now.Add(traceQuotaRetention)
+	l.Info(pathological, "looping")
+	now = now.Add(traceQuotaRetention)
+	l.Info(other, "other")
+
+	if output := sb.String(); strings.Contains(output, "looping") || !strings.Contains(output, "other") {
+		t.Fatalf("expected the count of a trace to be retained while it writes logs but got '%v'", output)
+	}
+}
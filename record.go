@@ -0,0 +1,307 @@
+package qlog
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Record describes a log that is to be written, before it is encoded. It is passed to any DropFilters registered on
+	// the Log writing it, and to its Encoder
+	Record struct {
+		// Context is the context.Context passed to the log method
+		Context  context.Context
+		Time     time.Time
+		Severity string
+		// Flag is the OutputFlag of the severity of the log
+		Flag         OutputMask
+		TraceID      string
+		SpanID       string
+		ParentSpanID string
+		Message      string
+		Error        error
+		// Labels are the key, value pairs passed to the log method, preceded by any carried by its context. Any values
+		// expressed as a func() T have been evaluated. The labels common to all logs of the Log are not included
+		Labels []any
+		// Logger is the name of the Log writing the log, if it was retrieved with Get
+		Logger    string
+		ctxLabels []any // the labels of the context, until they are merged into Labels
+		log       *Log  // the Log writing the log, whose labels are included by AllLabels
+		prepared  bool  // whether the values of Labels have been offloaded and guarded by the Log
+		completed bool  // whether the Trace-ID and the labels of the extractors of the Log have been set
+	}
+	// DropFilter is a func that reports whether a Record should be dropped, rather than written
+	DropFilter func(Record) bool
+	// Filter is a func that reports whether a Record should be written. Unlike a DropFilter, it is passed the Record
+	// before its labels are evaluated, so its Labels are only those passed to the log method, and any value may be a
+	// func() T; Label also returns those carried by its context. Its TraceID is that of its context, rather than of any
+	// TraceID func of the Log, and the labels of any extractors are not included
+	Filter func(Record) bool
+)
+
+// AddFilter registers a Filter with the Log. Each log is passed to the filters, in the order they were registered, before
+// any DropFilters, and is vetoed should any report that it should not be written. As the labels of the log are not
+// evaluated first, a vetoed log costs little more than one not enabled by the OutputMask, so filters suit high-volume
+// logs that are vetoed by their severity, message or eagerly evaluated labels. Logs derived from the Log inherit its
+// filters.
+//
+// For example:
+//
+//	logger.AddFilter(func(r qlog.Record) bool { return r.Flag != qlog.OutputFlagDebug || r.Label("tenant") == "acme" })
+//
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddFilter(f Filter) {
+	l.filters = append(l.filters[:len(l.filters):len(l.filters)], f) // never share an appended element with a derived Log
+}
+
+// Registers a Filter with the default logger, see Log.AddFilter.
+// This operation is safe for concurrent use.
+func AddFilter(f Filter) {
+	configure(func(l *Log) { l.AddFilter(f) })
+}
+
+// AddDropFilter registers a DropFilter with the Log. Each log is passed to the filters, in the order they were
+// registered, before it is encoded, and is dropped, at little cost, should any report that it should be. This allows logs
+// to be discarded by their content, rather than only their severity, such as the access logs of health checks.
+// Logs derived from the Log inherit its filters.
+//
+// For example:
+//
+//	logger.AddDropFilter(func(r qlog.Record) bool { return r.Label("path") == "/metrics" })
+//
+// Where any filters are registered, the labels of each log are evaluated before they are passed to them, regardless
+// of whether it is then written. A Filter, which is passed the log before they are evaluated, avoids this cost.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddDropFilter(f DropFilter) {
+	l.dropFilters = append(l.dropFilters[:len(l.dropFilters):len(l.dropFilters)], f) // never share an appended element with a derived Log
+}
+
+// Registers a DropFilter with the default logger, see Log.AddDropFilter.
+// This operation is safe for concurrent use.
+func AddDropFilter(f DropFilter) {
+	configure(func(l *Log) { l.AddDropFilter(f) })
+}
+
+// AddBeforeWrite registers a func to be called with the Record of each log before it is encoded, in the order they were
+// registered, such that it can enrich or modify it, for example by adding labels or rewriting its message. The Labels
+// of the Record are a copy, with lazy values evaluated, so can be modified or appended to. Logs derived from the Log
+// inherit its funcs.
+//
+// For example:
+//
+//	logger.AddBeforeWrite(func(r *qlog.Record) { r.Labels = append(r.Labels, "region", region()) })
+//
+// The funcs are called synchronously by the goroutine writing the log, so should be quick to return. Modifying the
+// Flag of the Record does not change where the log is written.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddBeforeWrite(f func(*Record)) {
+	l.beforeWrite = append(l.beforeWrite[:len(l.beforeWrite):len(l.beforeWrite)], f) // never share an appended element with a derived Log
+}
+
+// Registers a func to be called with the Record of each log of the default logger before it is encoded, see
+// Log.AddBeforeWrite.
+// This operation is safe for concurrent use.
+func AddBeforeWrite(f func(*Record)) {
+	configure(func(l *Log) { l.AddBeforeWrite(f) })
+}
+
+// AddAfterWrite registers a func to be called with the Record of each log once it is written, in the order they were
+// registered, along with the number of bytes written and the error, if any, of writing it. This allows metrics to be
+// recorded, alerts raised and failed writes accounted for, without wrapping the Writer. Logs derived from the Log inherit
+// its funcs.
+//
+// For example:
+//
+//	logger.AddAfterWrite(func(r qlog.Record, n int, err error) { bytesWritten.WithLabelValues(r.Severity).Add(float64(n)) })
+//
+// The funcs are called synchronously by the goroutine writing the log, after any hooks, so should be quick to return.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) AddAfterWrite(f func(r Record, n int, err error)) {
+	l.afterWrite = append(l.afterWrite[:len(l.afterWrite):len(l.afterWrite)], f) // never share an appended element with a derived Log
+}
+
+// Registers a func to be called with the Record of each log of the default logger once it is written, see
+// Log.AddAfterWrite.
+// This operation is safe for concurrent use.
+func AddAfterWrite(f func(r Record, n int, err error)) {
+	configure(func(l *Log) { l.AddAfterWrite(f) })
+}
+
+// Label returns the value of the label of the Record with the specified key, or nil if it has none. Where the key occurs
+// more than once, the last value is returned, as it is that which takes precedence
+func (r Record) Label(key string) any {
+	for _, labels := range [2][]any{r.Labels, r.ctxLabels} { // the labels of the context are overridden by Labels
+		for i := len(labels) - 2; i >= 0; i -= 2 {
+			if k, _ := labels[i].(string); k == key {
+				return labels[i+1]
+			}
+		}
+	}
+
+	return nil
+}
+
+// AllLabels returns the labels of the Log writing the log, other than those overridden by the Record's Labels,
+// followed by the Record's Labels. These are all the labels written with the log, such as are required by an Encoder
+func (r Record) AllLabels() []any {
+	labels := []any{}
+
+	if r.log != nil {
+		for _, cl := range r.log.commonLabels {
+			if !overridden(cl.key, r.Labels) && !overridden(cl.key, r.ctxLabels) {
+				labels = append(labels, cl.key, resolve(cl.value))
+			}
+		}
+	}
+
+	return append(labels, mergeLabels(r.ctxLabels, r.Labels)...)
+}
+
+// record returns the Record of a log. The labels of its context are held apart from its Labels, and its lazy values
+// are not evaluated, until it is merged
+func (l *Log) record(ctx context.Context, flag OutputMask, severity, message string, err error, labels []any) Record {
+	r := l.draft(ctx, flag, severity, message, err, labels)
+	l.complete(&r)
+
+	return r
+}
+
+// draft returns the Record of a log as it is passed to Filters; with the Trace-ID of its context, rather than that of
+// any TraceID func of the Log, and without the labels of its extractors, as neither is run until the log passes them
+func (l *Log) draft(ctx context.Context, flag OutputMask, severity, message string, err error, labels []any) Record {
+	r := Record{Context: ctx, Time: l.now(), Severity: severity, Flag: flag, Message: message, Error: err, Labels: balance(labels), Logger: l.name, log: l}
+
+	if l.bound != nil {
+		r.TraceID, r.SpanID, r.ParentSpanID = l.bound.traceID, l.bound.spanID, l.bound.parentSpanID
+	} else {
+		r.TraceID, r.SpanID, r.ParentSpanID = TraceID(ctx), SpanID(ctx), ParentSpanID(ctx)
+	}
+
+	r.ctxLabels = contextLabels(ctx)
+
+	return r
+}
+
+// complete sets the Trace-ID of a draft Record with any TraceID func of the Log, and adds the labels of its extractors,
+// unless it is already complete
+func (l *Log) complete(r *Record) {
+	if r.completed {
+		return
+	}
+
+	if l.bound == nil && l.TraceID != nil {
+		r.TraceID = l.TraceID(r.Context)
+	}
+
+	if len(l.extractors) > 0 {
+		r.ctxLabels = l.extract(r.Context, r.TraceID, r.ctxLabels)
+	}
+
+	r.completed = true
+}
+
+// merge merges the labels of the context of r into its Labels, evaluating any lazy values, on a copy so as not to
+// modify the caller's slice
+func (r *Record) merge() {
+	r.Labels, r.ctxLabels = mergeLabels(r.ctxLabels, r.Labels), nil
+}
+
+// prepare returns a copy of r, which must be merged, with the values of its Labels offloaded and guarded by the Log, and
+// any labels with keys that cannot be written removed
+func (l *Log) prepare(r Record) Record {
+	labels := make([]any, 0, len(r.Labels))
+
+	for i := 0; i+1 < len(r.Labels); i += 2 {
+		if key, ok := labelKey(r.Labels[i]); ok {
+			labels = append(labels, key, l.prepareLabel(r.Context, key, r.Labels[i+1]))
+		}
+	}
+
+	r.Labels, r.prepared = labels, true
+
+	return r
+}
+
+// prepareLabel returns the value of a label, offloaded to the BlobOffload of the Log, and guarded against cardinality,
+// as required
+func (l *Log) prepareLabel(ctx context.Context, key string, value any) any {
+	if l.BlobOffload != nil {
+		value = l.BlobOffload.offload(ctx, key, value)
+	}
+
+	return l.guardCardinality(ctx, key, value)
+}
+
+// beforeWriting returns r once modified by the BeforeWrite funcs of the Log. It is passed by value so that r only escapes
+// to the heap where there are funcs
+func (l *Log) beforeWriting(r Record) Record {
+	for _, f := range l.beforeWrite {
+		f(&r)
+	}
+
+	return r
+}
+
+// entry returns the Entry that describes r once it is written
+func (r Record) entry() Entry {
+	return Entry{Context: r.Context, Time: r.Time, Severity: r.Severity, TraceID: r.TraceID, SpanID: r.SpanID, ParentSpanID: r.ParentSpanID,
+		Message: r.Message, Error: r.Error, Labels: r.Labels, Flag: r.Flag, Logger: r.Logger, log: r.log}
+}
+
+// record returns the Record of the log described by e
+func (e Entry) record() Record {
+	return Record{Context: e.Context, Time: e.Time, Severity: e.Severity, Flag: e.Flag, TraceID: e.TraceID, SpanID: e.SpanID, ParentSpanID: e.ParentSpanID,
+		Message: e.Message, Error: e.Error, Labels: e.Labels, Logger: e.Logger, log: e.log}
+}
+
+// filter reports whether a log passes the Filters, then the DropFilters, of the Log, along with its Record to be encoded.
+// Exempt logs pass regardless. The Record is only completed, and merged, where there are DropFilters, so that extractors are run, and lazy values
+// evaluated, once at most
+func (l *Log) filter(ctx context.Context, flag OutputMask, severity, message string, err error, labels []any) (Record, bool) {
+	r := l.draft(ctx, flag, severity, message, err, labels)
+
+	if exempt(ctx) {
+		return r, true
+	}
+
+	for _, f := range l.filters {
+		if !f(r) {
+			return r, false
+		}
+	}
+
+	if len(l.dropFilters) == 0 {
+		return r, true
+	}
+
+	l.complete(&r)
+	r.merge()
+
+	for _, f := range l.dropFilters {
+		if f(r) {
+			return r, false
+		}
+	}
+
+	return r, true
+}
+
+// mergeLabels returns a copy of the labels of a context, other than those overridden by labels, followed by labels,
+// with any lazy values evaluated. The passed slices are not modified
+func mergeLabels(ctxLabels, labels []any) []any {
+	all := make([]any, 0, len(ctxLabels)+len(labels))
+
+	for i := 0; i+1 < len(ctxLabels); i += 2 {
+		if key, _ := ctxLabels[i].(string); !overridden(key, labels) {
+			all = append(all, ctxLabels[i], ctxLabels[i+1])
+		}
+	}
+
+	all = append(all, labels...)
+
+	for i := 1; i < len(all); i += 2 {
+		all[i] = resolve(all[i])
+	}
+
+	return all
+}
@@ -0,0 +1,150 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDropFilter(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false, "service", "api").WithWriter(&sb)
+	records := []Record{}
+	evaluated := 0
+
+	l.AddDropFilter(func(r Record) bool {
+		records = append(records, r)
+		return r.Label("path") == "/metrics"
+	})
+
+	ctx := ContextWithLabels(ContextFrom(context.Background(), "abc"), "path", "/orders")
+
+	l.Info(ctx, "request", "status", func() int { evaluated++; return 200 })
+	l.Info(ctx, "request", "path", "/metrics")
+	l.Info(ContextWithLabels(context.Background(), "path", "/metrics"), "request")
+
+	output := sb.String()
+
+	if strings.Count(output, "request") != 1 || !strings.Contains(output, `path="/orders" status=200`) {
+		t.Fatalf("expected only the log without a path of /metrics to be written but got '%v'", output)
+	}
+
+	if evaluated != 1 {
+		t.Fatalf("expected lazy labels to be evaluated once but got %v evaluations", evaluated)
+	}
+
+	if len(records) != 3 || records[0].TraceID != "abc" || records[0].Severity != "INFO" || records[0].Label("status") != 200 || records[0].Label("service") != nil {
+		t.Fatalf("expected the filter to receive a record of each log but got '%v'", records)
+	}
+
+	sb.Reset()
+	derived := l.WithLabels("component", "health")
+	derived.AddDropFilter(func(r Record) bool { return r.Message == "ping" })
+	derived.Info(context.Background(), "ping")
+	l.Info(context.Background(), "ping")
+
+	if output := sb.String(); strings.Count(output, "ping") != 1 || strings.Contains(output, "health") {
+		t.Fatalf("expected a filter added to a derived log to apply only to it but got '%v'", output)
+	}
+}
+
+func TestBeforeWrite(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	entries := []Entry{}
+
+	l.AddHook(func(e Entry) { entries = append(entries, e) })
+	l.AddBeforeWrite(func(r *Record) { r.Labels = append(r.Labels, "region", "eu-west-1") })
+	l.AddBeforeWrite(func(r *Record) { r.Message = strings.ReplaceAll(r.Message, "secret", "***") })
+
+	labels := []any{"user", func() string { return "u1" }}
+	l.Info(context.Background(), "login with secret", labels...)
+
+	if output := sb.String(); !strings.Contains(output, `user="u1" region="eu-west-1" message="login with ***"`) {
+		t.Fatalf("expected the record to be modified before it was encoded but got '%v'", output)
+	}
+
+	if len(entries) != 1 || entries[0].Message != "login with ***" || len(entries[0].Labels) != 4 {
+		t.Fatalf("expected hooks to receive the modified record but got '%v'", entries)
+	}
+
+	if _, ok := labels[1].(func() string); !ok {
+		t.Fatalf("expected the labels passed to the log not to be modified but got '%v'", labels)
+	}
+}
+
+func TestAfterWrite(t *testing.T) {
+	type write struct {
+		message string
+		n       int
+		err     error
+	}
+
+	fw := &flakyWriter{failures: 1, err: errors.New("disk full")}
+	l := New(OutputMaskAll, false).WithWriter(fw)
+	writes := []write{}
+
+	l.AddAfterWrite(func(r Record, n int, err error) { writes = append(writes, write{r.Message, n, err}) })
+
+	l.Info(context.Background(), "first", "lazy", func() int { return 1 })
+	l.Info(context.Background(), "second")
+
+	bt := l.Batch(context.Background())
+	bt.Info("third")
+	bt.Info("fourth")
+	bt.Write()
+
+	lines := strings.SplitAfter(fw.String(), "\n")
+
+	if len(writes) != 4 || writes[0].n != 1 || writes[0].err == nil || writes[1].n != len(lines[0])-1 || writes[1].err != nil {
+		t.Fatalf("expected each write to be reported with its bytes written and error but got '%v'", writes)
+	}
+
+	if writes[2].message != "third" || writes[2].n != len(lines[1]) || writes[3].message != "fourth" || writes[3].n != len(lines[2]) {
+		t.Fatalf("expected each log of a batch to be reported with its bytes written but got '%v'", writes)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	evaluated, dropFiltered := 0, 0
+
+	l.AddFilter(func(r Record) bool { return r.Flag != OutputFlagDebug || r.Label("tenant") == "acme" })
+	l.AddDropFilter(func(r Record) bool { dropFiltered++; return false })
+
+	lazy := func() string { evaluated++; return "v" }
+
+	l.Debug(ContextWithLabels(context.Background(), "tenant", "other"), "vetoed", "lazy", lazy)
+	l.Debug(ContextWithLabels(context.Background(), "tenant", "other"), "written", "tenant", "acme", "lazy", lazy)
+	l.Info(context.Background(), "written", "lazy", lazy)
+
+	if output := sb.String(); strings.Contains(output, "vetoed") || strings.Count(output, "written") != 2 {
+		t.Fatalf("expected only the debug log of the other tenant to be vetoed but got '%v'", output)
+	}
+
+	if evaluated != 2 || dropFiltered != 2 {
+		t.Fatalf("expected the vetoed log not to be evaluated or passed to drop filters but got %v evaluations and %v drop filters", evaluated, dropFiltered)
+	}
+}
+
+func TestFilterExtractors(t *testing.T) {
+	sb := strings.Builder{}
+	extracted, traced := 0, 0
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	l.TraceID = func(ctx context.Context) string { traced++; return "custom" }
+	l.AddExtractor(func(ctx context.Context) []any { extracted++; return []any{"extracted", true} })
+	l.AddFilter(func(r Record) bool { return r.Message != "vetoed" })
+
+	l.Info(context.Background(), "vetoed")
+	l.Info(context.Background(), "written")
+
+	if output := sb.String(); extracted != 1 || traced != 1 || !strings.HasPrefix(output, `trace="custom"`) || !strings.Contains(output, `extracted=true message="written"`) {
+		t.Fatalf("expected extractors and the Trace-ID func to be run once, for the written log, but got %v and %v calls: '%v'", extracted, traced, output)
+	}
+}
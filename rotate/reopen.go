@@ -0,0 +1,131 @@
+package rotate
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/comradequinn/qlog"
+)
+
+// ReopenWriter is an io.Writer that writes to a file and reopens its path on request, such as when signalled by an
+// external tool like logrotate. This supports the classic "rotate and HUP" workflow, where the file is renamed
+// externally and the process signalled to start a new file at the original path; logs are written to the renamed
+// file until it is reopened, so none are lost or misdirected.
+type ReopenWriter struct {
+	filename string
+	mx       sync.Mutex
+	file     *os.File
+}
+
+// NewReopenWriter creates a ReopenWriter that writes to the file at filename, creating it if required
+func NewReopenWriter(filename string) (*ReopenWriter, error) {
+	f, err := openAppend(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReopenWriter{filename: filename, file: f}, nil
+}
+
+// Name returns the path of the file
+func (w *ReopenWriter) Name() string {
+	return w.filename
+}
+
+// Write writes b to the current file
+func (w *ReopenWriter) Write(b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	return w.file.Write(b)
+}
+
+// Reopen opens the path of the file, creating it if required, and closes the previously opened file. Should the
+// path fail to open, writes continue to the previously opened file
+func (w *ReopenWriter) Reopen() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	f, err := openAppend(w.filename)
+
+	if err != nil {
+		return err
+	}
+
+	previous := w.file
+	w.file = f
+
+	return previous.Close()
+}
+
+// Sync commits the current file to stable storage, allowing the ReopenWriter to be used with a qlog.SyncWriter
+func (w *ReopenWriter) Sync() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	return w.file.Sync()
+}
+
+// Close closes the current file. Subsequent writes fail with os.ErrClosed
+func (w *ReopenWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+// ReopenOnSignal reopens the file each time the signal is received. An error is logged, with the default qlog logger,
+// should it fail to reopen. The returned func stops handling the signal.
+func (w *ReopenWriter) ReopenOnSignal(sig os.Signal) func() {
+	signals, done := make(chan os.Signal, 1), make(chan struct{})
+	signal.Notify(signals, sig)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				if err := w.Reopen(); err != nil {
+					qlog.Error(context.Background(), "log file reopen failed", err, "file", w.filename)
+				}
+			}
+		}
+	}()
+
+	once := sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// openAppend opens the file at filename for appending, creating it if required
+func openAppend(filename string) (*os.File, error) {
+	return os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package rotate
+
+// ReopenOnSIGHUP has no effect on platforms without SIGHUP, use ReopenOnSignal or Reopen instead.
+// The returned func is a no-op.
+func (w *ReopenWriter) ReopenOnSIGHUP() func() {
+	return func() {}
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package rotate
+
+import "syscall"
+
+// ReopenOnSIGHUP reopens the file each time SIGHUP is received, as is conventional for logrotate's postrotate script.
+// The returned func stops handling the signal.
+func (w *ReopenWriter) ReopenOnSIGHUP() func() {
+	return w.ReopenOnSignal(syscall.SIGHUP)
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	filename, rotated := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1")
+
+	w, err := NewReopenWriter(filename)
+
+	if err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	defer w.Close()
+
+	stop := w.ReopenOnSIGHUP()
+	defer stop()
+
+	w.Write([]byte("log1\n"))
+	os.Rename(filename, rotated) // as logrotate would
+	w.Write([]byte("log2\n"))
+
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(filename); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w.Write([]byte("log3\n"))
+
+	for name, expected := range map[string]string{rotated: "log1\nlog2\n", filename: "log3\n"} {
+		if b, _ := os.ReadFile(name); string(b) != expected {
+			t.Fatalf("expected %v to contain '%v' but got '%v'", filepath.Base(name), expected, string(b))
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Package rotate provides a rotating file writer for use as the Writer of a qlog.Log, such as via qlog.SetWriter.
+//
+// The current file is rotated when writing a log would exceed its maximum size or, optionally, at a fixed interval.
+// Rotated files are renamed with the time of their rotation, such as `app-2024-01-02T15-04-05.000.log`, optionally
+// compressed with gzip, and removed once they exceed the maximum number, age or total size of backups. For example:
+//
+//	w := rotate.New("/var/log/app/app.log")
+//	w.MaxBackups, w.Compress = 5, true
+//	defer w.Close()
+//
+//	qlog.SetWriter(w)
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the format of the rotation time in the names of backups, it sorts lexically and is valid in file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+var timeNow = time.Now
+
+// Writer is an io.Writer that writes to a file, rotating it based on its size and age.
+// The fields configure the rotation and should be set before the first write.
+type Writer struct {
+	// Filename is the path of the current file. Backups are written to the same directory
+	Filename string
+	// MaxSize is the size, in bytes, beyond which the file is rotated. A log larger than MaxSize is written to an empty file
+	MaxSize int64
+	// Interval, if greater than zero, rotates the file at each multiple of the Interval since the unix epoch, such as
+	// hourly or daily in UTC
+	Interval time.Duration
+	// MaxBackups, if greater than zero, is the maximum number of backups retained; the oldest are removed first
+	MaxBackups int
+	// MaxAge, if greater than zero, is the maximum age of retained backups, based on the time of their rotation
+	MaxAge time.Duration
+	// MaxTotalSize, if greater than zero, is the maximum total size, in bytes, of retained backups; the oldest are removed first
+	MaxTotalSize int64
+	// Compress determines whether backups are compressed with gzip
+	Compress bool
+	// CompressAfter, if greater than zero, delays the compression of backups until they are older than it, so that
+	// recent backups remain readable with standard tools
+	CompressAfter time.Duration
+	// JanitorInterval, if greater than zero, enforces the retention of backups at that interval, in addition to on
+	// each rotation, so that backups are removed once they exceed MaxAge even when the file is rarely rotated
+	JanitorInterval time.Duration
+
+	mx       sync.Mutex
+	file     *os.File
+	size     int64
+	opened   time.Time
+	millWg   sync.WaitGroup
+	milled   chan struct{} // signals the background goroutine to enforce retention following a rotation
+	rotated  time.Time     // the time of the most recent rotation
+	closed   bool
+	stop     chan struct{} // closed to stop the background goroutine, nil if it has not been started
+	millErrs []error
+}
+
+// New creates a Writer that writes to the file at filename, rotating it once it reaches 100MiB.
+// No backups are removed until MaxBackups or MaxAge are set.
+func New(filename string) *Writer {
+	return &Writer{Filename: filename, MaxSize: 100 << 20}
+}
+
+// Write writes b to the current file, rotating it first if required
+func (w *Writer) Write(b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.size > 0 && (w.size+int64(len(b)) > w.MaxSize || w.due()) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(b)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Rotate rotates the current file, regardless of its size or age
+func (w *Writer) Rotate() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	if w.file == nil {
+		return w.open()
+	}
+
+	return w.rotate()
+}
+
+// Sync commits the current file to stable storage, allowing the Writer to be used with a qlog.SyncWriter
+func (w *Writer) Sync() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Sync()
+}
+
+// Close closes the current file and waits for the compression and removal of any backups to complete.
+// Any error compressing or removing backups since the Writer was created is returned.
+func (w *Writer) Close() error {
+	w.mx.Lock()
+
+	var err error
+
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+
+	w.closed = true
+
+	if w.stop != nil {
+		close(w.stop)
+	}
+
+	w.mx.Unlock()
+
+	w.millWg.Wait()
+
+	return errors.Join(append([]error{err}, w.millErrs...)...)
+}
+
+// due reports whether the file has reached the end of its interval, the caller must hold mx
+func (w *Writer) due() bool {
+	return w.Interval > 0 && !timeNow().Truncate(w.Interval).Equal(w.opened.Truncate(w.Interval))
+}
+
+// open opens the current file for appending, creating it and its directory if required, the caller must hold mx
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Filename), 0o755); err != nil {
+		return err
+	}
+
+	f, err := openAppend(w.Filename)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file, w.size, w.opened = f, info.Size(), info.ModTime()
+
+	if w.stop == nil {
+		w.stop, w.milled = make(chan struct{}), make(chan struct{}, 1)
+		w.millWg.Add(1)
+
+		go w.run()
+	}
+
+	if w.size == 0 {
+		w.opened = timeNow()
+	}
+
+	return nil
+}
+
+// rotate renames the current file as a backup and opens a new one, the caller must hold mx
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	w.file = nil
+
+	now := timeNow()
+
+	if err := os.Rename(w.Filename, w.backupName(now)); err != nil {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.rotated = now
+
+	select { // a pending signal will enforce retention as of this rotation
+	case w.milled <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// backupName returns the name of a backup of the current file rotated at t
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.Filename)
+
+	return strings.TrimSuffix(w.Filename, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backup is a rotated file
+type backup struct {
+	path    string
+	rotated time.Time
+	size    int64
+}
+
+// backups returns the backups of the current file, newest first
+func (w *Writer) backups() ([]backup, error) {
+	ext := filepath.Ext(w.Filename)
+	prefix := filepath.Base(strings.TrimSuffix(w.Filename, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(w.Filename))
+
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []backup{}
+
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".gz")
+
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		rotated, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+
+		if err != nil { // not a backup, but a file with a similar name
+			continue
+		}
+
+		info, err := e.Info()
+
+		if err != nil { // removed since the directory was read
+			continue
+		}
+
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(w.Filename), e.Name()), rotated: rotated, size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotated.After(backups[j].rotated) })
+
+	return backups, nil
+}
+
+// run enforces the retention of backups following each rotation and, if set, at each JanitorInterval, until stop is
+// closed. Retention is enforced by a single goroutine so that backups are never compressed or removed concurrently
+func (w *Writer) run() {
+	defer w.millWg.Done()
+
+	var tick <-chan time.Time
+
+	if w.JanitorInterval > 0 {
+		ticker := time.NewTicker(w.JanitorInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			select { // enforce retention following a final rotation
+			case <-w.milled:
+				w.mill(w.lastRotated())
+			default:
+			}
+
+			return
+		case <-w.milled:
+			w.mill(w.lastRotated())
+		case <-tick:
+			w.mill(timeNow())
+		}
+	}
+}
+
+func (w *Writer) lastRotated() time.Time {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.rotated
+}
+
+// mill removes the backups beyond MaxBackups or MaxTotalSize, or older than MaxAge at now, and compresses those
+// remaining, if required
+func (w *Writer) mill(now time.Time) {
+	backups, err := w.backups()
+
+	if err != nil {
+		w.millErrs = append(w.millErrs, err)
+		return
+	}
+
+	total := int64(0)
+
+	for i, b := range backups {
+		if (w.MaxBackups > 0 && i >= w.MaxBackups) || (w.MaxAge > 0 && now.Sub(b.rotated) > w.MaxAge) {
+			w.remove(b.path)
+			continue
+		}
+
+		if w.Compress && !strings.HasSuffix(b.path, ".gz") && now.Sub(b.rotated) >= w.CompressAfter {
+			size, err := compress(b.path)
+
+			if err != nil {
+				w.millErrs = append(w.millErrs, err)
+			} else {
+				b.path, b.size = b.path+".gz", size
+			}
+		}
+
+		if total += b.size; w.MaxTotalSize > 0 && total > w.MaxTotalSize {
+			w.remove(b.path)
+		}
+	}
+}
+
+func (w *Writer) remove(path string) {
+	if err := os.Remove(path); err != nil {
+		w.millErrs = append(w.millErrs, err)
+	}
+}
+
+// compress replaces the file at path with a gzip compressed copy, returning its size
+func compress(path string) (int64, error) {
+	src, err := os.Open(path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+
+	if err != nil {
+		return 0, err
+	}
+
+	gz := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+
+		return 0, err
+	}
+
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+
+		return 0, err
+	}
+
+	info, err := dst.Stat()
+
+	if err = errors.Join(err, dst.Close()); err != nil {
+		os.Remove(path + ".gz")
+		return 0, err
+	}
+
+	return info.Size(), os.Remove(path)
+}
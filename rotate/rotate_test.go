@@ -0,0 +1,162 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tick freezes the time used by the Writer, returning a func that advances it
+func tick(t *testing.T) func(d time.Duration) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func files(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		t.Fatalf("expected no error reading the directory but got '%v'", err)
+	}
+
+	names := []string{}
+
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestSizeRotation(t *testing.T) {
+	advance, dir := tick(t), t.TempDir()
+
+	w := New(filepath.Join(dir, "app.log"))
+	w.MaxSize, w.MaxBackups = 10, 2
+
+	for _, log := range []string{"log1\n", "log2\n", "log3\n", "log4\n", "log5\n", "log6\n", "log7\n"} {
+		if _, err := w.Write([]byte(log)); err != nil {
+			t.Fatalf("expected no error writing but got '%v'", err)
+		}
+
+		advance(time.Second)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	expected := "app-2024-01-02T15-04-09.000.log,app-2024-01-02T15-04-11.000.log,app.log"
+
+	if actual := strings.Join(files(t, dir), ","); actual != expected {
+		t.Fatalf("expected files '%v' but got '%v'", expected, actual)
+	}
+
+	for name, content := range map[string]string{"app.log": "log7\n", "app-2024-01-02T15-04-11.000.log": "log5\nlog6\n"} {
+		if b, _ := os.ReadFile(filepath.Join(dir, name)); string(b) != content {
+			t.Fatalf("expected %v to contain '%v' but got '%v'", name, content, string(b))
+		}
+	}
+}
+
+func TestIntervalRotation(t *testing.T) {
+	advance, dir := tick(t), t.TempDir()
+
+	w := New(filepath.Join(dir, "app.log"))
+	w.Interval, w.MaxAge, w.Compress = time.Hour, 90*time.Minute, true
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("log\n"))
+		advance(time.Hour)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	expected := "app-2024-01-02T17-04-05.000.log.gz,app-2024-01-02T18-04-05.000.log.gz,app.log"
+
+	if actual := strings.Join(files(t, dir), ","); actual != expected {
+		t.Fatalf("expected files '%v' but got '%v'", expected, actual)
+	}
+
+	f, _ := os.Open(filepath.Join(dir, "app-2024-01-02T18-04-05.000.log.gz"))
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+
+	if err != nil {
+		t.Fatalf("expected a gzip compressed backup but got '%v'", err)
+	}
+
+	if b, _ := io.ReadAll(gz); string(b) != "log\n" {
+		t.Fatalf("expected the compressed backup to contain the log but got '%v'", string(b))
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	w := New(filepath.Join(t.TempDir(), "app.log"))
+	w.Close()
+
+	if _, err := w.Write([]byte("log\n")); err != os.ErrClosed {
+		t.Fatalf("expected writes after close to fail but got '%v'", err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	advance, dir := tick(t), t.TempDir()
+
+	w := New(filepath.Join(dir, "app.log"))
+	w.MaxSize, w.MaxTotalSize, w.Compress, w.CompressAfter = 1000, 650, true, 30*time.Minute
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte(strings.Repeat("a", 599) + "\n"))
+		advance(time.Hour)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	// the backup before the newest is old enough to be compressed, so fits the total size, but the oldest does not
+	expected := "app-2024-01-02T17-04-05.000.log.gz,app-2024-01-02T18-04-05.000.log,app.log"
+
+	if actual := strings.Join(files(t, dir), ","); actual != expected {
+		t.Fatalf("expected files '%v' but got '%v'", expected, actual)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	tick(t)
+	dir := t.TempDir()
+
+	for _, name := range []string{"app-2024-01-02T13-04-05.000.log", "app-2024-01-02T14-04-05.000.log", "other.log"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("log\n"), 0o644)
+	}
+
+	w := New(filepath.Join(dir, "app.log"))
+	w.MaxAge, w.JanitorInterval = 90*time.Minute, 10*time.Millisecond
+
+	w.Write([]byte("log\n")) // starts the janitor, without rotating
+	time.Sleep(50 * time.Millisecond)
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	expected := "app-2024-01-02T14-04-05.000.log,app.log,other.log"
+
+	if actual := strings.Join(files(t, dir), ","); actual != expected {
+		t.Fatalf("expected files '%v' but got '%v'", expected, actual)
+	}
+}
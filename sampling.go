@@ -0,0 +1,155 @@
+package qlog
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// sampledKey is the type of the context key of the sampling decision of a trace
+type sampledKey struct{}
+
+// Sampling configuration
+//
+// These are intended for configuration during start-up. They are not safe for concurrent use.
+var (
+	// SamplingHeader is the HTTP header that propagates the sampling decision of a trace between services
+	SamplingHeader = "X-Qlog-Sampled"
+	// UnsampledMask is the set of severities of log that are not written for traces that are not sampled.
+	//
+	// By default it is the verbose severities Info, Debug and Trace
+	UnsampledMask = OutputFlagInfo | OutputFlagDebug | OutputFlagTrace
+)
+
+// Sample makes a head-based sampling decision for the trace of ctx, such that rate, between 0 and 1, is the proportion
+// of traces sampled. The verbose logs of traces that are not sampled, as defined by UnsampledMask, are not written.
+//
+// If a decision has already been made for the trace, such as by an upstream service, ctx is returned unchanged so that
+// all services in the call chain behave consistently, rather than each sampling independently.
+func Sample(ctx context.Context, rate float64) context.Context {
+	if _, decided := Sampled(ctx); decided {
+		return ctx
+	}
+
+	return WithSampled(ctx, rand.Float64() < rate)
+}
+
+// WithSampled returns a context.Context carrying the specified sampling decision for its trace
+func WithSampled(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, sampledKey{}, sampled)
+}
+
+// Sampled returns the sampling decision for the trace of ctx and whether one has been made. A trace for which no
+// decision has been made is treated as sampled
+func Sampled(ctx context.Context) (sampled bool, decided bool) {
+	sampled, decided = ctx.Value(sampledKey{}).(bool)
+
+	return sampled || !decided, decided
+}
+
+// InjectSampling sets the SamplingHeader of h to the sampling decision for the trace of ctx, if one has been made,
+// so that it is propagated to a downstream service
+func InjectSampling(ctx context.Context, h http.Header) {
+	if sampled, decided := Sampled(ctx); decided {
+		h.Set(SamplingHeader, strconv.FormatBool(sampled))
+	}
+}
+
+// ExtractSampling returns a context.Context carrying the sampling decision propagated by an upstream service in the
+// SamplingHeader of h, or ctx unchanged if h carries no valid decision
+func ExtractSampling(ctx context.Context, h http.Header) context.Context {
+	sampled, err := strconv.ParseBool(h.Get(SamplingHeader))
+
+	if err != nil {
+		return ctx
+	}
+
+	return WithSampled(ctx, sampled)
+}
+
+// sampledOut reports whether a log with the specified flag is not written because its trace is not sampled. The logs of
+// verbose traces are always sampled
+func sampledOut(ctx context.Context, flag OutputMask) bool {
+	if flag&UnsampledMask == 0 || Verbose(ctx) {
+		return false
+	}
+
+	sampled, _ := Sampled(ctx)
+
+	return !sampled
+}
+
+// unsampled reports whether the trace of ctx is propagated as not sampled; either by the sampling decision it carries,
+// such as that of Sample or a remote parent, or by its span
+func unsampled(ctx context.Context) bool {
+	sampled, _ := Sampled(ctx)
+
+	return !sampled || currentSpan(ctx).unsampled
+}
+
+// maxSampleAfterKeys is the maximum number of distinct severities and messages counted by SetSampleAfter; the logs of
+// any beyond it are written in full, as messages are expected to be constant rather than interpolated
+const maxSampleAfterKeys = 10000
+
+// sampleAfterKey identifies the logs counted together by SetSampleAfter
+type sampleAfterKey struct {
+	flag    OutputMask
+	message string
+}
+
+// sampleAfter samples the logs of each severity and message after the first n
+type sampleAfter struct {
+	n      int64
+	rate   float64
+	counts sync.Map // *atomic.Int64, by sampleAfterKey
+	keys   atomic.Int64
+}
+
+var sampledAfter = atomic.Pointer[sampleAfter]{}
+
+// SetSampleAfter writes the first n logs of each distinct severity and message in full, then samples the remainder
+// such that rate, between 0 and 1, is the proportion written. This gives full fidelity for novel errors and cheap
+// coverage for those that are known to be noisy. Sampled logs have a `sample_rate` label of the rate.
+//
+// Fatal and Audit logs, and those of traces marked verbose, are never sampled. Pass zero for n to disable the sampling.
+// This operation is safe for concurrent use.
+func SetSampleAfter(n int, rate float64) {
+	if n <= 0 {
+		sampledAfter.Store(nil)
+		return
+	}
+
+	sampledAfter.Store(&sampleAfter{n: int64(n), rate: rate})
+}
+
+// sampleAfterFirst returns the rate at which the log with the specified flag and message is sampled, and whether it
+// is sampled
+func sampleAfterFirst(ctx context.Context, flag OutputMask, message string) (float64, bool) {
+	s := sampledAfter.Load()
+
+	if s == nil || flag&(OutputFlagFatal|OutputFlagAudit) != 0 || Verbose(ctx) {
+		return 1, true
+	}
+
+	key := sampleAfterKey{flag: flag, message: message}
+	c, ok := s.counts.Load(key)
+
+	if !ok {
+		if s.keys.Load() >= maxSampleAfterKeys {
+			return 1, true
+		}
+
+		if c, ok = s.counts.LoadOrStore(key, &atomic.Int64{}); !ok {
+			s.keys.Add(1)
+		}
+	}
+
+	if c.(*atomic.Int64).Add(1) <= s.n {
+		return 1, true
+	}
+
+	return s.rate, rand.Float64() < s.rate
+}
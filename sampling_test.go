@@ -0,0 +1,98 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSampling(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+
+	unsampled := Sample(ContextFrom(context.Background(), ""), 0)
+
+	if sampled, decided := Sampled(unsampled); sampled || !decided {
+		t.Fatalf("expected trace not to be sampled at a rate of 0")
+	}
+
+	if Sample(unsampled, 1) != unsampled {
+		t.Fatalf("expected an existing sampling decision to be retained")
+	}
+
+	l.Info(unsampled, "verbose")
+	l.Warning(unsampled, "important", nil)
+
+	if output := sb.String(); strings.Contains(output, "verbose") || !strings.Contains(output, "important") {
+		t.Fatalf("expected only the verbose logs of an unsampled trace to be dropped but got '%v'", output)
+	}
+
+	h := http.Header{}
+	InjectSampling(unsampled, h)
+
+	if h.Get(SamplingHeader) != "false" {
+		t.Fatalf("expected the sampling decision to be injected but got '%v'", h.Get(SamplingHeader))
+	}
+
+	downstream := ExtractSampling(context.Background(), h)
+
+	if sampled, decided := Sampled(downstream); sampled || !decided {
+		t.Fatalf("expected the sampling decision to be extracted")
+	}
+
+	if sampled, decided := Sampled(ExtractSampling(context.Background(), http.Header{})); !sampled || decided {
+		t.Fatalf("expected an undecided trace to be treated as sampled")
+	}
+
+	if traceparent := Traceparent(unsampled); !strings.HasSuffix(traceparent, "-00") {
+		t.Fatalf("expected the sampling decision to be propagated in the traceparent but got '%v'", traceparent)
+	}
+
+	sb.Reset()
+	remote := ContextFromTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "")
+
+	if Sample(remote, 1) != remote {
+		t.Fatalf("expected the sampling decision of a remote parent to be retained")
+	}
+
+	l.Info(remote, "verbose")
+
+	if output := sb.String(); output != "" {
+		t.Fatalf("expected the verbose logs of a trace not sampled by a remote parent to be dropped but got '%v'", output)
+	}
+}
+
+func TestSampleAfter(t *testing.T) {
+	defer SetSampleAfter(0, 0)
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+	ctx := context.Background()
+
+	SetSampleAfter(3, 0.5)
+
+	for i := 0; i < 1000; i++ {
+		l.Warning(ctx, "noisy", nil)
+		l.Info(ctx, "noisy")
+	}
+
+	l.Warning(ctx, "novel", nil)
+
+	output := sb.String()
+
+	for _, severity := range []string{"WARNING", "INFO"} {
+		if logs := strings.Count(output, `severity="`+severity+`"`); logs < 400 || logs > 600 {
+			t.Fatalf("%v: expected the first 3 logs and half of the remainder to be written but got %v", severity, logs)
+		}
+	}
+
+	logs := strings.Split(strings.TrimSpace(output), "\n")
+
+	for i, l := range logs {
+		if expected := i >= 6 && !strings.Contains(l, "novel"); strings.Contains(l, SampleRateLabel+"=0.5") != expected {
+			t.Fatalf("expected only the sampled logs to have a sample rate but got '%v' at %v", l, i)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package qlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Scope creates a Log for a session or transaction, such as a checkout process, that includes a `scope` label with the
+// specified name, along with any passed labels, on all its logs.
+//
+// The returned func ends the scope. It writes an info log summarising the scope, labelled with its duration in
+// milliseconds and the number of logs written by the scoped Log, and any Logs derived from it, during the scope.
+// The scoped Log should not be used once the scope has ended.
+//
+// For example:
+//
+//	l, end := logger.Scope(ctx, "checkout", "basket", basketID)
+//	defer end()
+func (l *Log) Scope(ctx context.Context, name string, labels ...any) (*Log, func()) {
+	start, count := l.now(), &atomic.Int64{}
+
+	s := l.WithLabels(append([]any{"scope", name}, labels...)...)
+	s.counters = append(s.counters[:len(s.counters):len(s.counters)], count) // nested scopes also count towards their parents
+
+	once := sync.Once{}
+
+	return s, func() {
+		once.Do(func() {
+			s.Info(ctx, "scope ended", "duration_ms", int(s.now().Sub(start).Milliseconds()), "entries", int(count.Load()))
+		})
+	}
+}
+
+// Scope creates a Log from the default logger for a session or transaction, such as a checkout process, that includes
+// a `scope` label with the specified name, along with any passed labels, on all its logs.
+//
+// The returned func ends the scope. It writes an info log summarising the scope, labelled with its duration in
+// milliseconds and the number of logs written by the scoped Log, and any Logs derived from it, during the scope.
+// The scoped Log should not be used once the scope has ended.
+func Scope(ctx context.Context, name string, labels ...any) (*Log, func()) {
+	return defaultLog.Load().Scope(ctx, name, labels...)
+}
+
+// count counts a log written by the Log towards each Scope it belongs to
+func (l *Log) count() {
+	for _, c := range l.counters {
+		c.Add(1)
+	}
+}
@@ -0,0 +1,65 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer = &sb
+
+	start := time.Now()
+	timeNow = func() time.Time { return start }
+	defer func() { timeNow = time.Now }()
+
+	ctx := ContextFrom(context.Background(), "")
+	s, end := l.Scope(ctx, "checkout", "basket", 42)
+
+	s.Info(ctx, "scoped")
+	s.WithLabels("step", "payment").Notice(ctx, "derived")
+
+	inner, endInner := s.Scope(ctx, "payment")
+	inner.Info(ctx, "nested")
+	endInner()
+
+	l.Info(ctx, "not scoped")
+
+	timeNow = func() time.Time { return start.Add(1500 * time.Millisecond) }
+	end()
+	end()
+
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 logs but got %v: '%s'", len(lines), sb.String())
+	}
+
+	if !strings.Contains(lines[0], `scope="checkout" basket=42 message="scoped"`) {
+		t.Fatalf("expected scoped labels but got '%s'", lines[0])
+	}
+
+	if !strings.Contains(lines[3], `scope="payment" basket=42 duration_ms=0 entries=1 message="scope ended"`) {
+		t.Fatalf("expected nested scope summary but got '%s'", lines[3])
+	}
+
+	if !strings.Contains(lines[5], `scope="checkout" basket=42 duration_ms=1500 entries=4 message="scope ended"`) {
+		t.Fatalf("expected scope summary but got '%s'", lines[5])
+	}
+
+	sb.Reset()
+	SetByteBudget(1)
+
+	s, end = l.Scope(ctx, "shed")
+	s.Info(ctx, "shed by the byte budget")
+
+	SetByteBudget(0)
+	end()
+
+	if output := sb.String(); strings.Contains(output, "shed by the byte budget") || !strings.Contains(output, `entries=0 message="scope ended"`) {
+		t.Fatalf("expected only the logs written to be counted but got '%v'", output)
+	}
+}
@@ -0,0 +1,374 @@
+// Package sentry provides a qlog hook that reports Error and Fatal logs to Sentry as events, with their message, error,
+// labels, stack trace and trace ID, without qlog depending on the Sentry SDK.
+//
+// Events are sampled and rate limited, so that a storm of errors neither overwhelms Sentry nor the application, and are
+// sent from a background goroutine, other than those of Fatal logs, which are sent before the hook returns. For example:
+//
+//	r, err := sentry.New(sentry.Config{DSN: dsn, Environment: "production", SampleRate: 0.5})
+//	// ...
+//	defer r.Close()
+//
+//	qlog.OnSeverity(qlog.OutputFlagError|qlog.OutputFlagFatal, r.Hook)
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Config configures a Reporter
+type Config struct {
+	// DSN is the Sentry DSN of the project, such as "https://key@o0.ingest.sentry.io/0"
+	DSN string
+	// Environment, Release and ServerName are the environment, release and server_name of each event. ServerName
+	// defaults to the hostname reported by the operating system
+	Environment, Release, ServerName string
+	// SampleRate is the fraction of logs, from 0 to 1, reported as events, by default 1
+	SampleRate float64
+	// RateLimit is the maximum number of events sent per minute, by default 60. Events beyond it are dropped
+	RateLimit int
+	// Queue is the maximum number of events waiting to be sent, by default 100. Events beyond it are dropped
+	Queue int
+	// Client is the http.Client used to send events, by default one with a 10s timeout
+	Client *http.Client
+}
+
+// Reporter reports logs to Sentry as events. Its Hook is registered for the severities to be reported, typically Error
+// and Fatal, with qlog.OnSeverity
+type Reporter struct {
+	config   Config
+	endpoint string
+	auth     string
+	queue    chan []byte
+	pending  sync.WaitGroup
+	mx       sync.Mutex
+	tokens   float64
+	refilled time.Time
+	dropped  atomic.Uint64
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// New creates a Reporter that sends events to the project identified by the DSN in the Config
+func New(c Config) (*Reporter, error) {
+	dsn, err := url.Parse(c.DSN)
+
+	if err != nil || dsn.User == nil || dsn.Host == "" {
+		return nil, fmt.Errorf("sentry: invalid dsn '%v'", c.DSN)
+	}
+
+	path, project := "", strings.TrimPrefix(dsn.Path, "/")
+
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		path, project = "/"+project[:i], project[i+1:]
+	}
+
+	if project == "" {
+		return nil, fmt.Errorf("sentry: invalid dsn '%v', it has no project", c.DSN)
+	}
+
+	if c.SampleRate <= 0 {
+		c.SampleRate = 1
+	}
+
+	if c.RateLimit <= 0 {
+		c.RateLimit = 60
+	}
+
+	if c.Queue <= 0 {
+		c.Queue = 100
+	}
+
+	if c.ServerName == "" {
+		c.ServerName, _ = os.Hostname()
+	}
+
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	r := &Reporter{
+		config:   c,
+		endpoint: fmt.Sprintf("%v://%v%v/api/%v/envelope/", dsn.Scheme, dsn.Host, path, project),
+		auth:     "Sentry sentry_version=7, sentry_client=qlog/1.0, sentry_key=" + dsn.User.Username(),
+		queue:    make(chan []byte, c.Queue),
+		tokens:   float64(c.RateLimit),
+		refilled: time.Now(),
+		closed:   make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// Hook reports the log described by e as an event, unless it is sampled out or the rate limit has been reached. It
+// must be called by the goroutine writing the log, as it is when registered as a qlog.Hook, so that the stack trace
+// of the event is that of the log call
+func (r *Reporter) Hook(e qlog.Entry) {
+	if mrand.Float64() >= r.config.SampleRate || !r.allow() {
+		r.dropped.Add(1)
+		return
+	}
+
+	envelope := r.envelope(e, stacktrace())
+
+	if e.Flag&qlog.OutputFlagFatal != 0 { // the process is about to exit, so send synchronously
+		r.send(envelope)
+		return
+	}
+
+	select {
+	case <-r.closed:
+		r.dropped.Add(1)
+		return
+	default:
+	}
+
+	r.pending.Add(1)
+
+	select {
+	case r.queue <- envelope:
+	default:
+		r.pending.Done()
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped due to sampling, rate limiting or a full queue
+func (r *Reporter) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Flush waits until all queued events have been sent, or ctx is done
+func (r *Reporter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the Reporter, waiting up to 10s for queued events to be sent. Events reported after Close are dropped
+func (r *Reporter) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.Flush(ctx)
+	r.once.Do(func() { close(r.closed) })
+
+	return err
+}
+
+// allow reports whether an event can be sent within the rate limit, consuming a token from the bucket if so
+func (r *Reporter) allow() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	now := time.Now()
+	r.tokens += now.Sub(r.refilled).Minutes() * float64(r.config.RateLimit)
+	r.refilled = now
+
+	if limit := float64(r.config.RateLimit); r.tokens > limit {
+		r.tokens = limit
+	}
+
+	if r.tokens < 1 {
+		return false
+	}
+
+	r.tokens--
+
+	return true
+}
+
+// run sends queued events until the Reporter is closed
+func (r *Reporter) run() {
+	for {
+		select {
+		case envelope := <-r.queue:
+			r.send(envelope)
+			r.pending.Done()
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+// send sends an envelope to Sentry. Failures are reported to qlog.ReportError, as writing a log could report it again
+func (r *Reporter) send(envelope []byte) {
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(envelope))
+
+	if err != nil {
+		qlog.ReportError(fmt.Errorf("sentry: unable to create request: %w", err))
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	rsp, err := r.config.Client.Do(req)
+
+	if err != nil {
+		qlog.ReportError(fmt.Errorf("sentry: unable to send event: %w", err))
+		return
+	}
+
+	io.Copy(io.Discard, io.LimitReader(rsp.Body, 1<<16))
+	rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		qlog.ReportError(fmt.Errorf("sentry: unable to send event: %v", rsp.Status))
+	}
+}
+
+type (
+	// event is a Sentry event, see https://develop.sentry.dev/sdk/event-payloads/
+	event struct {
+		EventID     string            `json:"event_id"`
+		Timestamp   string            `json:"timestamp"`
+		Platform    string            `json:"platform"`
+		Level       string            `json:"level"`
+		Logger      string            `json:"logger,omitempty"`
+		Message     string            `json:"message"`
+		Environment string            `json:"environment,omitempty"`
+		Release     string            `json:"release,omitempty"`
+		ServerName  string            `json:"server_name,omitempty"`
+		Tags        map[string]string `json:"tags,omitempty"`
+		Extra       map[string]any    `json:"extra,omitempty"`
+		Exception   []exception       `json:"exception"`
+	}
+	exception struct {
+		Type       string     `json:"type"`
+		Value      string     `json:"value"`
+		Stacktrace stackTrace `json:"stacktrace"`
+	}
+	stackTrace struct {
+		Frames []frame `json:"frames"`
+	}
+	frame struct {
+		Function string `json:"function"`
+		Module   string `json:"module"`
+		Filename string `json:"filename"`
+		AbsPath  string `json:"abs_path"`
+		Lineno   int    `json:"lineno"`
+		InApp    bool   `json:"in_app"`
+	}
+)
+
+// envelope returns the envelope for the event describing the log e, with the stack trace frames
+func (r *Reporter) envelope(e qlog.Entry, frames []frame) []byte {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	ev := event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   e.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       strings.ToLower(e.Severity),
+		Logger:      e.Logger,
+		Message:     e.Message,
+		Environment: r.config.Environment,
+		Release:     r.config.Release,
+		ServerName:  r.config.ServerName,
+		Exception:   []exception{{Type: e.Severity, Value: e.Message, Stacktrace: stackTrace{Frames: frames}}},
+	}
+
+	if e.Error != nil {
+		cause := e.Error
+
+		for errors.Unwrap(cause) != nil {
+			cause = errors.Unwrap(cause)
+		}
+
+		ev.Exception[0].Type, ev.Exception[0].Value = fmt.Sprintf("%T", cause), e.Error.Error()
+	}
+
+	if e.TraceID != "" {
+		ev.Tags = map[string]string{"trace_id": e.TraceID}
+	}
+
+	if labels := e.AllLabels(); len(labels) > 0 {
+		ev.Extra = make(map[string]any, len(labels)/2)
+
+		for i := 0; i+1 < len(labels); i += 2 {
+			ev.Extra[fmt.Sprint(labels[i])] = fmt.Sprint(labels[i+1])
+		}
+	}
+
+	payload, _ := json.Marshal(ev)
+
+	b := fmt.Appendf(nil, `{"event_id":"%v","sent_at":"%v"}`+"\n", ev.EventID, time.Now().UTC().Format(time.RFC3339Nano))
+	b = fmt.Appendf(b, `{"type":"event","length":%v}`+"\n", len(payload))
+
+	return append(append(b, payload...), '\n')
+}
+
+// stacktrace returns the frames of the stack of the caller, oldest first as Sentry expects, excluding those of qlog
+// and its packages, other than in tests
+func stacktrace() []frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := []frame{}
+
+	for {
+		f, more := frames.Next()
+
+		if !strings.HasPrefix(f.Function, "github.com/comradequinn/qlog") || strings.HasSuffix(f.File, "_test.go") {
+			module, function := f.Function, f.Function
+
+			if i := strings.LastIndex(module, "/"); i >= 0 {
+				if j := strings.Index(module[i:], "."); j >= 0 {
+					module, function = module[:i+j], module[i+j+1:]
+				}
+			} else if j := strings.Index(module, "."); j >= 0 {
+				module, function = module[:j], module[j+1:]
+			}
+
+			stack = append(stack, frame{
+				Function: function,
+				Module:   module,
+				Filename: f.File[strings.LastIndex(f.File, "/")+1:],
+				AbsPath:  f.File,
+				Lineno:   f.Line,
+				InApp:    !strings.HasPrefix(module, "runtime") && !strings.HasPrefix(module, "testing"),
+			})
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+
+	return stack
+}
@@ -0,0 +1,105 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comradequinn/qlog"
+)
+
+type server struct {
+	mx     sync.Mutex
+	events []event
+	auth   string
+	path   string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	b, _ := io.ReadAll(r.Body)
+	lines := bytes.Split(b, []byte("\n"))
+
+	ev := event{}
+	json.Unmarshal(lines[2], &ev)
+
+	s.events, s.auth, s.path = append(s.events, ev), r.Header.Get("X-Sentry-Auth"), r.URL.Path
+}
+
+func TestReporter(t *testing.T) {
+	s := &server{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	r, err := New(Config{DSN: strings.Replace(srv.URL, "://", "://public@", 1) + "/sub/42", Environment: "test"})
+
+	if err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	l := qlog.New(qlog.OutputMaskAll, false, "service", "checkout")
+	l.Writer = io.Discard
+	l.TraceID = func(ctx context.Context) string { return "trace-1" }
+	l.OnSeverity(qlog.OutputFlagError|qlog.OutputFlagFatal, r.Hook)
+
+	l.Info(context.Background(), "not reported")
+	l.Error(context.Background(), "payment failed", fmt.Errorf("charging card: %w", errors.New("declined")), "order", 7)
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("expected queued events to be sent but got '%v'", err)
+	}
+
+	if len(s.events) != 1 {
+		t.Fatalf("expected 1 event but got %v", len(s.events))
+	}
+
+	ev := s.events[0]
+
+	if s.path != "/sub/api/42/envelope/" || !strings.Contains(s.auth, "sentry_key=public") {
+		t.Fatalf("expected an authenticated envelope request but got path '%v' and auth '%v'", s.path, s.auth)
+	}
+
+	if ev.Level != "error" || ev.Message != "payment failed" || ev.Environment != "test" || ev.Tags["trace_id"] != "trace-1" ||
+		ev.Extra["service"] != "checkout" || ev.Extra["order"] != "7" {
+		t.Fatalf("expected the event to describe the log but got '%+v'", ev)
+	}
+
+	if x := ev.Exception[0]; x.Type != "*errors.errorString" || x.Value != "charging card: declined" {
+		t.Fatalf("expected an exception of the cause but got '%+v'", x)
+	}
+
+	if frames := ev.Exception[0].Stacktrace.Frames; len(frames) == 0 || frames[len(frames)-1].Function != "TestReporter" {
+		t.Fatalf("expected the stack trace to end at the log call but got '%+v'", frames)
+	}
+}
+
+func TestReporterRateLimit(t *testing.T) {
+	r, _ := New(Config{DSN: "http://key@127.0.0.1:1/1", RateLimit: 2})
+	defer r.Close()
+
+	for i := 0; i < 5; i++ {
+		r.allow()
+	}
+
+	if r.allow() {
+		t.Fatalf("expected events beyond the rate limit to be refused")
+	}
+}
+
+func TestNewInvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://o0.ingest.sentry.io/0", "https://key@o0.ingest.sentry.io/"} {
+		if _, err := New(Config{DSN: dsn}); err == nil {
+			t.Fatalf("%v: expected an invalid dsn error", dsn)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package qlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity defines the importance of a log. Greater values are more severe.
+//
+// Severities provide a simpler, threshold based, alternative to configuring verbosity with an OutputMask,
+// where all logs at or above a minimum Severity are written
+type Severity int
+
+// Severities of the logs written by each log method
+const (
+	SeverityDebug   Severity = 100
+	SeverityTrace   Severity = 200
+	SeverityInfo    Severity = 300
+	SeverityNotice  Severity = 400
+	SeverityWarning Severity = 500
+	SeverityError   Severity = 600
+	SeverityFatal   Severity = 700
+	SeverityAudit   Severity = 1000
+)
+
+// OutputFlagCustomMin is the lowest OutputFlag that may be assigned to a custom Severity, lower flags are reserved by qlog
+const OutputFlagCustomMin OutputMask = 1 << 16
+
+type severity struct {
+	name string
+	flag OutputMask
+}
+
+// severities maps each Severity to its name and the OutputFlag that enables it
+var severities = map[Severity]severity{
+	SeverityDebug:   {"DEBUG", OutputFlagDebug},
+	SeverityTrace:   {"TRACE", OutputFlagTrace},
+	SeverityInfo:    {"INFO", OutputFlagInfo},
+	SeverityNotice:  {"NOTICE", OutputFlagNotice},
+	SeverityWarning: {"WARNING", OutputFlagWarning},
+	SeverityError:   {"ERROR", OutputFlagError},
+	SeverityFatal:   {"FATAL", OutputFlagFatal},
+	SeverityAudit:   {"AUDIT", OutputFlagAudit},
+}
+
+// RegisterSeverity registers a custom Severity, such as an AUDIT or SECURITY severity, that can then be written with Custom.
+//
+// The value of the Severity determines its ordering relative to other severities, for example a value between SeverityNotice
+// and SeverityWarning is considered more severe than a Notice but less severe than a Warning. The name is written as the
+// severity field of the log and is used by ParseOutputMask. The flag is the OutputFlag that enables the Severity in an OutputMask;
+// it must be a single bit no lower than OutputFlagCustomMin.
+//
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func RegisterSeverity(s Severity, name string, flag OutputMask) error {
+	if _, ok := severities[s]; ok {
+		return fmt.Errorf("invalid severity: severity %d is already registered", int(s))
+	}
+
+	if flag < OutputFlagCustomMin || flag&(flag-1) != 0 {
+		return fmt.Errorf("invalid severity: flag must be a single bit no lower than %b", OutputFlagCustomMin)
+	}
+
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	if _, ok := outputMasks[strings.ToLower(name)]; ok || name == "" {
+		return fmt.Errorf("invalid severity: name '%v' is reserved", name)
+	}
+
+	for _, sv := range severities {
+		if sv.name == name || sv.flag == flag {
+			return fmt.Errorf("invalid severity: name '%v' or flag %b is already registered", name, flag)
+		}
+	}
+
+	severities[s] = severity{name: name, flag: flag}
+
+	return nil
+}
+
+// String returns the name of the Severity
+func (s Severity) String() string {
+	if sv, ok := severities[s]; ok {
+		return sv.name
+	}
+
+	return fmt.Sprintf("SEVERITY(%d)", int(s))
+}
+
+// MinSeverityMask returns an OutputMask that includes all severities at or above the specified Severity
+//
+// For example, MinSeverityMask(SeverityWarning) includes Warning, Error and Fatal logs.
+// Audit logs are not subject to verbosity settings, so OutputFlagAudit is never included
+func MinSeverityMask(s Severity) OutputMask {
+	m := OutputFlagNone
+
+	for sv, v := range severities {
+		if sv >= s && sv != SeverityAudit {
+			m |= v.flag
+		}
+	}
+
+	return m
+}
+
+// severityOf returns the Severity enabled by the OutputFlag, or SeverityInfo if no Severity is registered for it
+func severityOf(flag OutputMask) Severity {
+	for s, sv := range severities {
+		if sv.flag == flag {
+			return s
+		}
+	}
+
+	return SeverityInfo
+}
+
+// sortedSeverities returns the registered severities in descending order of severity
+func sortedSeverities() []severity {
+	keys := make([]Severity, 0, len(severities))
+
+	for s := range severities {
+		keys = append(keys, s)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] > keys[j] })
+
+	sorted := make([]severity, 0, len(keys))
+
+	for _, s := range keys {
+		sorted = append(sorted, severities[s])
+	}
+
+	return sorted
+}
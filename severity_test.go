@@ -0,0 +1,78 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMinSeverityMask(t *testing.T) {
+	tcs := []struct {
+		Severity Severity
+		Expected OutputMask
+	}{
+		{Severity: SeverityFatal, Expected: OutputFlagFatal},
+		{Severity: SeverityWarning, Expected: OutputFlagFatal | OutputFlagError | OutputFlagWarning},
+		{Severity: SeverityNotice, Expected: OutputMaskImportant},
+		{Severity: SeverityInfo, Expected: OutputMaskDetail},
+		{Severity: SeverityDebug, Expected: OutputMaskAll | OutputFlagTrace},
+	}
+
+	for _, tc := range tcs {
+		if m := MinSeverityMask(tc.Severity); m != tc.Expected {
+			t.Fatalf("%v: expected mask '%v' but got '%v'", tc.Severity, tc.Expected, m)
+		}
+	}
+}
+
+func TestCustomSeverity(t *testing.T) {
+	const severitySecurity, outputFlagSecurity = Severity(450), OutputFlagCustomMin << 1
+
+	if err := RegisterSeverity(severitySecurity, "security", outputFlagSecurity); err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	defer delete(severities, severitySecurity)
+
+	for _, invalid := range []struct {
+		Severity Severity
+		Name     string
+		Flag     OutputMask
+	}{
+		{Severity: SeverityInfo, Name: "other", Flag: OutputFlagCustomMin},
+		{Severity: 451, Name: "other", Flag: OutputFlagInfo},
+		{Severity: 451, Name: "other", Flag: OutputFlagCustomMin | OutputFlagCustomMin<<2},
+		{Severity: 451, Name: "SECURITY", Flag: OutputFlagCustomMin},
+		{Severity: 451, Name: "all", Flag: OutputFlagCustomMin},
+	} {
+		if err := RegisterSeverity(invalid.Severity, invalid.Name, invalid.Flag); err == nil {
+			t.Fatalf("expected error registering '%+v'", invalid)
+		}
+	}
+
+	if m := MinSeverityMask(SeverityNotice); m != OutputMaskImportant|outputFlagSecurity {
+		t.Fatalf("expected custom severity to be included in threshold mask but got '%v'", m)
+	}
+
+	if m, err := ParseOutputMask("error|security"); err != nil || m.String() != "error|security" {
+		t.Fatalf("expected custom severity to be parsed but got '%v', %v", m, err)
+	}
+
+	sb := strings.Builder{}
+	l := New(outputFlagSecurity, false)
+	l.Writer = &sb
+
+	l.Custom(context.Background(), severitySecurity, "custom")
+	l.Custom(context.Background(), 451, "unregistered")
+
+	if output := sb.String(); strings.Count(output, "\n") != 1 || !strings.Contains(output, `severity="SECURITY"`) {
+		t.Fatalf("expected one custom severity log but got '%s'", output)
+	}
+
+	sb.Reset()
+	l.Custom(context.Background(), SeverityAudit, "audited")
+
+	if output := sb.String(); !strings.Contains(output, `severity="AUDIT"`) || !strings.Contains(output, `message="audited"`) {
+		t.Fatalf("expected audit severity logs to be written regardless of the output mask but got '%s'", output)
+	}
+}
@@ -0,0 +1,51 @@
+package qlog
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// HandleSignalsFor changes the verbosity of the default logger to the specified OutputMask when the raise signal is received
+// and restores the verbosity that preceded it when the restore signal is received. A notice is logged on each change.
+//
+// This is the conventional operational pattern for long-running daemons, allowing verbose logging to be enabled temporarily
+// without a restart. The returned func stops handling the signals.
+func HandleSignalsFor(raise, restore os.Signal, m OutputMask) func() {
+	signals, done := make(chan os.Signal, 1), make(chan struct{})
+	signal.Notify(signals, raise, restore)
+
+	go func() {
+		previous, raised := OutputFlagNone, false
+
+		for {
+			select {
+			case <-done:
+				return
+			case s := <-signals:
+				switch {
+				case s == raise && !raised:
+					previous, raised = defaultLog.Load().OutputMask(), true
+					SetOutputMask(m)
+				case s == restore && raised:
+					raised = false
+					SetOutputMask(previous)
+				default:
+					continue
+				}
+
+				Notice(context.Background(), "verbosity changed by signal", "signal", s.String(), "output_mask", defaultLog.Load().OutputMask().String())
+			}
+		}
+	}()
+
+	once := sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package qlog
+
+// HandleSignals has no effect on platforms without SIGUSR1 and SIGUSR2, use HandleSignalsFor to configure the signals.
+// The returned func is a no-op.
+func HandleSignals() func() {
+	return func() {}
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package qlog
+
+import "syscall"
+
+// HandleSignals changes the verbosity of the default logger to OutputMaskAll, including Trace, when SIGUSR1 is received
+// and restores the verbosity that preceded it when SIGUSR2 is received. A notice is logged on each change.
+//
+// Use HandleSignalsFor to configure the signals and verbosity. The returned func stops handling the signals.
+func HandleSignals() func() {
+	return HandleSignalsFor(syscall.SIGUSR1, syscall.SIGUSR2, OutputMaskAll|OutputFlagTrace)
+}
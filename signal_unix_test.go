@@ -0,0 +1,37 @@
+//go:build unix
+
+package qlog
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	defer resetDefaultLog()()
+
+	SetWriter(io.Discard)
+	SetOutputMask(OutputMaskImportant)
+
+	stop := HandleSignals()
+	defer stop()
+
+	await := func(expected OutputMask) {
+		for i := 0; i < 100 && defaultLog.Load().OutputMask() != expected; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if m := defaultLog.Load().OutputMask(); m != expected {
+			t.Fatalf("expected mask '%v' but got '%v'", expected, m)
+		}
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	await(OutputMaskAll | OutputFlagTrace)
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	await(OutputMaskImportant)
+}
@@ -0,0 +1,121 @@
+package qlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Sync writes any logs held by the writers of the Log, such as those queued by an AsyncWriter or batched by a
+// BufferedWriter, and commits them to stable storage where the writers support it, such as files. Writers that wrap
+// others, such as a Router or an AsyncWriter, are synced from the outermost inwards, so that held logs reach the
+// innermost writers before they are synced.
+//
+// It returns any errors encountered, but continues to sync the remaining writers.
+func (l *Log) Sync() error {
+	errs := []error{}
+
+	l.walk(func(w io.Writer) {
+		if f, ok := w.(interface{ Flush(context.Context) error }); ok {
+			errs = append(errs, f.Flush(context.Background()))
+		}
+
+		if s, ok := w.(interface{ Sync() error }); ok {
+			errs = append(errs, s.Sync())
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Close writes any logs held by the writers of the Log, as Sync does, then closes those that are an io.Closer, such as
+// files, from the outermost inwards. The standard output and error streams are never closed. The Log should not be
+// used once it has been closed, other than to write logs synchronously to writers that remain open, such as stderr.
+//
+// It returns any errors encountered, but continues to close the remaining writers.
+func (l *Log) Close() error {
+	errs := []error{l.Sync()}
+
+	l.walk(func(w io.Writer) {
+		if c, ok := w.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Flush blocks until all logs held by the writers of the default logger, such as an AsyncWriter, have been
+// written or ctx is done.
+// This operation is safe for concurrent use.
+func Flush(ctx context.Context) error {
+	var err error
+
+	defaultLog.Load().walk(func(w io.Writer) {
+		if f, ok := w.(interface{ Flush(context.Context) error }); ok && err == nil {
+			err = f.Flush(ctx)
+		}
+	})
+
+	return err
+}
+
+// Sync writes all logs held by the writers of the default logger and commits them to stable storage, see Log.Sync.
+// This operation is safe for concurrent use.
+func Sync() error {
+	return defaultLog.Load().Sync()
+}
+
+// Close writes all logs held by the writers of the default logger, such as an AsyncWriter or BufferedWriter, and closes
+// them, see Log.Close. Defer it in main, so that no logs are lost on exit.
+// This operation is safe for concurrent use.
+func Close() error {
+	return defaultLog.Load().Close()
+}
+
+// walk calls fn for each distinct writer of the Log, and each writer they wrap, from the outermost inwards. The
+// standard output and error streams are skipped, as they are neither buffered nor to be closed
+func (l *Log) walk(fn func(w io.Writer)) {
+	visited := []io.Writer{}
+
+	var visit func(w io.Writer)
+
+	visit = func(w io.Writer) {
+		if w == nil || w == os.Stdout || w == os.Stderr {
+			return
+		}
+
+		if reflect.TypeOf(w).Comparable() {
+			for _, v := range visited {
+				if reflect.TypeOf(v).Comparable() && v == w {
+					return
+				}
+			}
+
+			visited = append(visited, w)
+		}
+
+		fn(w)
+
+		switch w := w.(type) {
+		case *Router:
+			for _, rt := range w.Routes() {
+				visit(rt.Writer)
+			}
+		case *RetryWriter:
+			visit(w.Writer)
+			visit(w.Fallback)
+		case *CircuitBreaker:
+			visit(w.Writer)
+			visit(w.Fallback)
+		case interface{ Unwrap() io.Writer }:
+			visit(w.Unwrap())
+		}
+	}
+
+	visit(l.Writer)
+	visit(l.AuditWriter)
+	visit(l.FallbackWriter)
+}
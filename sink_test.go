@@ -0,0 +1,55 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type closableSyncer struct {
+	countingSyncer
+	closed bool
+}
+
+func (cs *closableSyncer) Close() error {
+	cs.closed = true
+	return nil
+}
+
+func TestSyncAndClose(t *testing.T) {
+	buffered, synced := &closableSyncer{}, &closableSyncer{}
+
+	l := New(OutputMaskAll, false)
+	l.Writer = NewRouter(
+		Route{Writer: NewBufferedWriter(buffered, 1024, time.Hour), Mask: OutputMaskAll},
+		Route{Writer: NewSyncWriter(synced, SyncPolicy{}), Mask: OutputMaskAll},
+	)
+	l.AuditWriter = synced // visited once, despite being reachable twice
+
+	l.Info(context.Background(), "held")
+
+	if buffered.writes != 0 {
+		t.Fatalf("expected the log to be held by the buffered writer")
+	}
+
+	if err := l.Sync(); err != nil {
+		t.Fatalf("expected no error syncing but got '%v'", err)
+	}
+
+	if !strings.Contains(buffered.String(), `message="held"`) || buffered.syncs != 1 {
+		t.Fatalf("expected the held log to be written and synced but got '%v' and %v syncs", buffered.String(), buffered.syncs)
+	}
+
+	if synced.syncs != 2 { // synced once by the SyncWriter wrapping it and once directly
+		t.Fatalf("expected the wrapped syncer to be synced twice but got %v", synced.syncs)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("expected no error closing but got '%v'", err)
+	}
+
+	if !buffered.closed || !synced.closed {
+		t.Fatalf("expected the closable writers to be closed")
+	}
+}
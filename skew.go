@@ -0,0 +1,178 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// started is the time the process started, it carries a monotonic reading so uptime is unaffected by changes to the wall clock
+var started = time.Now()
+
+// SkewWriter is an io.Writer that annotates each log with the uptime of the process and, once set, an estimate of the
+// offset of the local clock from a reference clock. This allows a tool merging logs from many hosts, such as those
+// shipped to a network sink, to correct for clock skew when interleaving them.
+//
+// The uptime is written as an `uptime_ms` label, measured with the monotonic clock, so it is unaffected by changes to
+// the wall clock. The offset is written as a `clock_offset_ms` label; add it to the timestamp of a log to estimate the
+// time on the reference clock. Use SetOffset to set it, such as periodically from the result of SNTPOffset.
+type SkewWriter struct {
+	w         io.Writer
+	offset    atomic.Int64
+	hasOffset atomic.Bool
+	mx        sync.Mutex
+	b         []byte
+}
+
+// NewSkewWriter creates a SkewWriter that writes annotated logs to w
+//
+// For example:
+//
+//	sw := qlog.NewSkewWriter(conn)
+//	qlog.SetWriter(qlog.NewRouter(qlog.Route{Writer: os.Stdout, Mask: qlog.OutputMaskAll}, qlog.Route{Writer: sw, Mask: qlog.OutputMaskAll}))
+//
+//	go func() {
+//		for range time.Tick(time.Minute) {
+//			if offset, err := qlog.SNTPOffset(ctx, "pool.ntp.org:123"); err == nil {
+//				sw.SetOffset(offset)
+//			}
+//		}
+//	}()
+func NewSkewWriter(w io.Writer) *SkewWriter {
+	return &SkewWriter{w: w}
+}
+
+// Unwrap returns the underlying Writer
+func (sw *SkewWriter) Unwrap() io.Writer {
+	return sw.w
+}
+
+// SetOffset sets the estimated offset of the local clock from the reference clock, included in subsequent logs
+func (sw *SkewWriter) SetOffset(offset time.Duration) {
+	sw.offset.Store(int64(offset))
+	sw.hasOffset.Store(true)
+}
+
+// Write annotates b, which must be a single log in either format, and writes it to the underlying Writer
+func (sw *SkewWriter) Write(b []byte) (int, error) {
+	return sw.WriteSeverity(OutputFlagNone, b)
+}
+
+// WriteSeverity annotates b, which must be a single log in either format, and writes it to the underlying Writer,
+// passing on the severity flag if the Writer is a SeverityWriter
+func (sw *SkewWriter) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	sw.mx.Lock()
+	defer sw.mx.Unlock()
+
+	sw.b = sw.annotate(sw.b[:0], b)
+
+	var err error
+
+	if w, ok := sw.w.(SeverityWriter); ok {
+		_, err = w.WriteSeverity(flag, sw.b)
+	} else {
+		_, err = sw.w.Write(sw.b)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// annotate appends the log in b to a, with the uptime and offset labels inserted before its end
+func (sw *SkewWriter) annotate(a, b []byte) []byte {
+	openField, closeField, end := ` `, `=`, bytes.LastIndexByte(b, '\n')
+
+	if bytes.HasPrefix(b, []byte("{")) {
+		openField, closeField, end = `, "`, `": `, bytes.LastIndexByte(b, '}')
+	}
+
+	if end < 0 {
+		end = len(b)
+	}
+
+	a = append(a, bytes.TrimRight(b[:end], " ")...)
+	a = appendField(a, openField, "uptime_ms", closeField)
+	a = strconv.AppendInt(a, time.Since(started).Milliseconds(), 10)
+
+	if sw.hasOffset.Load() {
+		a = appendField(a, openField, "clock_offset_ms", closeField)
+		a = strconv.AppendInt(a, time.Duration(sw.offset.Load()).Milliseconds(), 10)
+	}
+
+	if end < len(b) && b[end] == '}' {
+		a = append(a, ' ')
+	}
+
+	return append(a, b[end:]...)
+}
+
+// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and the unix epoch, 1970
+const ntpEpochOffset = 2208988800
+
+// SNTPOffset estimates the offset of the local clock from that of the NTP server at address, such as
+// "pool.ntp.org:123", with a single SNTP request. Add the offset to the local time to estimate the time on the server.
+// The request is abandoned once ctx is done.
+func SNTPOffset(ctx context.Context, address string) (time.Duration, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", address)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0x1B // no leap indicator, version 3, client mode
+
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(sent))
+
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 48)
+
+	n, err := conn.Read(resp)
+	received := time.Now()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 48 || resp[0]&0x07 != 4 { // the response must be a complete packet in server mode
+		return 0, errors.New("qlog: invalid sntp response")
+	}
+
+	serverReceived := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	serverSent := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+func toNTPTime(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+
+	return secs<<32 | frac
+}
+
+func fromNTPTime(v uint64) time.Time {
+	secs, frac := int64(v>>32)-ntpEpochOffset, int64((v&0xFFFFFFFF)*1e9>>32)
+
+	return time.Unix(secs, frac)
+}
@@ -0,0 +1,80 @@
+package qlog
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSkewWriter(t *testing.T) {
+	type testCase struct {
+		JSON     bool
+		Offset   time.Duration
+		Expected string
+	}
+
+	for name, tc := range map[string]testCase{
+		"json":               {JSON: true, Expected: `"message": "test", "uptime_ms": \d+ }\n$`},
+		"json with offset":   {JSON: true, Offset: 1500 * time.Millisecond, Expected: `"message": "test", "uptime_ms": \d+, "clock_offset_ms": 1500 }\n$`},
+		"logfmt":             {Expected: `message="test" uptime_ms=\d+\n$`},
+		"logfmt with offset": {Offset: -20 * time.Millisecond, Expected: `message="test" uptime_ms=\d+ clock_offset_ms=-20\n$`},
+	} {
+		w := strings.Builder{}
+		sw := NewSkewWriter(&w)
+
+		if tc.Offset != 0 {
+			sw.SetOffset(tc.Offset)
+		}
+
+		l := New(OutputMaskAll, tc.JSON)
+		l.Writer = sw
+		l.Info(context.Background(), "test")
+
+		if !regexp.MustCompile(tc.Expected).MatchString(w.String()) {
+			t.Fatalf("%v: expected log matching '%v' but got '%v'", name, tc.Expected, w.String())
+		}
+	}
+}
+
+func TestSNTPOffset(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	defer conn.Close()
+
+	go func() { // a server whose clock is an hour ahead
+		req := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(req)
+
+		if err != nil {
+			return
+		}
+
+		resp := make([]byte, 48)
+		resp[0] = 0x1C // no leap indicator, version 3, server mode
+		now := toNTPTime(time.Now().Add(time.Hour))
+		binary.BigEndian.PutUint64(resp[32:], now)
+		binary.BigEndian.PutUint64(resp[40:], now)
+		conn.WriteTo(resp, addr)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	offset, err := SNTPOffset(ctx, conn.LocalAddr().String())
+
+	if err != nil {
+		t.Fatalf("expected no error but got '%v'", err)
+	}
+
+	if offset < time.Hour-time.Second || offset > time.Hour+time.Second {
+		t.Fatalf("expected an offset of an hour but got %v", offset)
+	}
+}
@@ -0,0 +1,88 @@
+package qlog
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// spanContext is the trace context carried by a context.Context created with ContextFrom
+type spanContext struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	unsampled    bool   // set where the span of a SpanSource is not sampled, so it is propagated as such
+	tracestate   string // the vendor specific trace state of a remote parent, propagated unaltered
+}
+
+// Span configuration
+//
+// These act as defaults for all Logs
+var (
+	// SpanIDFieldName and ParentSpanIDFieldName define the keys assigned to the Span-ID and parent Span-ID in the log.
+	// Each is only written where the log has one
+	SpanIDFieldName       = "span"
+	ParentSpanIDFieldName = "parent_span"
+	// SpanID returns the Span-ID associated with the passed ctx, the ID of the operation within the trace that wrote a log
+	//
+	// By default it returns the Span-ID of the ExternalSpan active on ctx, if a SpanSource is set and has one, otherwise
+	// that assigned to the ctx by the conventional call to qlog.ContextFrom(); override this, if required, to read the
+	// Span-ID written by other tracing tooling
+	SpanID = func(ctx context.Context) string {
+		return currentSpan(ctx).spanID
+	}
+	// ParentSpanID returns the Span-ID of the parent of the span associated with the passed ctx, if it has one
+	//
+	// By default it returns the Span-ID of the context.Context from which ctx was derived by qlog.ContextFrom(), or none
+	// where an ExternalSpan is active on ctx; override this, if required, to read the parent Span-ID written by tracing
+	// tooling
+	ParentSpanID = func(ctx context.Context) string {
+		return currentSpan(ctx).parentSpanID
+	}
+)
+
+// ExternalSpan is a span started by tracing tooling, such as OpenTelemetry, rather than by ContextFrom
+type ExternalSpan struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// SpanSource returns the ExternalSpan active on ctx, if there is one
+type SpanSource func(ctx context.Context) (ExternalSpan, bool)
+
+// spanSourceValue wraps a SpanSource so that it can be stored in an atomic.Pointer
+type spanSourceValue struct {
+	source SpanSource
+}
+
+var spanSource = atomic.Pointer[spanSourceValue]{}
+
+// SetSpanSource sets the SpanSource from which the spans of tracing tooling, such as OpenTelemetry, are read. Where a
+// span of that tooling is active on a context.Context, its Trace-ID and Span-ID are written in logs, and propagated,
+// rather than those minted by qlog, so that logs written within instrumented code correlate with its traces. Contexts
+// derived with ContextFrom from one with an active span are in its trace, with it as their parent span.
+//
+// Pass nil to read only the spans of ContextFrom.
+// This operation is safe for concurrent use.
+func SetSpanSource(s SpanSource) {
+	if s == nil {
+		spanSource.Store(nil)
+		return
+	}
+
+	spanSource.Store(&spanSourceValue{source: s})
+}
+
+// currentSpan returns the span active on ctx; the ExternalSpan returned by the SpanSource, if there is one, otherwise
+// the span of ContextFrom, if there is one
+func currentSpan(ctx context.Context) spanContext {
+	if v := spanSource.Load(); v != nil {
+		if span, ok := v.source(ctx); ok && span.TraceID != "" {
+			return spanContext{traceID: span.TraceID, spanID: span.SpanID, unsampled: !span.Sampled}
+		}
+	}
+
+	sc, _ := ctx.Value(traceIDKey).(spanContext)
+
+	return sc
+}
@@ -0,0 +1,76 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContextFromSpans(t *testing.T) {
+	root := ContextFrom(context.Background(), "")
+	child := ContextFrom(root, "")
+	grandchild := ContextFrom(child, TraceID(child))
+	remote := ContextFrom(child, "remote-trace")
+
+	if TraceID(child) != TraceID(root) || TraceID(grandchild) != TraceID(root) {
+		t.Fatalf("expected derived contexts to share the trace '%v' but got '%v' and '%v'", TraceID(root), TraceID(child), TraceID(grandchild))
+	}
+
+	if SpanID(root) == "" || SpanID(child) == SpanID(root) || SpanID(grandchild) == SpanID(child) {
+		t.Fatalf("expected each context to have a fresh span but got '%v', '%v' and '%v'", SpanID(root), SpanID(child), SpanID(grandchild))
+	}
+
+	if ParentSpanID(root) != "" || ParentSpanID(child) != SpanID(root) || ParentSpanID(grandchild) != SpanID(child) {
+		t.Fatalf("expected each derived context to record its parent span")
+	}
+
+	if TraceID(remote) != "remote-trace" || ParentSpanID(remote) != "" {
+		t.Fatalf("expected a context with a different trace to have no parent span but got '%v'", ParentSpanID(remote))
+	}
+
+	w := &strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(w)
+
+	l.Info(root, "root")
+	l.Info(child, "child")
+
+	for i, expected := range []string{
+		`trace="` + TraceID(root) + `" span="` + SpanID(root) + `" severity="INFO"`,
+		`trace="` + TraceID(root) + `" span="` + SpanID(child) + `" parent_span="` + SpanID(root) + `" severity="INFO"`,
+	} {
+		if log := strings.Split(w.String(), "\n")[i]; !strings.HasPrefix(log, expected) {
+			t.Fatalf("expected log %v to start '%v' but got '%v'", i, expected, log)
+		}
+	}
+}
+
+func TestSetSpanSource(t *testing.T) {
+	type spanKey struct{}
+
+	SetSpanSource(func(ctx context.Context) (ExternalSpan, bool) {
+		span, ok := ctx.Value(spanKey{}).(ExternalSpan)
+		return span, ok
+	})
+
+	defer SetSpanSource(nil)
+
+	ctx := context.WithValue(context.Background(), spanKey{}, ExternalSpan{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+
+	if TraceID(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" || SpanID(ctx) != "00f067aa0ba902b7" || ParentSpanID(ctx) != "" {
+		t.Fatalf("expected the ids of the external span but got trace '%v', span '%v' and parent '%v'", TraceID(ctx), SpanID(ctx), ParentSpanID(ctx))
+	}
+
+	if expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"; Traceparent(ctx) != expected {
+		t.Fatalf("expected the external span to be propagated as '%v' but got '%v'", expected, Traceparent(ctx))
+	}
+
+	if child := ContextFrom(context.Background(), ""); TraceID(child) == "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected a context without an external span to have its own trace")
+	}
+
+	SetSpanSource(nil)
+
+	if TraceID(ctx) != "" {
+		t.Fatalf("expected the external span to be ignored once the span source is removed but got '%v'", TraceID(ctx))
+	}
+}
@@ -0,0 +1,375 @@
+// Package splunk provides a qlog sink that writes logs as events to a Splunk HTTP Event Collector (HEC), removing the
+// need for a universal forwarder.
+//
+// Events are sent in batches and, optionally, their indexing acknowledged. The sourcetype, index and source of each
+// event can be overridden by the labels of its log. For example:
+//
+//	w := splunk.New(splunk.Config{URL: "https://splunk.example.com:8088", Token: token, Index: "app"})
+//	defer w.Close()
+//
+//	qlog.SetWriter(w)
+//	qlog.Info(ctx, "payment taken", splunk.LabelSourceType, "payments")
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Labels that, rather than being written as fields of the event, override the metadata of the event
+const (
+	LabelSourceType = "splunk_sourcetype"
+	LabelIndex      = "splunk_index"
+	LabelSource     = "splunk_source"
+)
+
+// ErrNotAcknowledged is returned by Flush when the indexing of events has not been acknowledged before it returns
+var ErrNotAcknowledged = errors.New("splunk: events not acknowledged")
+
+// Config configures a Splunk HEC Writer
+type Config struct {
+	// URL is the base URL of the HEC, such as "https://splunk.example.com:8088"
+	URL string
+	// Token is the HEC token used to authenticate
+	Token string
+	// Host, Source, SourceType and Index are the default metadata of each event. Host defaults to the hostname
+	// reported by the operating system; the others, when empty, default to those configured for the token
+	Host, Source, SourceType, Index string
+	// BatchSize is the maximum number of events in a batch, by default 100
+	BatchSize int
+	// Interval is the maximum time an event waits in a batch before it is sent, by default 1s
+	Interval time.Duration
+	// Ack, if true, requests indexer acknowledgement of each batch, which is confirmed by Flush. It must be enabled
+	// for the token
+	Ack bool
+	// Channel is the channel identifier sent with each batch, as required when acknowledgement is enabled, by
+	// default a random GUID
+	Channel string
+	// Client is the http.Client used to send batches, by default one with a 10s timeout
+	Client *http.Client
+}
+
+// Writer is a qlog.EntryWriter that writes logs as events to a Splunk HEC in batches.
+//
+// A batch is sent once it holds BatchSize events, or once the oldest event in it has waited for the Interval. Fatal
+// logs are sent immediately, along with any batched before them. Should a batch that was sent by the Interval elapsing
+// fail, the error is returned by the next call to Write, or Flush
+type Writer struct {
+	config Config
+	mx     sync.Mutex
+	b      []byte
+	events int
+	timer  *time.Timer
+	err    error              // the error from sending a batch when the interval elapsed, if any
+	acks   map[int64]struct{} // the IDs of sent batches whose indexing has not been acknowledged
+}
+
+// New creates a Writer with the specified Config
+func New(c Config) *Writer {
+	if c.Host == "" {
+		c.Host, _ = os.Hostname()
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+
+	if c.Channel == "" {
+		c.Channel = guid()
+	}
+
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	c.URL = strings.TrimSuffix(c.URL, "/")
+
+	return &Writer{config: c, acks: map[int64]struct{}{}}
+}
+
+// Write adds b, a log encoded by qlog, to the current batch as the message of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry adds the log described by e to the current batch as an event, sending the batch immediately if it is full
+// or the log is Fatal
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	event, err := w.encode(e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if err := w.err; err != nil {
+		w.err = nil
+		return 0, err
+	}
+
+	w.b = append(w.b, event...)
+	w.events++
+
+	if w.events >= w.config.BatchSize || e.Flag&qlog.OutputFlagFatal != 0 {
+		if err := w.send(); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.config.Interval, w.elapsed)
+	}
+
+	return len(b), nil
+}
+
+// Flush sends the current batch and, if Ack is enabled, waits until the indexing of all sent batches is
+// acknowledged, returning ErrNotAcknowledged should ctx be done first
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mx.Lock()
+
+	err := w.err
+	w.err = nil
+
+	if serr := w.send(); err == nil {
+		err = serr
+	}
+
+	w.mx.Unlock()
+
+	if err != nil || !w.config.Ack {
+		return err
+	}
+
+	for {
+		pending, err := w.acknowledge(ctx)
+
+		if err != nil || pending == 0 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v batches pending", ErrNotAcknowledged, pending)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Close sends the current batch and, if Ack is enabled, waits up to 10s for the indexing of all sent batches to be
+// acknowledged
+func (w *Writer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return w.Flush(ctx)
+}
+
+// elapsed sends the current batch once the interval has elapsed since its first event was added
+func (w *Writer) elapsed() {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.timer = nil // set before sending, which stops any timer it finds
+
+	if err := w.send(); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// send sends the current batch, the caller must hold mx
+func (w *Writer) send() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+
+	if w.events == 0 {
+		return nil
+	}
+
+	body := w.b
+	w.b, w.events = nil, 0
+
+	var res struct {
+		Text  string `json:"text"`
+		Code  int    `json:"code"`
+		AckID *int64 `json:"ackId"`
+	}
+
+	if err := w.post(context.Background(), "/services/collector/event", body, &res); err != nil {
+		return err
+	}
+
+	if w.config.Ack && res.AckID != nil {
+		w.acks[*res.AckID] = struct{}{}
+	}
+
+	return nil
+}
+
+// acknowledge queries the HEC for the acknowledgement of the pending batches, returning the number still pending
+func (w *Writer) acknowledge(ctx context.Context) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if len(w.acks) == 0 {
+		return 0, nil
+	}
+
+	req := struct {
+		Acks []int64 `json:"acks"`
+	}{}
+
+	for id := range w.acks {
+		req.Acks = append(req.Acks, id)
+	}
+
+	body, _ := json.Marshal(req)
+
+	var res struct {
+		Acks map[string]bool `json:"acks"`
+	}
+
+	if err := w.post(ctx, "/services/collector/ack", body, &res); err != nil {
+		return len(w.acks), err
+	}
+
+	for id, acked := range res.Acks {
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil && acked {
+			delete(w.acks, n)
+		}
+	}
+
+	return len(w.acks), nil
+}
+
+// post posts body to the HEC endpoint at path and decodes its response into res
+func (w *Writer) post(ctx context.Context, path string, body []byte, res any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL+path, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("splunk: unable to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Splunk "+w.config.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Splunk-Request-Channel", w.config.Channel)
+
+	rsp, err := w.config.Client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("splunk: unable to post to %v: %w", path, err)
+	}
+
+	defer rsp.Body.Close()
+
+	b, _ := io.ReadAll(io.LimitReader(rsp.Body, 1<<16))
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("splunk: %v responded %v: %s", path, rsp.Status, bytes.TrimSpace(b))
+	}
+
+	json.Unmarshal(b, res)
+
+	return nil
+}
+
+// encode returns the HEC event for the log described by e. The labels of the log are written as fields of the event,
+// other than those that override its metadata
+func (w *Writer) encode(e qlog.Entry) ([]byte, error) {
+	type event struct {
+		Time       float64        `json:"time"`
+		Host       string         `json:"host,omitempty"`
+		Source     string         `json:"source,omitempty"`
+		SourceType string         `json:"sourcetype,omitempty"`
+		Index      string         `json:"index,omitempty"`
+		Event      map[string]any `json:"event"`
+	}
+
+	ev := event{
+		Time:       float64(e.Time.UnixMilli()) / 1e3,
+		Host:       w.config.Host,
+		Source:     w.config.Source,
+		SourceType: w.config.SourceType,
+		Index:      w.config.Index,
+		Event:      map[string]any{"severity": e.Severity, "message": e.Message},
+	}
+
+	if e.TraceID != "" {
+		ev.Event["trace_id"] = e.TraceID
+	}
+
+	if e.Error != nil {
+		ev.Event["error"] = e.Error.Error()
+	}
+
+	labels := e.AllLabels()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		k := fmt.Sprint(labels[i])
+
+		switch k {
+		case LabelSourceType:
+			ev.SourceType = fmt.Sprint(labels[i+1])
+		case LabelIndex:
+			ev.Index = fmt.Sprint(labels[i+1])
+		case LabelSource:
+			ev.Source = fmt.Sprint(labels[i+1])
+		default:
+			ev.Event[k] = fieldValue(labels[i+1])
+		}
+	}
+
+	return json.Marshal(ev)
+}
+
+// fieldValue returns v as a value that can be encoded as JSON
+func fieldValue(v any) any {
+	switch v := v.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return fieldValue(float64(v))
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) { // not representable in JSON
+			return v
+		}
+	case error:
+		return v.Error()
+	}
+
+	return fmt.Sprint(v)
+}
+
+// guid returns a random, version 4, GUID
+func guid() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,131 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+type hec struct {
+	mx      sync.Mutex
+	batches []string
+	acked   bool
+	headers http.Header
+}
+
+func (h *hec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.headers = r.Header
+	b, _ := io.ReadAll(r.Body)
+
+	switch r.URL.Path {
+	case "/services/collector/event":
+		h.batches = append(h.batches, string(b))
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+	case "/services/collector/ack":
+		json.NewEncoder(w).Encode(map[string]any{"acks": map[string]bool{"7": h.acked}})
+		h.acked = true // acknowledged on the next query
+	}
+}
+
+func TestWriterBatching(t *testing.T) {
+	h := &hec{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL, Token: "token", Host: "host", Index: "main", BatchSize: 2, Interval: time.Hour})
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 250000000, time.UTC)
+
+	w.WriteEntry(qlog.Entry{Time: ts, Severity: "INFO", Message: "first", Labels: []any{"k", "v", LabelSourceType, "payments"}}, nil)
+
+	if len(h.batches) != 0 {
+		t.Fatalf("expected no batch to be sent before it is full but got %v", len(h.batches))
+	}
+
+	w.WriteEntry(qlog.Entry{Time: ts, Severity: "ERROR", Message: "second", Error: errors.New("boom"), Labels: []any{LabelIndex, "errors"}}, nil)
+
+	expected := `{"time":1709296200.25,"host":"host","sourcetype":"payments","index":"main","event":{"k":"v","message":"first","severity":"INFO"}}` +
+		`{"time":1709296200.25,"host":"host","index":"errors","event":{"error":"boom","message":"second","severity":"ERROR"}}`
+
+	if len(h.batches) != 1 || h.batches[0] != expected {
+		t.Fatalf("expected batch '%v' but got '%v'", expected, h.batches)
+	}
+
+	w.WriteEntry(qlog.Entry{Time: ts, Severity: "FATAL", Flag: qlog.OutputFlagFatal, Message: "fatal"}, nil)
+
+	if len(h.batches) != 2 || !strings.Contains(h.batches[1], `"message":"fatal"`) {
+		t.Fatalf("expected a fatal log to be sent immediately but got '%v'", h.batches)
+	}
+}
+
+func TestWriterInterval(t *testing.T) {
+	h := &hec{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL, Token: "token", Interval: 10 * time.Millisecond})
+	w.Write([]byte("raw\n"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if len(h.batches) != 1 || !strings.Contains(h.batches[0], `"message":"raw"`) {
+		t.Fatalf("expected the batch to be sent once the interval elapsed but got '%v'", h.batches)
+	}
+}
+
+func TestWriterAck(t *testing.T) {
+	h := &hec{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL, Token: "token", Ack: true})
+	w.Write([]byte("log\n"))
+
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("expected the batch to be acknowledged but got '%v'", err)
+	}
+
+	if len(w.acks) != 0 || h.headers.Get("X-Splunk-Request-Channel") != w.config.Channel {
+		t.Fatalf("expected no pending acknowledgements on channel '%v' but got %v on '%v'", w.config.Channel, len(w.acks), h.headers.Get("X-Splunk-Request-Channel"))
+	}
+
+	h.acked = false
+	w.Write([]byte("log\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := w.Flush(ctx); !errors.Is(err, ErrNotAcknowledged) {
+		t.Fatalf("expected ErrNotAcknowledged but got '%v'", err)
+	}
+}
+
+func TestWriterUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(&hec{})
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL, Token: "wrong", BatchSize: 1})
+
+	if _, err := w.Write([]byte("log\n")); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected an unauthorized error but got '%v'", err)
+	}
+}
@@ -0,0 +1,133 @@
+package qlog
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type subscription struct {
+	outputMask OutputMask
+	ch         chan []byte
+}
+
+var (
+	subscriptionsMx = sync.RWMutex{}
+	subscriptions   = map[*subscription]struct{}{}
+	subscribed      = atomic.Int32{} // allows publish to return without acquiring a lock when there are no subscribers
+)
+
+// Subscribe registers to receive a copy of every log written, by any Log, whose severity is included in the outputMask.
+//
+// Logs are delivered on the returned channel which is buffered to the specified size. Should a subscriber not receive
+// logs as quickly as they are written, the logs that do not fit in the buffer are dropped rather than block the writer.
+//
+// The returned func cancels the subscription and closes the channel
+func Subscribe(outputMask OutputMask, size int) (<-chan []byte, func()) {
+	s := &subscription{outputMask: outputMask, ch: make(chan []byte, size)}
+
+	subscriptionsMx.Lock()
+	subscriptions[s] = struct{}{}
+	subscribed.Add(1)
+	subscriptionsMx.Unlock()
+
+	once := sync.Once{}
+
+	return s.ch, func() {
+		once.Do(func() {
+			subscriptionsMx.Lock()
+			delete(subscriptions, s)
+			subscribed.Add(-1)
+			close(s.ch)
+			subscriptionsMx.Unlock()
+		})
+	}
+}
+
+func publish(flag OutputMask, b []byte) {
+	if subscribed.Load() == 0 {
+		return
+	}
+
+	subscriptionsMx.RLock()
+	defer subscriptionsMx.RUnlock()
+
+	var cp []byte
+
+	for s := range subscriptions {
+		if s.outputMask&flag == 0 {
+			continue
+		}
+
+		if cp == nil {
+			cp = append([]byte(nil), b...)
+		}
+
+		select {
+		case s.ch <- cp:
+		default:
+		}
+	}
+}
+
+// StreamHandler returns a http.Handler that streams live logs to clients as server-sent events.
+// This provides a "live tail" of the process's logs that can be consumed directly by a browser based admin UI.
+//
+// The streamed logs can be filtered by passing an OutputMask, in the format accepted by ParseOutputMask, in the `mask`
+// query parameter; by default all logs are streamed.
+//
+// If authorize is not nil, it is called for each request. If it returns false, the request is rejected with a 403
+func StreamHandler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize != nil && !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		outputMask := OutputMaskAll | OutputFlagTrace | OutputFlagAudit
+
+		if m := r.URL.Query().Get("mask"); m != "" {
+			v, err := ParseOutputMask(m)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			outputMask = v
+		}
+
+		logs, cancel := Subscribe(outputMask, 100)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case b := <-logs:
+				// a message may contain line breaks, each line must carry its own data field to be a valid event
+				b = bytes.ReplaceAll(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"), []byte("\ndata: "))
+
+				if _, err := w.Write(append(append([]byte("data: "), b...), "\n\n"...)); err != nil {
+					return
+				}
+
+				flusher.Flush()
+			}
+		}
+	})
+}
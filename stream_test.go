@@ -0,0 +1,82 @@
+package qlog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	l := New(OutputMaskAll, false)
+	l.Writer = io.Discard
+
+	logs, cancel := Subscribe(OutputFlagError, 10)
+	ctx := ContextFrom(context.Background(), "")
+
+	l.Info(ctx, "info message")
+	l.Error(ctx, "error message", nil)
+
+	select {
+	case b := <-logs:
+		if !strings.Contains(string(b), `message="error message"`) {
+			t.Fatalf("expected error log but got '%s'", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected log to be published to subscriber")
+	}
+
+	cancel()
+
+	if _, ok := <-logs; ok {
+		t.Fatalf("expected channel to be closed on cancel")
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	l := New(OutputMaskAll, false)
+	l.Writer = io.Discard
+
+	svr := httptest.NewServer(StreamHandler(func(r *http.Request) bool { return r.Header.Get("Authorization") == "secret" }))
+	defer svr.Close()
+
+	if rs, err := http.Get(svr.URL); err != nil || rs.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected unauthorized request to be rejected")
+	}
+
+	rq, _ := http.NewRequest(http.MethodGet, svr.URL+"?mask=error", nil)
+	rq.Header.Set("Authorization", "secret")
+
+	rs, err := http.DefaultClient.Do(rq)
+
+	if err != nil || rs.StatusCode != http.StatusOK {
+		t.Fatalf("expected stream to be opened but got '%v'", err)
+	}
+
+	defer rs.Body.Close()
+
+	go func() {
+		for i := 0; i < 100 && subscribed.Load() == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		ctx := ContextFrom(context.Background(), "")
+		l.Info(ctx, "info message")
+		l.Error(ctx, "error\nmessage", nil)
+	}()
+
+	r := bufio.NewReader(rs.Body)
+	line, _ := r.ReadString('\n')
+
+	if !strings.HasPrefix(line, "data: ") || !strings.Contains(line, "severity=\"ERROR\"") {
+		t.Fatalf("expected error log event but got '%s'", line)
+	}
+
+	if line, _ = r.ReadString('\n'); line != "data: message\"\n" {
+		t.Fatalf("expected multi-line message to be split across data fields but got '%s'", line)
+	}
+}
@@ -0,0 +1,87 @@
+package qlog
+
+import (
+	"io"
+	"sync"
+)
+
+// Syncer is a Writer whose writes can be committed to stable storage, such as an *os.File
+type Syncer interface {
+	io.Writer
+	Sync() error
+}
+
+// SyncPolicy defines when a SyncWriter commits the logs written to stable storage
+type SyncPolicy struct {
+	// Mask is the severities of logs after which the Syncer is synced
+	Mask OutputMask
+	// Bytes, if greater than zero, is the number of bytes written, of any severity, after which the Syncer is synced
+	Bytes int
+}
+
+// DefaultSyncPolicy syncs after each Fatal, Error and Audit log, so that the most important logs survive a crash
+var DefaultSyncPolicy = SyncPolicy{Mask: OutputFlagFatal | OutputFlagError | OutputFlagAudit}
+
+// SyncWriter is an io.Writer that syncs its underlying Syncer, such as a file, according to a SyncPolicy. This ensures
+// that crash post-mortems are not missing the last, and typically most important, logs, whilst avoiding the cost of
+// syncing after every log.
+type SyncWriter struct {
+	w        Syncer
+	policy   SyncPolicy
+	mx       sync.Mutex
+	unsynced int
+}
+
+// NewSyncWriter creates a SyncWriter that writes to w and syncs it according to the policy
+//
+// For example:
+//
+//	qlog.SetWriter(qlog.NewSyncWriter(file, qlog.SyncPolicy{Mask: qlog.OutputFlagFatal | qlog.OutputFlagError, Bytes: 1 << 20}))
+func NewSyncWriter(w Syncer, policy SyncPolicy) *SyncWriter {
+	return &SyncWriter{w: w, policy: policy}
+}
+
+// Write writes b, which has no known severity, syncing the Syncer if the policy's Bytes have been written since the
+// last sync
+func (sw *SyncWriter) Write(b []byte) (int, error) {
+	return sw.WriteSeverity(OutputFlagNone, b)
+}
+
+// WriteSeverity writes b, syncing the Syncer if the severity flag is included in the policy's Mask, or the policy's
+// Bytes have been written since the last sync
+func (sw *SyncWriter) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	sw.mx.Lock()
+	defer sw.mx.Unlock()
+
+	n, err := sw.w.Write(b)
+	sw.unsynced += n
+
+	if err != nil {
+		return n, err
+	}
+
+	if sw.policy.Mask&flag != 0 || (sw.policy.Bytes > 0 && sw.unsynced >= sw.policy.Bytes) {
+		return n, sw.sync()
+	}
+
+	return n, nil
+}
+
+// Unwrap returns the underlying Syncer
+func (sw *SyncWriter) Unwrap() io.Writer {
+	return sw.w
+}
+
+// Sync commits the logs written so far to stable storage
+func (sw *SyncWriter) Sync() error {
+	sw.mx.Lock()
+	defer sw.mx.Unlock()
+
+	return sw.sync()
+}
+
+// sync syncs the Syncer, the caller must hold mx
+func (sw *SyncWriter) sync() error {
+	sw.unsynced = 0
+	return sw.w.Sync()
+}
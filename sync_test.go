@@ -0,0 +1,43 @@
+package qlog
+
+import (
+	"context"
+	"testing"
+)
+
+type countingSyncer struct {
+	countingWriter
+	syncs int
+}
+
+func (cs *countingSyncer) Sync() error {
+	cs.syncs++
+	return nil
+}
+
+func TestSyncWriter(t *testing.T) {
+	type testCase struct {
+		Policy   SyncPolicy
+		Expected int
+	}
+
+	for name, tc := range map[string]testCase{
+		"default":         {Policy: DefaultSyncPolicy, Expected: 1},
+		"every log":       {Policy: SyncPolicy{Mask: OutputMaskAll}, Expected: 3},
+		"bytes":           {Policy: SyncPolicy{Bytes: 1}, Expected: 3},
+		"bytes and error": {Policy: SyncPolicy{Mask: OutputFlagError, Bytes: 1 << 20}, Expected: 1},
+		"none":            {Expected: 0},
+	} {
+		cs := &countingSyncer{}
+
+		l := New(OutputMaskAll, false)
+		l.Writer = NewSyncWriter(cs, tc.Policy)
+		l.Info(context.Background(), "info")
+		l.Error(context.Background(), "error", nil)
+		l.Info(context.Background(), "info")
+
+		if cs.syncs != tc.Expected {
+			t.Fatalf("%v: expected %v syncs but got %v", name, tc.Expected, cs.syncs)
+		}
+	}
+}
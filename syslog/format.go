@@ -0,0 +1,58 @@
+package syslog
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Formatter is a qlog.EntryWriter that formats each log as an RFC5424 syslog message, on a line, and writes it to an
+// underlying Writer, such as a local file that must be in syslog format. The Network, Address, TLS and Timeout of
+// its Config are not used.
+//
+// For example:
+//
+//	f, err := os.OpenFile("/var/log/app.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+//	// ...
+//	qlog.SetWriter(syslog.NewFormatter(f, syslog.Config{Facility: syslog.FacilityLocal0}))
+type Formatter struct {
+	w      io.Writer
+	config Config
+	procID string
+	mx     sync.Mutex
+	b      []byte
+}
+
+// NewFormatter creates a Formatter that writes logs formatted as RFC5424 messages, as defined by the Config, to w
+func NewFormatter(w io.Writer, c Config) *Formatter {
+	return &Formatter{w: w, config: withDefaults(c), procID: strconv.Itoa(os.Getpid())}
+}
+
+// Write writes b, a log encoded by qlog, as the MSG of a notice
+func (f *Formatter) Write(b []byte) (int, error) {
+	return f.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e, formatted as an RFC5424 message
+func (f *Formatter) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.b = append(appendMessage(f.b[:0], f.config, f.procID, e), '\n')
+
+	if _, err := f.w.Write(f.b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// Unwrap returns the underlying Writer
+func (f *Formatter) Unwrap() io.Writer {
+	return f.w
+}
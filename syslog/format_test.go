@@ -0,0 +1,26 @@
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/comradequinn/qlog"
+)
+
+func TestFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	l := qlog.New(qlog.OutputMaskAll, true)
+	l.Writer = NewFormatter(buf, Config{Facility: FacilityLocal0, Hostname: "host", AppName: "app"})
+
+	l.Info(context.Background(), "started", "port", 8080)
+	l.Error(context.Background(), "failed", nil)
+
+	expected := regexp.MustCompile(`^<134>1 \S+ host app \d+ - \[qlog@32473 port="8080"\] started\n<131>1 \S+ host app \d+ - \[qlog@32473\] failed\n$`)
+
+	if !expected.Match(buf.Bytes()) {
+		t.Fatalf("expected messages matching '%v' but got '%v'", expected, buf.String())
+	}
+}
@@ -0,0 +1,358 @@
+// Package syslog provides a qlog sink that writes logs as RFC5424 syslog messages to a syslog server over UDP, TCP,
+// TLS or a unix socket, for environments, such as appliances, that can only ship logs via syslog.
+//
+// Each qlog severity is mapped to a syslog severity and the labels of each log are written as structured data. For
+// example:
+//
+//	w := syslog.New(syslog.Config{Network: "tcp", Address: "logs.example.com:6514", TLS: &tls.Config{}, AppName: "checkout"})
+//	defer w.Close()
+//
+//	qlog.SetWriter(w)
+//
+// Where logs must be in syslog format, but are not sent to a syslog server, such as when written to a local file, a
+// Formatter formats them as RFC5424 messages for any io.Writer.
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/comradequinn/qlog"
+)
+
+// Facility is a syslog facility, identifying the type of program writing the message
+type Facility int
+
+// Syslog facilities, as defined by RFC5424. The kernel facility is reserved for the kernel so is not included
+const (
+	FacilityUser Facility = iota + 1
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityLocal0 Facility = iota + 5
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Syslog severities, as defined by RFC5424
+const (
+	severityEmergency = iota
+	severityAlert
+	severityCritical
+	severityError
+	severityWarning
+	severityNotice
+	severityInformational
+	severityDebug
+)
+
+// severities maps the names of qlog severities to syslog severities
+var severities = map[string]int{
+	"FATAL":   severityCritical,
+	"ERROR":   severityError,
+	"WARNING": severityWarning,
+	"NOTICE":  severityNotice,
+	"AUDIT":   severityNotice,
+	"INFO":    severityInformational,
+	"DEBUG":   severityDebug,
+	"TRACE":   severityDebug,
+}
+
+// Severity returns the syslog severity, from 0, emergency, to 7, debug, of the qlog severity with the specified name.
+// Unrecognised severities, such as custom severities, are notices
+func Severity(name string) int {
+	if severity, ok := severities[name]; ok {
+		return severity
+	}
+
+	return severityNotice
+}
+
+// DefaultSDID is the SD-ID of the structured data element that holds the labels of each log
+const DefaultSDID = "qlog@32473"
+
+// Config configures a syslog Writer or Formatter
+type Config struct {
+	// Network is the network of the syslog server; one of "udp", "tcp", "unix" or "unixgram"
+	Network string
+	// Address is the address of the syslog server, such as "logs.example.com:514" or "/dev/log"
+	Address string
+	// TLS, if not nil, secures connections to the syslog server, which must be over "tcp"
+	TLS *tls.Config
+	// Facility is the facility of each message, by default FacilityUser
+	Facility Facility
+	// Hostname is the HOSTNAME of each message, by default the hostname reported by the operating system
+	Hostname string
+	// AppName is the APP-NAME of each message, by default the name of the executable
+	AppName string
+	// SDID is the SD-ID of the structured data element that holds the labels of each log, by default DefaultSDID
+	SDID string
+	// Timeout limits the time taken to connect and write each message, by default 5s
+	Timeout time.Duration
+}
+
+// Writer is a qlog.EntryWriter that writes logs as RFC5424 syslog messages to a syslog server.
+//
+// Messages are written one per datagram over "udp" and "unixgram", and with octet counting framing, as defined by
+// RFC6587, over "tcp" and "unix". Should a write fail, the Writer reconnects and retries it once.
+type Writer struct {
+	config Config
+	procID string
+	mx     sync.Mutex
+	conn   net.Conn
+	closed chan struct{} // closed when the server closes conn, if it is a stream
+	b      []byte
+}
+
+// New creates a Writer with the specified Config. The connection to the syslog server is made by the first write
+func New(c Config) *Writer {
+	return &Writer{config: withDefaults(c), procID: strconv.Itoa(os.Getpid())}
+}
+
+// Write writes b, a log encoded by qlog, as the MSG of a notice
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "NOTICE", Message: strings.TrimSuffix(string(b), "\n")}, b)
+}
+
+// WriteEntry writes the log described by e as a syslog message
+func (w *Writer) WriteEntry(e qlog.Entry, b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.b = appendMessage(w.b[:0], w.config, w.procID, e)
+
+	if err := w.send(); err != nil {
+		w.close()
+
+		if err = w.send(); err != nil { // reconnect and retry once, as the server may have closed an idle connection
+			w.close()
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Close closes the connection to the syslog server
+func (w *Writer) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.close()
+}
+
+// send writes the message in b, connecting to the syslog server if required, the caller must hold mx
+func (w *Writer) send() error {
+	stream := w.config.Network == "tcp" || w.config.Network == "unix"
+
+	if stream && w.conn != nil {
+		select {
+		case <-w.closed:
+			w.close()
+		default:
+		}
+	}
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	msg := w.b
+
+	if stream { // octet counting framing, RFC6587
+		msg = append(strconv.AppendInt(make([]byte, 0, len(w.b)+8), int64(len(w.b)), 10), ' ')
+		msg = append(msg, w.b...)
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.config.Timeout))
+
+	_, err := w.conn.Write(msg)
+
+	return err
+}
+
+// connect connects to the syslog server, the caller must hold mx
+func (w *Writer) connect() error {
+	d := &net.Dialer{Timeout: w.config.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if w.config.TLS != nil {
+		if w.config.Network != "tcp" {
+			return errors.New("syslog: tls requires the tcp network")
+		}
+
+		conn, err = tls.DialWithDialer(d, "tcp", w.config.Address, w.config.TLS)
+	} else {
+		conn, err = d.Dial(w.config.Network, w.config.Address)
+	}
+
+	if err != nil {
+		return fmt.Errorf("syslog: unable to connect to %v: %w", w.config.Address, err)
+	}
+
+	w.conn, w.closed = conn, make(chan struct{})
+
+	if w.config.Network == "tcp" || w.config.Network == "unix" {
+		go watch(conn, w.closed)
+	}
+
+	return nil
+}
+
+// watch closes the closed chan when conn is closed. Syslog servers do not write to clients, so a read only returns when
+// the connection is closed; without this the first write after the server closes an idle connection appears to succeed
+// but is lost
+func watch(conn net.Conn, closed chan struct{}) {
+	io.Copy(io.Discard, conn)
+	close(closed)
+}
+
+// close closes the connection to the syslog server, if there is one, the caller must hold mx
+func (w *Writer) close() error {
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	return err
+}
+
+// withDefaults returns c with defaults assigned to any fields that are not set
+func withDefaults(c Config) Config {
+	if c.Hostname == "" {
+		c.Hostname, _ = os.Hostname()
+	}
+
+	if c.AppName == "" {
+		c.AppName = "-"
+
+		if exe, err := os.Executable(); err == nil {
+			c.AppName = exe[strings.LastIndexAny(exe, `/\`)+1:]
+		}
+	}
+
+	if c.SDID == "" {
+		c.SDID = DefaultSDID
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	if c.Facility == 0 {
+		c.Facility = FacilityUser
+	}
+
+	return c
+}
+
+// appendMessage appends the RFC5424 message for the log described by e to b
+func appendMessage(b []byte, c Config, procID string, e qlog.Entry) []byte {
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(int(c.Facility)*8+Severity(e.Severity)), 10)
+	b = append(b, ">1 "...)
+	b = e.Time.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, ' ')
+	b = appendHeaderField(b, c.Hostname, 255)
+	b = append(b, ' ')
+	b = appendHeaderField(b, c.AppName, 48)
+	b = append(b, ' ')
+	b = appendHeaderField(b, procID, 128)
+	b = append(b, " - ["...)
+	b = append(b, c.SDID...)
+
+	if e.TraceID != "" {
+		b = appendParam(b, "trace", e.TraceID)
+	}
+
+	if e.Error != nil {
+		b = appendParam(b, "error", e.Error.Error())
+	}
+
+	labels := e.AllLabels()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		b = appendParam(b, fmt.Sprint(labels[i]), fmt.Sprint(labels[i+1]))
+	}
+
+	b = append(b, "] "...)
+
+	return append(b, e.Message...)
+}
+
+// appendHeaderField appends a header field of at most max printable ASCII characters to b, or the nil value if it is empty
+func appendHeaderField(b []byte, v string, max int) []byte {
+	if v == "" {
+		return append(b, '-')
+	}
+
+	for i := 0; i < len(v) && i < max; i++ {
+		if v[i] < 33 || v[i] > 126 {
+			b = append(b, '_')
+			continue
+		}
+
+		b = append(b, v[i])
+	}
+
+	return b
+}
+
+// appendParam appends a structured data parameter to b. Characters not permitted in a PARAM-NAME are replaced and
+// those requiring it in a PARAM-VALUE are escaped
+func appendParam(b []byte, name, value string) []byte {
+	b = append(b, ' ')
+
+	for i := 0; i < len(name) && i < 32; i++ {
+		if c := name[i]; c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			b = append(b, '_')
+			continue
+		}
+
+		b = append(b, name[i])
+	}
+
+	b = append(b, `="`...)
+
+	for _, r := range value {
+		switch r {
+		case '"', '\\', ']':
+			b = append(b, '\\')
+		case utf8.RuneError:
+			r = '?'
+		}
+
+		b = utf8.AppendRune(b, r)
+	}
+
+	return append(b, '"')
+}
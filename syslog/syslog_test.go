@@ -0,0 +1,138 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/qlog"
+)
+
+func TestAppendMessage(t *testing.T) {
+	type testCase struct {
+		Entry    qlog.Entry
+		Expected string
+	}
+
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	c := Config{Facility: FacilityLocal0, Hostname: "host", AppName: "app", SDID: DefaultSDID}
+
+	for name, tc := range map[string]testCase{
+		"error": {
+			Entry:    qlog.Entry{Time: ts, Severity: "ERROR", TraceID: "abc", Message: "failed", Error: errors.New("boom"), Labels: []any{"k", "v"}},
+			Expected: `<131>1 2024-03-01T12:30:00.123456Z host app 42 - [qlog@32473 trace="abc" error="boom" k="v"] failed`,
+		},
+		"info": {
+			Entry:    qlog.Entry{Time: ts, Severity: "INFO", Message: "started"},
+			Expected: `<134>1 2024-03-01T12:30:00.123456Z host app 42 - [qlog@32473] started`,
+		},
+		"custom severity": {
+			Entry:    qlog.Entry{Time: ts, Severity: "SECURITY", Message: "m"},
+			Expected: `<133>1 2024-03-01T12:30:00.123456Z host app 42 - [qlog@32473] m`,
+		},
+		"escaping": {
+			Entry:    qlog.Entry{Time: ts, Severity: "WARNING", Message: "m", Labels: []any{`bad key=]"`, `a"b\c]d`}},
+			Expected: `<132>1 2024-03-01T12:30:00.123456Z host app 42 - [qlog@32473 bad_key___="a\"b\\c\]d"] m`,
+		},
+	} {
+		if actual := string(appendMessage(nil, c, "42", tc.Entry)); actual != tc.Expected {
+			t.Fatalf("%v: expected '%v' but got '%v'", name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	defer conn.Close()
+
+	w := New(Config{Network: "udp", Address: conn.LocalAddr().String(), AppName: "app"})
+	defer w.Close()
+
+	l := qlog.New(qlog.OutputMaskAll, false)
+	l.Writer = w
+	l = l.WithLabels("service", "checkout")
+
+	l.Warning(context.Background(), "low stock", nil, "sku", "123")
+
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(b)
+
+	if err != nil {
+		t.Fatalf("expected a datagram but got '%v'", err)
+	}
+
+	if msg, expected := string(b[:n]), regexp.MustCompile(`^<12>1 \S+ \S+ app \d+ - \[qlog@32473 service="checkout" sku="123"\] low stock$`); !expected.MatchString(msg) {
+		t.Fatalf("expected message matching '%v' but got '%v'", expected, msg)
+	}
+}
+
+func TestWriterTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("expected no error listening but got '%v'", err)
+	}
+
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			length, err := r.ReadString(' ')
+
+			if err != nil {
+				conn.Close()
+				continue
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			b := make([]byte, n)
+			r.Read(b)
+			msgs <- string(b)
+
+			conn.Close() // close after each message, forcing the writer to reconnect
+		}
+	}()
+
+	w := New(Config{Network: "tcp", Address: ln.Addr().String()})
+	defer w.Close()
+
+	for _, msg := range []string{"first", "second"} {
+		w.WriteEntry(qlog.Entry{Time: time.Now(), Severity: "INFO", Message: msg}, nil)
+
+		if msg == "first" {
+			time.Sleep(20 * time.Millisecond) // allow the server to close the connection
+		}
+	}
+
+	for _, expected := range []string{"] first", "] second"} {
+		select {
+		case msg := <-msgs:
+			if !strings.HasSuffix(msg, expected) {
+				t.Fatalf("expected message ending '%v' but got '%v'", expected, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected message ending '%v' but got none", expected)
+		}
+	}
+}
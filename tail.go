@@ -0,0 +1,160 @@
+package qlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Tail-based buffering configuration
+//
+// These are intended for configuration during start-up. They are not safe for concurrent use.
+var (
+	// TailMask is the set of severities of log that are held, rather than written, for traces that are buffered with
+	// BufferTrace.
+	//
+	// By default it is the verbose severities Info, Debug and Trace
+	TailMask = OutputFlagInfo | OutputFlagDebug | OutputFlagTrace
+	// TailFlushMask is the set of severities of log that cause the logs held for their trace to be written before them.
+	//
+	// By default it is Error and Fatal
+	TailFlushMask = OutputFlagError | OutputFlagFatal
+)
+
+// tailLog is a log held for a buffered trace, or as crash context, along with what is needed to write it later
+type tailLog struct {
+	l      *Log
+	flag   OutputMask
+	e      Entry
+	b      []byte
+	hooked bool
+}
+
+// tailBuffer is a ring of the most recent logs held for a trace, or as crash context
+type tailBuffer struct {
+	logs  []tailLog
+	start int
+	n     int
+}
+
+var (
+	tailsMx = sync.Mutex{}
+	tails   = map[string]*tailBuffer{}
+	tailing = atomic.Int32{} // allows tail to return without acquiring a lock when no trace is buffered
+)
+
+// BufferTrace holds the logs of the trace of ctx with a severity in the TailMask, rather than writing them, until end is
+// called. Should a log with a severity in the TailFlushMask be written for the trace, the held logs are written before
+// it; otherwise they are discarded when end is called. This provides the full detail of the traces that fail, while
+// writing only a fraction of the volume of those that succeed.
+//
+// Up to size logs are held, beyond which the oldest are discarded. If ctx has no Trace-ID, the returned context.Context
+// has a new one, so the logs of the trace must be written with it, or one derived from it.
+//
+// For example:
+//
+//	ctx, end := qlog.BufferTrace(ctx, 100)
+//	defer end()
+//
+//	qlog.Debug(ctx, "cache miss", "key", key) // only written should an error be logged for the trace
+func BufferTrace(ctx context.Context, size int) (context.Context, func()) {
+	if TraceID(ctx) == "" {
+		ctx = ContextFrom(ctx, "")
+	}
+
+	id := TraceID(ctx)
+
+	tailsMx.Lock()
+	defer tailsMx.Unlock()
+
+	if _, ok := tails[id]; ok || size <= 0 { // the trace is already buffered by a caller, which is responsible for ending it
+		return ctx, func() {}
+	}
+
+	tails[id] = &tailBuffer{logs: make([]tailLog, size)}
+	tailing.Add(1)
+
+	once := sync.Once{}
+
+	return ctx, func() {
+		once.Do(func() {
+			tailsMx.Lock()
+			delete(tails, id)
+			tailing.Add(-1)
+			tailsMx.Unlock()
+		})
+	}
+}
+
+// tail holds the log described by e, and encoded as b, if its severity is in the TailMask and its trace is buffered,
+// reporting whether it was held
+func tail(l *Log, flag OutputMask, e Entry, b []byte, hooked bool) bool {
+	if flag&TailMask == 0 || tailing.Load() == 0 {
+		return false
+	}
+
+	tailsMx.Lock()
+	defer tailsMx.Unlock()
+
+	tb, ok := tails[e.TraceID]
+
+	if !ok {
+		return false
+	}
+
+	tb.push(tailLog{l: l, flag: flag, e: e, b: append([]byte(nil), b...), hooked: hooked}) // b is reused once written
+
+	return true
+}
+
+// flushTail writes the logs held for the trace with the specified ID, if it is buffered, in the order they were held
+func flushTail(id string) {
+	if tailing.Load() == 0 {
+		return
+	}
+
+	tailsMx.Lock()
+
+	tb, ok := tails[id]
+
+	if !ok {
+		tailsMx.Unlock()
+		return
+	}
+
+	logs := tb.drain()
+
+	tailsMx.Unlock()
+
+	for _, t := range logs {
+		t.l.emit(t.flag, t.e, t.b, t.hooked)
+	}
+}
+
+// push adds t to the ring, discarding the oldest log if it is full
+func (tb *tailBuffer) push(t tailLog) {
+	i := (tb.start + tb.n) % len(tb.logs)
+
+	if tb.n == len(tb.logs) {
+		tb.start = (tb.start + 1) % len(tb.logs)
+	} else {
+		tb.n++
+	}
+
+	tb.logs[i] = t
+}
+
+// drain removes the logs from the ring, returning them in the order they were added
+func (tb *tailBuffer) drain() []tailLog {
+	logs := make([]tailLog, 0, tb.n)
+
+	for i := 0; i < tb.n; i++ {
+		j := (tb.start + i) % len(tb.logs)
+		logs = append(logs, tb.logs[j])
+		tb.logs[j] = tailLog{}
+	}
+
+	tb.start, tb.n = 0, 0
+
+	return logs
+}
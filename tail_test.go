@@ -0,0 +1,52 @@
+package qlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBufferTrace(t *testing.T) {
+	if !DebugEnabled {
+		t.Skip("debug logs are compiled out by the qlog_nodebug build tag")
+	}
+
+	sb := strings.Builder{}
+	l := New(OutputMaskAll, false).WithWriter(&sb)
+
+	failed, endFailed := BufferTrace(context.Background(), 2)
+	succeeded, endSucceeded := BufferTrace(context.Background(), 2)
+
+	if TraceID(failed) == "" || TraceID(failed) == TraceID(succeeded) {
+		t.Fatalf("expected each buffered trace to have its own Trace-ID but got '%v' and '%v'", TraceID(failed), TraceID(succeeded))
+	}
+
+	for _, ctx := range []context.Context{failed, succeeded} {
+		l.Debug(ctx, "discarded")
+		l.Debug(ctx, "first")
+		l.Info(ContextFrom(ctx, ""), "second")
+		l.Warning(ctx, "warned", nil)
+	}
+
+	if output := sb.String(); strings.Count(output, "\n") != 2 || strings.Count(output, "warned") != 2 {
+		t.Fatalf("expected only the warnings to be written before an error but got '%v'", output)
+	}
+
+	l.Error(failed, "failed", nil)
+	endFailed()
+	endSucceeded()
+
+	l.Info(failed, "ended")
+
+	logs := strings.Split(strings.TrimSpace(sb.String()), "\n")
+
+	for i, expected := range []string{"warned", "warned", "first", "second", "failed", "ended"} {
+		if i >= len(logs) || !strings.Contains(logs[i], `message="`+expected+`"`) {
+			t.Fatalf("expected log %v to be '%v' but got '%v'", i, expected, sb.String())
+		}
+	}
+
+	if len(logs) != 6 || !strings.Contains(logs[2], TraceID(failed)) {
+		t.Fatalf("expected only the held logs of the failed trace to be written but got '%v'", sb.String())
+	}
+}
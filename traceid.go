@@ -0,0 +1,224 @@
+package qlog
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	idMx          = sync.Mutex{}
+	idSrc         = rand.New(rand.NewSource(seed()))
+	secureIDs     = atomic.Bool{}
+	traceIDPolicy = atomic.Int32{}
+)
+
+// MaxTraceIDLength is the maximum length of a Trace-ID passed to ContextFrom
+const MaxTraceIDLength = 128
+
+// InvalidTraceIDLabel is the key of the label holding the sanitised form of an invalid Trace-ID replaced under the
+// TraceIDReplace policy
+const InvalidTraceIDLabel = "invalid_trace_id"
+
+// TraceIDPolicy defines how ContextFrom handles an invalid Trace-ID; one that is longer than MaxTraceIDLength or that
+// contains characters other than letters, digits, `-`, `_`, `.` and `:`. Trace-IDs are often read from headers, so
+// writing them unaltered would allow a crafted ID to inject content, such as forged fields, into logs
+type TraceIDPolicy int
+
+// Supported TraceIDPolicies
+const (
+	// TraceIDSanitise removes the characters that are not permitted and truncates the Trace-ID to MaxTraceIDLength.
+	// Should nothing remain, the Trace-ID of the context, or a new one, is used
+	TraceIDSanitise TraceIDPolicy = iota
+	// TraceIDReplace replaces the Trace-ID with that of the context, or a new one, and labels the logs of the returned
+	// context with an InvalidTraceIDLabel of the sanitised Trace-ID, so that the replacement is evident
+	TraceIDReplace
+)
+
+// SetTraceIDPolicy sets how ContextFrom handles invalid Trace-IDs, by default TraceIDSanitise.
+// This operation is safe for concurrent use.
+func SetTraceIDPolicy(p TraceIDPolicy) {
+	traceIDPolicy.Store(int32(p))
+}
+
+// traceIDGenerator wraps a Trace-ID generator so that it can be stored in an atomic.Pointer
+type traceIDGenerator struct {
+	generate func() string
+}
+
+var traceIDGen = atomic.Pointer[traceIDGenerator]{}
+
+// SetTraceIDGenerator sets the func that generates the Trace-IDs of new traces, such as UUIDv4, UUIDv7, ULID or
+// NewXRayTraceID, to align them with the conventions of other services or tooling. Pass nil to restore the default,
+// which generates 32 hex character IDs in the format of W3C trace context.
+//
+// Trace-IDs that are not hex, such as ULIDs, cannot be propagated with W3C trace context or B3 headers.
+// This operation is safe for concurrent use.
+func SetTraceIDGenerator(g func() string) {
+	if g == nil {
+		traceIDGen.Store(nil)
+		return
+	}
+
+	traceIDGen.Store(&traceIDGenerator{generate: g})
+}
+
+// UUIDv4 generates a random Trace-ID in the format of a version 4 UUID, as defined by RFC9562, such as
+// `4bf92f35-77b3-4da6-a3ce-929d0e0e4736`
+func UUIDv4() string {
+	b := [16]byte{}
+	randomBytes(b[:])
+
+	return uuid(b, 4)
+}
+
+// UUIDv7 generates a Trace-ID in the format of a version 7 UUID, as defined by RFC9562, such as
+// `01920cb6-4f6c-7d2a-9b3e-0c6a3f1e5d47`. Its first 48 bits are the current time in milliseconds, so Trace-IDs sort
+// in the order they were generated
+func UUIDv7() string {
+	b := [16]byte{}
+	randomBytes(b[6:])
+	putMillis(b[:6], timeNow())
+
+	return uuid(b, 7)
+}
+
+// ULID generates a Trace-ID in the format of a ULID, such as `01J4G6V1MZ8K3Q2W5E7R9T0Y1U`, 26 Crockford base32
+// characters whose first 48 bits are the current time in milliseconds, so Trace-IDs sort in the order they were generated
+func ULID() string {
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	b := [16]byte{}
+	randomBytes(b[6:])
+	putMillis(b[:6], timeNow())
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	id := [26]byte{}
+
+	for i := len(id) - 1; i >= 0; i-- { // encode the 128 bits, 5 at a time, from the least significant
+		id[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(id[:])
+}
+
+// newTraceID generates the Trace-ID of a new trace with the generator set by SetTraceIDGenerator
+func newTraceID() string {
+	if g := traceIDGen.Load(); g != nil {
+		return g.generate()
+	}
+
+	return randomHex(16)
+}
+
+// newSpanID generates a Span-ID in the format of W3C trace context, so that it can be propagated in traceparent headers
+func newSpanID() string {
+	return randomHex(8)
+}
+
+// validTraceID returns whether id is no longer than MaxTraceIDLength and contains only permitted characters
+func validTraceID(id string) bool {
+	if len(id) > MaxTraceIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if !traceIDChar(id[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// invalidTraceID returns the context and Trace-ID with which to create a context from an invalid Trace-ID, according to
+// the TraceIDPolicy. An empty Trace-ID is returned where the Trace-ID of ctx, or a new one, is to be used
+func invalidTraceID(ctx context.Context, id string) (context.Context, string) {
+	sanitised := make([]byte, 0, len(id))
+
+	for i := 0; i < len(id) && len(sanitised) < MaxTraceIDLength; i++ {
+		if traceIDChar(id[i]) {
+			sanitised = append(sanitised, id[i])
+		}
+	}
+
+	if TraceIDPolicy(traceIDPolicy.Load()) == TraceIDReplace {
+		return ContextWithLabels(ctx, InvalidTraceIDLabel, string(sanitised)), ""
+	}
+
+	return ctx, string(sanitised)
+}
+
+// traceIDChar returns whether c is permitted in a Trace-ID
+func traceIDChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':'
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	randomBytes(b)
+
+	return hex.EncodeToString(b)
+}
+
+// SetSecureIDs sets whether the random bits of Trace-IDs and Span-IDs, including those of UUIDv4, UUIDv7, ULID and
+// NewXRayTraceID, are read from crypto/rand, rather than math/rand. The default Trace-IDs then have 128 bits of entropy
+// that cannot be predicted from other IDs, so parties that receive them in headers cannot guess, or force collisions
+// with, the IDs of other traces. This is slower, so is disabled by default.
+// This operation is safe for concurrent use.
+func SetSecureIDs(v bool) {
+	secureIDs.Store(v)
+}
+
+// randomBytes fills b with random bytes
+func randomBytes(b []byte) {
+	if secureIDs.Load() {
+		if _, err := crand.Read(b); err == nil {
+			return
+		}
+	}
+
+	idMx.Lock()
+	idSrc.Read(b)
+	idMx.Unlock()
+}
+
+// seed returns a seed for the source of random IDs. It is read from crypto/rand, rather than the time, so that
+// processes started in the same nanosecond do not generate the same IDs
+func seed() int64 {
+	b := [8]byte{}
+
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+
+	return int64(binary.BigEndian.Uint64(b[:]))
+}
+
+// putMillis writes the milliseconds since the unix epoch of t to the 48 bits of b
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// uuid formats b as a UUID of the specified version, setting its version and variant bits
+func uuid(b [16]byte, version byte) string {
+	b[6] = b[6]&0x0f | version<<4
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,96 @@
+package qlog
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceIDGenerators(t *testing.T) {
+	timeNow = func() time.Time { return time.UnixMilli(1465510280000) }
+	defer func() { timeNow = time.Now }()
+
+	for name, test := range map[string]struct {
+		Generate func() string
+		Pattern  string
+	}{
+		"uuidv4": {Generate: UUIDv4, Pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		"uuidv7": {Generate: UUIDv7, Pattern: `^01553738-3b40-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		"ulid":   {Generate: ULID, Pattern: `^01AMVKGET0[0-9A-HJKMNP-TV-Z]{16}$`},
+		"x-ray":  {Generate: NewXRayTraceID, Pattern: `^1-5759e988-[0-9a-f]{24}$`},
+	} {
+		SetTraceIDGenerator(test.Generate)
+
+		first, second := TraceID(ContextFrom(context.Background(), "")), TraceID(ContextFrom(context.Background(), ""))
+
+		if !regexp.MustCompile(test.Pattern).MatchString(first) || first == second {
+			t.Fatalf("%v: expected unique trace ids matching '%v' but got '%v' and '%v'", name, test.Pattern, first, second)
+		}
+	}
+
+	SetTraceIDGenerator(nil)
+
+	if id := TraceID(ContextFrom(context.Background(), "")); !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id) {
+		t.Fatalf("expected the default generator to be restored but got '%v'", id)
+	}
+}
+
+func TestSetSecureIDs(t *testing.T) {
+	SetSecureIDs(true)
+	defer SetSecureIDs(false)
+
+	idMx.Lock() // math/rand cannot be used while its lock is held, so any ids generated must be read from crypto/rand
+	defer idMx.Unlock()
+
+	ctx := ContextFrom(context.Background(), "")
+
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(TraceID(ctx)) || !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(SpanID(ctx)) {
+		t.Fatalf("expected secure trace and span ids but got '%v' and '%v'", TraceID(ctx), SpanID(ctx))
+	}
+}
+
+func TestTraceIDPolicy(t *testing.T) {
+	defer SetTraceIDPolicy(TraceIDSanitise)
+
+	injected := `abc" severity="FATAL` + "\n" + `x`
+
+	for policy, expected := range map[TraceIDPolicy]struct {
+		TraceID string
+		Label   string
+	}{
+		TraceIDSanitise: {TraceID: "abcseverityFATALx"},
+		TraceIDReplace:  {Label: `invalid_trace_id="abcseverityFATALx"`},
+	} {
+		SetTraceIDPolicy(policy)
+
+		w := &strings.Builder{}
+		l := New(OutputMaskAll, false).WithWriter(w)
+		ctx := ContextFrom(context.Background(), injected)
+
+		l.Info(ctx, "sanitised")
+
+		if expected.TraceID != "" && TraceID(ctx) != expected.TraceID {
+			t.Fatalf("%v: expected trace id '%v' but got '%v'", policy, expected.TraceID, TraceID(ctx))
+		}
+
+		if expected.TraceID == "" && !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(TraceID(ctx)) {
+			t.Fatalf("%v: expected a new trace id but got '%v'", policy, TraceID(ctx))
+		}
+
+		if !strings.Contains(w.String(), expected.Label) || strings.Count(w.String(), "\n") != 1 || strings.Contains(w.String(), "FATAL\"") {
+			t.Fatalf("%v: expected a single, sanitised, log containing '%v' but got '%v'", policy, expected.Label, w.String())
+		}
+	}
+
+	SetTraceIDPolicy(TraceIDSanitise)
+
+	if id := TraceID(ContextFrom(context.Background(), strings.Repeat("a", MaxTraceIDLength+1))); len(id) != MaxTraceIDLength {
+		t.Fatalf("expected a long trace id to be truncated to %v characters but got %v", MaxTraceIDLength, len(id))
+	}
+
+	if id := TraceID(ContextFrom(context.Background(), "1-5759e988-bd862e3fe1be46a994272793")); id != "1-5759e988-bd862e3fe1be46a994272793" {
+		t.Fatalf("expected a valid trace id to be unaltered but got '%v'", id)
+	}
+}
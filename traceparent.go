@@ -0,0 +1,142 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseTraceparent parses a W3C trace context traceparent header, such as
+// `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`, returning the Trace-ID, the Span-ID of the remote parent
+// and whether the remote parent sampled the trace.
+//
+// Headers of future versions are parsed as far as the fields defined by version 00, as required by the specification
+func ParseTraceparent(header string) (traceID, parentSpanID string, sampled bool, err error) {
+	h := strings.TrimSpace(header)
+
+	if len(h) < 55 || h[2] != '-' || h[35] != '-' || h[52] != '-' || (len(h) > 55 && h[55] != '-') {
+		return "", "", false, fmt.Errorf("invalid traceparent: '%v' is not in the format version-traceid-parentid-flags", header)
+	}
+
+	version, traceID, parentSpanID, flags := h[:2], h[3:35], h[36:52], h[53:55]
+
+	switch {
+	case !isLowerHex(version) || version == "ff":
+		return "", "", false, fmt.Errorf("invalid traceparent: unsupported version '%v'", version)
+	case version == "00" && len(h) != 55:
+		return "", "", false, fmt.Errorf("invalid traceparent: version 00 header '%v' has trailing data", header)
+	case !isLowerHex(traceID) || isZeros(traceID):
+		return "", "", false, fmt.Errorf("invalid traceparent: invalid trace-id '%v'", traceID)
+	case !isLowerHex(parentSpanID) || isZeros(parentSpanID):
+		return "", "", false, fmt.Errorf("invalid traceparent: invalid parent-id '%v'", parentSpanID)
+	case !isLowerHex(flags):
+		return "", "", false, fmt.Errorf("invalid traceparent: invalid trace-flags '%v'", flags)
+	}
+
+	f, _ := strconv.ParseUint(flags, 16, 8)
+
+	return traceID, parentSpanID, f&1 == 1, nil // the sampled flag is the least significant bit
+}
+
+// ContextFromTraceparent creates a new context.Context in the trace described by the W3C trace context traceparent and
+// tracestate headers, with a new, unique, Span-ID whose parent is the remote span that sent them. This allows logs to be
+// correlated with those of upstream services and the traces of any tracing tooling that speaks W3C trace context.
+//
+// Should traceparent be missing or invalid, the specification requires that the trace is restarted, so tracestate is
+// discarded and the result is that of ContextFrom(ctx, "")
+func ContextFromTraceparent(ctx context.Context, traceparent, tracestate string) context.Context {
+	if ctx == nil {
+		panic("nil context passed to context-from-traceparent")
+	}
+
+	traceID, parentSpanID, sampled, err := ParseTraceparent(traceparent)
+
+	if err != nil {
+		return ContextFrom(ctx, "")
+	}
+
+	return contextFromRemote(ctx, traceID, parentSpanID, sampled, strings.TrimSpace(tracestate))
+}
+
+// contextFromRemote creates a new context.Context in the specified trace, with a new, unique, Span-ID whose parent is
+// the remote span that propagated the trace
+func contextFromRemote(ctx context.Context, traceID, parentSpanID string, sampled bool, tracestate string) context.Context {
+	if !sampled { // the decision of the remote parent is carried as that of Sample, so its verbose logs are not written
+		ctx = WithSampled(ctx, false)
+	}
+
+	return context.WithValue(ctx, traceIDKey, spanContext{
+		traceID:      traceID,
+		spanID:       newSpanID(),
+		parentSpanID: parentSpanID,
+		tracestate:   tracestate,
+	})
+}
+
+// Traceparent returns the W3C trace context traceparent header for the span associated with the passed ctx, so that it
+// can be propagated to downstream services, such as in the header of a HTTP request. It returns an empty string where
+// ctx has no trace, or where the Trace-ID or Span-ID cannot be represented in a traceparent header.
+//
+// The Trace-ID and Span-ID are read with TraceID and SpanID. Hex IDs shorter than those of W3C trace context are
+// left-padded with zeros and any dashes, such as those of UUIDs, are removed. AWS X-Ray Trace-IDs are converted as X-Ray
+// does, so `1-5759e988-bd862e3fe1be46a994272793` becomes `5759e988bd862e3fe1be46a994272793`.
+func Traceparent(ctx context.Context) string {
+	traceID, ok := traceContextID(TraceID(ctx), 32)
+
+	if !ok {
+		return ""
+	}
+
+	spanID, ok := traceContextID(SpanID(ctx), 16)
+
+	if !ok {
+		return ""
+	}
+
+	flags := "01"
+
+	if unsampled(ctx) {
+		flags = "00"
+	}
+
+	return "00-" + traceID + "-" + spanID + "-" + flags
+}
+
+// Tracestate returns the W3C trace context tracestate header received with the traceparent header of the trace
+// associated with the passed ctx, so that it can be propagated, unaltered, alongside that returned by Traceparent
+func Tracestate(ctx context.Context) string {
+	return currentSpan(ctx).tracestate
+}
+
+// traceContextID returns id as a lower case hex ID of length n, if it can be represented as one. AWS X-Ray Trace-IDs
+// are converted in the same manner as X-Ray, by concatenating their time and random fields
+func traceContextID(id string, n int) (string, bool) {
+	if isXRayTraceID(id) {
+		id = id[2:]
+	}
+
+	id = strings.ToLower(strings.ReplaceAll(id, "-", ""))
+
+	if id == "" || len(id) > n || !isLowerHex(id) || isZeros(id) {
+		return "", false
+	}
+
+	return strings.Repeat("0", n-len(id)) + id, true
+}
+
+// isLowerHex returns whether s consists only of lower case hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isZeros returns whether s consists only of zeros, which W3C trace context reserves as an invalid ID
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,110 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	type expected struct {
+		traceID, parentSpanID string
+		sampled, err          bool
+	}
+
+	for header, expected := range map[string]expected{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        {traceID: "4bf92f3577b34da6a3ce929d0e0e4736", parentSpanID: "00f067aa0ba902b7", sampled: true},
+		" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ":      {traceID: "4bf92f3577b34da6a3ce929d0e0e4736", parentSpanID: "00f067aa0ba902b7"},
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-future": {traceID: "4bf92f3577b34da6a3ce929d0e0e4736", parentSpanID: "00f067aa0ba902b7", sampled: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra":  {err: true},
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        {err: true},
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":        {err: true},
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":        {err: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":        {err: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x":        {err: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736":                            {err: true},
+		"":                                                               {err: true},
+	} {
+		traceID, parentSpanID, sampled, err := ParseTraceparent(header)
+
+		if (err != nil) != expected.err {
+			t.Fatalf("%v: expected error to be %v but got %v", header, expected.err, err)
+		}
+
+		if traceID != expected.traceID || parentSpanID != expected.parentSpanID || sampled != expected.sampled {
+			t.Fatalf("%v: expected %v, %v, %v but got %v, %v, %v", header, expected.traceID, expected.parentSpanID, expected.sampled, traceID, parentSpanID, sampled)
+		}
+	}
+}
+
+func TestContextFromTraceparent(t *testing.T) {
+	ctx := ContextFromTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "congo=t61rcWkgMzE")
+	child := ContextFrom(ctx, "")
+
+	if TraceID(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" || ParentSpanID(ctx) != "00f067aa0ba902b7" || SpanID(ctx) == "00f067aa0ba902b7" {
+		t.Fatalf("expected the context to be a child of the remote span but got trace '%v', span '%v' and parent '%v'", TraceID(ctx), SpanID(ctx), ParentSpanID(ctx))
+	}
+
+	if expected, actual := "00-4bf92f3577b34da6a3ce929d0e0e4736-"+SpanID(child)+"-00", Traceparent(child); actual != expected {
+		t.Fatalf("expected traceparent '%v' but got '%v'", expected, actual)
+	}
+
+	if Tracestate(child) != "congo=t61rcWkgMzE" {
+		t.Fatalf("expected the tracestate to be propagated but got '%v'", Tracestate(child))
+	}
+
+	restarted := ContextFromTraceparent(context.Background(), "invalid", "congo=t61rcWkgMzE")
+
+	if TraceID(restarted) == "" || ParentSpanID(restarted) != "" || Tracestate(restarted) != "" {
+		t.Fatalf("expected an invalid traceparent to restart the trace but got trace '%v', parent '%v' and state '%v'", TraceID(restarted), ParentSpanID(restarted), Tracestate(restarted))
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	if header := Traceparent(ContextFrom(context.Background(), "")); !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(header) {
+		t.Fatalf("expected a generated trace to have a valid traceparent but got '%v'", header)
+	}
+
+	for traceID, expected := range map[string]string{
+		"4bf92f35-77b3-4da6-a3ce-929d0e0e4736": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"ABC123":                               "00000000000000000000000000abc123",
+		"not-hex":                              "",
+		"000":                                  "",
+	} {
+		ctx := ContextFrom(context.Background(), traceID)
+
+		if expected != "" {
+			expected = "00-" + expected + "-" + SpanID(ctx) + "-01"
+		}
+
+		if actual := Traceparent(ctx); actual != expected {
+			t.Fatalf("%v: expected traceparent '%v' but got '%v'", traceID, expected, actual)
+		}
+	}
+
+	if header := Traceparent(context.Background()); header != "" {
+		t.Fatalf("expected a context without a trace to have no traceparent but got '%v'", header)
+	}
+}
+
+func TestW3CPropagator(t *testing.T) {
+	ctx := W3CPropagator.Extract(context.Background(), http.Header{
+		"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"Tracestate":  {"congo=t61rcWkgMzE"},
+	})
+
+	h := http.Header{}
+	W3CPropagator.Inject(ctx, h)
+
+	if expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + SpanID(ctx) + "-01"; h.Get("traceparent") != expected || h.Get("tracestate") != "congo=t61rcWkgMzE" {
+		t.Fatalf("expected traceparent '%v' and the tracestate to be injected but got '%v'", expected, h)
+	}
+
+	h = http.Header{}
+	W3CPropagator.Inject(context.Background(), h)
+
+	if len(h) != 0 {
+		t.Fatalf("expected no headers to be injected for a context without a trace but got '%v'", h)
+	}
+}
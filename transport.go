@@ -0,0 +1,176 @@
+package qlog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TracingTransport is a http.RoundTripper that continues the trace of each outbound request's context in its headers,
+// with the Propagator set with SetPropagator, and logs its method, URL, status and duration. This links the logs of the
+// client to those of the services it calls.
+//
+// Each request is made in a new span, the child of that of its context, so the logs of the called service have the
+// span of the request as their parent. Idempotent requests that fail without a response, or with a 429, 502, 503 or
+// 504 status, are retried up to Retries times, with each retry logged at the RetrySeverity.
+type TracingTransport struct {
+	// Base makes the requests, by default http.DefaultTransport
+	Base http.RoundTripper
+	// Log writes the logs, by default the default logger
+	Log *Log
+	// Severity is that of the logs of requests that receive a response with a status below 400
+	Severity Severity
+	// ClientErrorSeverity is that of the logs of requests that receive a response with a 4xx status
+	ClientErrorSeverity Severity
+	// ServerErrorSeverity is that of the logs of requests that receive a response with a 5xx status, or no response
+	ServerErrorSeverity Severity
+	// Retries is the number of times a failed idempotent request is retried
+	Retries int
+	// RetryBackoff is the delay before the first retry, doubling for each subsequent retry, unless the response has a
+	// Retry-After header, which takes precedence
+	RetryBackoff time.Duration
+	// RetrySeverity is that of the logs of the attempts that are retried
+	RetrySeverity Severity
+}
+
+// Transport creates a TracingTransport that makes requests with base, or http.DefaultTransport if it is nil. Requests
+// are logged at SeverityInfo, or SeverityWarning for those with a 4xx status and SeverityError for those with a 5xx
+// status or no response. Requests are not retried unless Retries is set.
+//
+// For example:
+//
+//	client := &http.Client{Transport: qlog.Transport(nil)}
+func Transport(base http.RoundTripper) *TracingTransport {
+	return &TracingTransport{
+		Base:                base,
+		Severity:            SeverityInfo,
+		ClientErrorSeverity: SeverityWarning,
+		ServerErrorSeverity: SeverityError,
+		RetryBackoff:        100 * time.Millisecond,
+		RetrySeverity:       SeverityWarning,
+	}
+}
+
+// RoundTrip makes the request in a new span, logging it and retrying it if required
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base, l := t.Base, t.Log
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if l == nil {
+		l = defaultLog.Load()
+	}
+
+	ctx := ContextFrom(req.Context(), "")
+
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(ctx) // a RoundTripper must not modify the request, so the headers are injected in a clone
+		Inject(ctx, r.Header)
+
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			r.Body = body
+		}
+
+		start := time.Now()
+		rs, err := base.RoundTrip(r)
+		labels := []any{"method", req.Method, "url", req.URL.Redacted(), "duration_ms", int(time.Since(start).Milliseconds())}
+
+		if rs != nil {
+			labels = append(labels, "status", rs.StatusCode)
+		}
+
+		if attempt > 1 {
+			labels = append(labels, "attempt", attempt)
+		}
+
+		if delay, ok := t.retry(req, rs, err, attempt); ok {
+			l.logAt(ctx, t.RetrySeverity, "http request retried", err, append(labels, "retry_in_ms", int(delay.Milliseconds()))...)
+
+			if rs != nil {
+				io.Copy(io.Discard, rs.Body) // drain the body so that the connection can be reused
+				rs.Body.Close()
+			}
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		switch {
+		case err != nil || rs.StatusCode >= 500:
+			l.logAt(ctx, t.ServerErrorSeverity, "http request failed", err, labels...)
+		case rs.StatusCode >= 400:
+			l.logAt(ctx, t.ClientErrorSeverity, "http request failed", nil, labels...)
+		default:
+			l.logAt(ctx, t.Severity, "http request completed", nil, labels...)
+		}
+
+		return rs, err
+	}
+}
+
+// retry returns whether the attempt of req, which resulted in rs and err, should be retried and, if so, after what delay
+func (t *TracingTransport) retry(req *http.Request, rs *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt > t.Retries || !idempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return 0, false
+	}
+
+	if err == nil {
+		switch rs.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+
+		if v := rs.Header.Get("Retry-After"); v != "" {
+			if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second, true
+			}
+
+			if at, err := http.ParseTime(v); err == nil {
+				if delay := time.Until(at); delay > 0 {
+					return delay, true
+				}
+
+				return 0, true
+			}
+		}
+	}
+
+	return t.RetryBackoff << (attempt - 1), true
+}
+
+// idempotent returns whether req may be made more than once without changing its effect
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// logAt writes a log of Severity s, if it is registered and enabled for the Log. Unlike Fatal, a log of SeverityFatal
+// does not terminate the process
+func (l *Log) logAt(ctx context.Context, s Severity, message string, err error, labels ...any) {
+	sv, ok := severities[s]
+
+	if !ok || !l.enabled(ctx, sv.flag) {
+		return
+	}
+
+	l.log(ctx, sv.flag, sv.name, message, err, labels...)
+}
@@ -0,0 +1,73 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	requests := atomic.Int32{}
+	traceparents := make(chan string, 3)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case requests.Add(1) == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer srv.Close()
+
+	w := &strings.Builder{}
+	tr := Transport(nil)
+	tr.Log, tr.Retries, tr.RetryBackoff = New(OutputMaskAll, false).WithWriter(w), 1, time.Millisecond
+
+	client := &http.Client{Transport: tr}
+	ctx := ContextFrom(context.Background(), "")
+
+	for _, path := range []string{"/retried", "/missing"} {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+
+		rs, err := client.Do(r)
+
+		if err != nil {
+			t.Fatalf("%v: expected no error but got %v", path, err)
+		}
+
+		rs.Body.Close()
+	}
+
+	for i := 0; i < 3; i++ {
+		if traceID, _, _, err := ParseTraceparent(<-traceparents); err != nil || traceID != TraceID(ctx) {
+			t.Fatalf("expected request %v to carry the trace '%v' but got '%v'", i, TraceID(ctx), traceID)
+		}
+	}
+
+	logs := strings.Split(strings.TrimSpace(w.String()), "\n")
+
+	if len(logs) != 3 {
+		t.Fatalf("expected 3 logs but got %v", len(logs))
+	}
+
+	for i, expected := range [][]string{
+		{`parent_span="` + SpanID(ctx) + `" severity="WARNING"`, `method="GET" url="` + srv.URL + `/retried" duration_ms=`, `status=503 retry_in_ms=0 message="http request retried"`},
+		{`parent_span="` + SpanID(ctx) + `" severity="INFO"`, `status=200 attempt=2 message="http request completed"`},
+		{`severity="WARNING"`, `url="` + srv.URL + `/missing"`, `status=404 message="http request failed"`},
+	} {
+		for _, e := range expected {
+			if !strings.Contains(logs[i], e) {
+				t.Fatalf("expected log %v to contain '%v' but got '%v'", i, e, logs[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// verboseKey is the type of the context key that marks a trace as verbose
+type verboseKey struct{}
+
+// VerboseHeader is the HTTP header that marks a request's trace as verbose, when set to "true", and propagates it to
+// downstream services. As it raises the volume of logs written, it is empty by default, so no header is recognised;
+// set it only where the requests received are trusted, such as behind an authenticating gateway.
+//
+// It is intended for configuration during start-up. It is not safe for concurrent use.
+var VerboseHeader = ""
+
+// verbosity allows Verbose to return without searching the context when no trace has been marked verbose
+var verbosity = atomic.Bool{}
+
+// WithVerbose returns a context.Context whose trace is marked as verbose, so that its logs are written down to Debug,
+// regardless of the OutputMask, any level spec and sampling, while those of other traces are written as normal. This
+// allows the issue of a single request, or customer, to be reproduced in production without raising the verbosity of
+// all logs.
+func WithVerbose(ctx context.Context) context.Context {
+	verbosity.Store(true)
+
+	return context.WithValue(ctx, verboseKey{}, true)
+}
+
+// Verbose reports whether the trace of ctx has been marked as verbose with WithVerbose
+func Verbose(ctx context.Context) bool {
+	if !verbosity.Load() || ctx == nil {
+		return false
+	}
+
+	v, _ := ctx.Value(verboseKey{}).(bool)
+
+	return v
+}
+
+// InjectVerbose sets the VerboseHeader of h, if one is set, when the trace of ctx has been marked as verbose, so that
+// it is propagated to a downstream service
+func InjectVerbose(ctx context.Context, h http.Header) {
+	if VerboseHeader != "" && Verbose(ctx) {
+		h.Set(VerboseHeader, "true")
+	}
+}
+
+// ExtractVerbose returns a context.Context whose trace is marked as verbose if h has a VerboseHeader of "true", or ctx
+// unchanged if it has not, or no VerboseHeader is set
+func ExtractVerbose(ctx context.Context, h http.Header) context.Context {
+	if VerboseHeader == "" {
+		return ctx
+	}
+
+	if v, err := strconv.ParseBool(h.Get(VerboseHeader)); err != nil || !v {
+		return ctx
+	}
+
+	return WithVerbose(ctx)
+}
@@ -0,0 +1,54 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerbose(t *testing.T) {
+	defer func(h string) { VerboseHeader = h }(VerboseHeader)
+
+	sb := strings.Builder{}
+	l := New(OutputMaskImportant, false).WithWriter(&sb)
+
+	normal := ContextFrom(context.Background(), "")
+	verbose := WithVerbose(Sample(ContextFrom(context.Background(), ""), 0))
+
+	for _, ctx := range []context.Context{normal, verbose} {
+		l.Debug(ctx, "debugged")
+		l.Info(ctx, "informed")
+		l.Trace(ctx, "traced")
+	}
+
+	expected := 2
+
+	if !DebugEnabled { // the debug log is compiled out by the qlog_nodebug build tag
+		expected = 1
+	}
+
+	if output := sb.String(); strings.Count(output, "\n") != expected || strings.Contains(output, TraceID(normal)) || strings.Contains(output, "debugged") != DebugEnabled || !strings.Contains(output, "informed") {
+		t.Fatalf("expected only the debug and info logs of the verbose trace to be written but got '%v'", output)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Qlog-Verbose", "true")
+
+	if Verbose(RequestContext(r)) {
+		t.Fatalf("expected the verbose header not to be recognised unless VerboseHeader is set")
+	}
+
+	VerboseHeader = "X-Qlog-Verbose"
+
+	if !Verbose(RequestContext(r)) {
+		t.Fatalf("expected the trace of a request with the verbose header to be verbose")
+	}
+
+	h := http.Header{}
+	Inject(verbose, h)
+
+	if h.Get(VerboseHeader) != "true" {
+		t.Fatalf("expected the verbose header to be injected but got '%v'", h.Get(VerboseHeader))
+	}
+}
@@ -0,0 +1,283 @@
+package qlog
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SeverityWriter is implemented by writers that act on the severity of each log, such as those that route or prioritise logs.
+// When the Writer of a Log implements SeverityWriter, WriteSeverity is called in place of Write with the OutputFlag of the log
+type SeverityWriter interface {
+	io.Writer
+	WriteSeverity(flag OutputMask, b []byte) (int, error)
+}
+
+// EntryWriter is implemented by writers that write each log in their own format, from the Entry that describes it,
+// rather than as encoded by the Log, such as sinks for network protocols. When the Writer of a Log, or a Route of a
+// Router, implements EntryWriter, WriteEntry is called in place of Write with the Entry of the log, whose Labels have
+// been evaluated, along with its encoding. Use Entry.AllLabels to include the labels of the Log.
+// Both are only valid for the duration of the call.
+type EntryWriter interface {
+	io.Writer
+	WriteEntry(e Entry, b []byte) (int, error)
+}
+
+// wantsEntry reports whether w, or any Route of w if it is a Router, is an EntryWriter
+func wantsEntry(w io.Writer) bool {
+	switch w := w.(type) {
+	case *Router:
+		w.mx.RLock()
+		routes := w.routes
+		w.mx.RUnlock()
+
+		for _, rt := range routes {
+			if wantsEntry(rt.Writer) {
+				return true
+			}
+		}
+	case EntryWriter:
+		return true
+	}
+
+	return false
+}
+
+// OnWriteError registers a func to be called when the Writer, or AuditWriter, fails to write a log, replacing any
+// previously registered. It is passed the error and the log, which is only valid for the duration of the call.
+// Logs derived from the Log inherit it.
+//
+// This allows the failure of a destination, such as a network sink, to be detected rather than logs vanishing silently.
+// Set a FallbackWriter to retain the logs that could not be written.
+// This operation is intended for configuration during start-up. It is not safe for concurrent use.
+func (l *Log) OnWriteError(fn func(err error, record []byte)) {
+	l.onWriteError = fn
+}
+
+// Registers a func to be called when the default logger fails to write a log, replacing any previously registered
+// This operation is safe for concurrent use.
+func OnWriteError(fn func(err error, record []byte)) {
+	configure(func(l *Log) { l.OnWriteError(fn) })
+}
+
+// Sets the Writer that receives any log the default logger fails to write, such as stderr; pass nil for none
+// This operation is safe for concurrent use.
+func SetFallbackWriter(w io.Writer) {
+	configure(func(l *Log) { l.FallbackWriter = w })
+}
+
+// Route is a destination of a Router, which receives the logs with a severity included in its Mask
+type Route struct {
+	Writer io.Writer
+	Mask   OutputMask
+}
+
+// Router is a SeverityWriter that writes each log to every Route whose Mask includes its severity.
+// This allows, for example, all logs to be written to stdout with errors duplicated to a separate file, from a single Log.
+//
+// Routes can be attached and detached while the Router is in use, such as to capture logs to a file during an incident
+type Router struct {
+	mx     sync.RWMutex
+	routes []attachedRoute
+	nextID int
+}
+
+// attachedRoute is a Route along with an id that identifies it for detaching
+type attachedRoute struct {
+	Route
+	id int
+}
+
+// NewRouter creates a Router that writes to the specified Routes
+//
+// For example:
+//
+//	qlog.SetWriter(qlog.NewRouter(qlog.Route{Writer: os.Stdout, Mask: qlog.OutputMaskDetail}, qlog.Route{Writer: errFile, Mask: qlog.OutputFlagError|qlog.OutputFlagFatal}))
+func NewRouter(routes ...Route) *Router {
+	r := &Router{}
+
+	for _, rt := range routes {
+		r.Attach(rt)
+	}
+
+	return r
+}
+
+// Attach adds a Route to the Router, returning a func that detaches it. Detaching does not close the Route's Writer.
+// This operation is safe for concurrent use.
+func (r *Router) Attach(rt Route) (detach func()) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	r.routes = append(r.routes[:len(r.routes):len(r.routes)], attachedRoute{Route: rt, id: id}) // copied, so Routes are never modified in place
+
+	return func() {
+		r.mx.Lock()
+		defer r.mx.Unlock()
+
+		routes := make([]attachedRoute, 0, len(r.routes))
+
+		for _, ar := range r.routes {
+			if ar.id != id {
+				routes = append(routes, ar)
+			}
+		}
+
+		r.routes = routes
+	}
+}
+
+// Routes returns the Routes currently attached to the Router
+func (r *Router) Routes() []Route {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	routes := make([]Route, 0, len(r.routes))
+
+	for _, ar := range r.routes {
+		routes = append(routes, ar.Route)
+	}
+
+	return routes
+}
+
+// Write writes b, which has no known severity, to every Route
+func (r *Router) Write(b []byte) (int, error) {
+	return r.WriteSeverity(^OutputFlagNone, b)
+}
+
+// WriteSeverity writes b to every Route whose Mask includes the severity flag. If any write fails, the first error is
+// returned, however b is still written to the remaining Routes
+func (r *Router) WriteSeverity(flag OutputMask, b []byte) (int, error) {
+	return r.WriteEntry(Entry{Flag: flag}, b)
+}
+
+// WriteEntry writes the log described by e, and encoded as b, to every Route whose Mask includes its severity, as an
+// Entry to those that are an EntryWriter. If any write fails, the first error is returned, however the log is still
+// written to the remaining Routes
+func (r *Router) WriteEntry(e Entry, b []byte) (int, error) {
+	r.mx.RLock()
+	routes := r.routes
+	r.mx.RUnlock()
+
+	var err error
+
+	for _, rt := range routes {
+		if rt.Mask&e.Flag == 0 {
+			continue
+		}
+
+		var werr error
+
+		switch w := rt.Writer.(type) {
+		case EntryWriter:
+			_, werr = w.WriteEntry(e, b)
+		case SeverityWriter:
+			_, werr = w.WriteSeverity(e.Flag, b)
+		default:
+			_, werr = w.Write(b)
+		}
+
+		if werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// RetryWriter is an io.Writer that retries writes to its Writer that fail with a transient error, such as those
+// returned intermittently by pipes under pressure, with a truncated exponential backoff between attempts.
+//
+// Should all attempts fail, the whole log is written to the Fallback, if one is set, rather than being lost. Any part of
+// it written to the Writer is left in place, so a reader of the Writer may see a truncated log.
+//
+// The backoff is slept by the goroutine writing the log while it holds the write lock shared by all Logs, so every Log
+// waits for it. Keep MaxBackoff small, or wrap the RetryWriter with an AsyncWriter, such that retries are made by its
+// background goroutine instead
+type RetryWriter struct {
+	// Writer is the destination of the logs
+	Writer io.Writer
+	// Fallback, if not nil, receives any log that could not be written to the Writer
+	Fallback io.Writer
+	// Attempts is the maximum number of times a write is attempted
+	Attempts int
+	// MinBackoff is the delay before the first retry, it doubles with each subsequent retry up to MaxBackoff
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay between retries
+	MaxBackoff time.Duration
+	// Retryable reports whether an error is transient and the write should be retried.
+	// By default, EAGAIN, EPIPE and short writes are considered transient
+	Retryable func(err error) bool
+}
+
+// NewRetryWriter creates a RetryWriter for w that attempts each write up to 5 times, backing off from 1ms
+// up to 50ms between attempts, and uses the passed fallback, which may be nil, if all attempts fail
+func NewRetryWriter(w io.Writer, fallback io.Writer) *RetryWriter {
+	return &RetryWriter{
+		Writer:     w,
+		Fallback:   fallback,
+		Attempts:   5,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+		Retryable:  retryable,
+	}
+}
+
+// Write writes b to the Writer, retrying on transient errors and partial writes
+func (rw *RetryWriter) Write(b []byte) (int, error) {
+	isRetryable := rw.Retryable
+
+	if isRetryable == nil {
+		isRetryable = retryable
+	}
+
+	written, backoff := 0, rw.MinBackoff
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		var n int
+		n, err = rw.Writer.Write(b[written:])
+		written += n
+
+		if err == nil && written == len(b) {
+			return written, nil
+		}
+
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+
+		if !isRetryable(err) || attempt >= rw.Attempts {
+			break
+		}
+
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > rw.MaxBackoff {
+			backoff = rw.MaxBackoff
+		}
+	}
+
+	if rw.Fallback == nil {
+		return written, err
+	}
+
+	if _, ferr := rw.Fallback.Write(b); ferr != nil { // a fragment of a log is meaningless, so the whole log is written
+		return written, err
+	}
+
+	return len(b), nil
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrShortWrite)
+}
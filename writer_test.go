@@ -0,0 +1,139 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type flakyWriter struct {
+	failures int
+	err      error
+	strings.Builder
+}
+
+func (fw *flakyWriter) Write(b []byte) (int, error) {
+	if fw.failures > 0 {
+		fw.failures--
+		fw.Builder.Write(b[:1]) // partially write the log before failing
+
+		return 1, fw.err
+	}
+
+	return fw.Builder.Write(b)
+}
+
+func TestRetryWriter(t *testing.T) {
+	tcs := []struct {
+		Desc             string
+		Failures         int
+		Err              error
+		ExpectedWriter   string
+		ExpectedFallback string
+	}{
+		{Desc: "TestNoFailures", ExpectedWriter: "test log"},
+		{Desc: "TestRetried", Failures: 2, Err: syscall.EAGAIN, ExpectedWriter: "test log"},
+		{Desc: "TestExhausted", Failures: 3, Err: syscall.EPIPE, ExpectedWriter: "tes", ExpectedFallback: "test log"},
+		{Desc: "TestNotRetryable", Failures: 1, Err: fmt.Errorf("permanent"), ExpectedWriter: "t", ExpectedFallback: "test log"},
+	}
+
+	for _, tc := range tcs {
+		w, fallback := &flakyWriter{failures: tc.Failures, err: tc.Err}, strings.Builder{}
+		rw := NewRetryWriter(w, &fallback)
+		rw.Attempts, rw.MinBackoff, rw.MaxBackoff = 3, time.Microsecond, 2*time.Microsecond
+
+		n, err := rw.Write([]byte("test log"))
+
+		if err != nil || n != len("test log") {
+			t.Fatalf("%v: expected complete write but got %v, %v", tc.Desc, n, err)
+		}
+
+		if w.String() != tc.ExpectedWriter || fallback.String() != tc.ExpectedFallback {
+			t.Fatalf("%v: expected writer '%v' and fallback '%v' but got '%v' and '%v'", tc.Desc, tc.ExpectedWriter, tc.ExpectedFallback, w.String(), fallback.String())
+		}
+	}
+
+	rw := NewRetryWriter(&flakyWriter{failures: 10, err: syscall.EAGAIN}, nil)
+	rw.MinBackoff, rw.MaxBackoff = time.Microsecond, time.Microsecond
+
+	if _, err := rw.Write([]byte("test log")); err == nil {
+		t.Fatalf("expected error when no fallback is set")
+	}
+}
+
+func TestRouter(t *testing.T) {
+	all, errs := strings.Builder{}, strings.Builder{}
+
+	l := New(OutputMaskAll, false)
+	l.Writer = NewRouter(Route{Writer: &all, Mask: OutputMaskDetail}, Route{Writer: &errs, Mask: OutputFlagError | OutputFlagFatal})
+
+	ctx := ContextFrom(context.Background(), "")
+
+	l.Info(ctx, "info message")
+	l.Error(ctx, "error message", nil)
+	l.Debug(ctx, "debug message")
+
+	if output := all.String(); strings.Count(output, "\n") != 2 || !strings.Contains(output, "info message") || !strings.Contains(output, "error message") {
+		t.Fatalf("expected info and error logs to be routed to the detail writer but got '%v'", output)
+	}
+
+	if output := errs.String(); strings.Count(output, "\n") != 1 || !strings.Contains(output, "error message") {
+		t.Fatalf("expected only error logs to be routed to the error writer but got '%v'", output)
+	}
+}
+
+func TestSetSplitOutput(t *testing.T) {
+	SetSplitOutput()
+	defer SetWriter(os.Stderr)
+
+	r, ok := defaultLog.Load().Writer.(*Router)
+
+	if !ok || len(r.routes) != 2 {
+		t.Fatalf("expected the default writer to be a router but got '%T'", defaultLog.Load().Writer)
+	}
+
+	for _, flag := range []OutputMask{OutputFlagDebug, OutputFlagTrace, OutputFlagInfo, OutputFlagNotice} {
+		if r.routes[0].Writer != os.Stdout || r.routes[0].Mask&flag == 0 || r.routes[1].Mask&flag != 0 {
+			t.Fatalf("expected '%v' to be routed to stdout only", flag)
+		}
+	}
+
+	for _, flag := range []OutputMask{OutputFlagWarning, OutputFlagError, OutputFlagFatal, OutputFlagAudit} {
+		if r.routes[1].Writer != os.Stderr || r.routes[1].Mask&flag == 0 || r.routes[0].Mask&flag != 0 {
+			t.Fatalf("expected '%v' to be routed to stderr only", flag)
+		}
+	}
+}
+
+func TestOnWriteError(t *testing.T) {
+	fallback := strings.Builder{}
+	l := New(OutputMaskAll, false)
+	l.Writer, l.FallbackWriter = &flakyWriter{failures: 1, err: syscall.EPIPE}, &fallback
+
+	var (
+		reported error
+		record   string
+	)
+
+	l.OnWriteError(func(err error, b []byte) { reported, record = err, string(b) })
+	l.Info(context.Background(), "lost")
+
+	if reported != syscall.EPIPE || !strings.Contains(record, `message="lost"`) {
+		t.Fatalf("expected the write error and log to be reported but got '%v' and '%v'", reported, record)
+	}
+
+	if output := fallback.String(); output != record {
+		t.Fatalf("expected the log to be written to the fallback but got '%v'", output)
+	}
+
+	reported = nil
+	l.Info(context.Background(), "written")
+
+	if reported != nil || strings.Count(fallback.String(), "\n") != 1 {
+		t.Fatalf("expected successful writes not to be reported but got '%v'", reported)
+	}
+}
@@ -0,0 +1,105 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// XRayHeader is the header in which AWS X-Ray propagates traces between services
+const XRayHeader = "X-Amzn-Trace-Id"
+
+// NewXRayTraceID returns a new, unique, Trace-ID in the format of AWS X-Ray, such as
+// `1-5759e988-bd862e3fe1be46a994272793`, where the second field is the current time, in hex encoded epoch seconds. Pass
+// it to ContextFrom to start a trace that can be propagated to services instrumented with X-Ray
+func NewXRayTraceID() string {
+	return "1-" + fmt.Sprintf("%08x", uint32(timeNow().Unix())) + "-" + randomHex(12)
+}
+
+// ParseXRayHeader parses an AWS X-Ray XRayHeader, such as
+// `Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1`, returning the Trace-ID, in X-Ray
+// format, the Span-ID of the remote segment and whether the remote segment sampled the trace
+func ParseXRayHeader(header string) (traceID, parentSpanID string, sampled bool, err error) {
+	sampled = true // the absence of a decision defers it to the receiver, which samples all traces
+
+	for _, field := range strings.Split(header, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+
+		switch k {
+		case "Root":
+			traceID = v
+		case "Parent":
+			parentSpanID = v
+		case "Sampled":
+			sampled = v != "0"
+		}
+	}
+
+	if !isXRayTraceID(traceID) {
+		return "", "", false, fmt.Errorf("invalid x-ray header: invalid root '%v'", traceID)
+	}
+
+	if len(parentSpanID) != 16 || !isLowerHex(parentSpanID) || isZeros(parentSpanID) {
+		return "", "", false, fmt.Errorf("invalid x-ray header: invalid parent '%v'", parentSpanID)
+	}
+
+	return traceID, parentSpanID, sampled, nil
+}
+
+// XRayTraceID returns the Trace-ID associated with the passed ctx in the format of AWS X-Ray, or an empty string where
+// ctx has no trace, or its Trace-ID cannot be represented in that format. Trace-IDs in the format of W3C trace context
+// are converted in the same manner as X-Ray, with the first 8 hex digits as the time
+func XRayTraceID(ctx context.Context) string {
+	traceID := TraceID(ctx)
+
+	if isXRayTraceID(traceID) {
+		return traceID
+	}
+
+	if id, ok := traceContextID(traceID, 32); ok {
+		return "1-" + id[:8] + "-" + id[8:]
+	}
+
+	return ""
+}
+
+// isXRayTraceID returns whether id is an AWS X-Ray Trace-ID, such as `1-5759e988-bd862e3fe1be46a994272793`
+func isXRayTraceID(id string) bool {
+	return len(id) == 35 && strings.HasPrefix(id, "1-") && id[10] == '-' && isLowerHex(id[2:10]) && isLowerHex(id[11:]) && !isZeros(id[11:])
+}
+
+// xrayPropagator is a Propagator for AWS X-Ray
+type xrayPropagator struct{}
+
+func (xrayPropagator) Extract(ctx context.Context, h http.Header) context.Context {
+	traceID, parentSpanID, sampled, err := ParseXRayHeader(h.Get(XRayHeader))
+
+	if err != nil {
+		return ContextFrom(ctx, "")
+	}
+
+	return contextFromRemote(ctx, traceID, parentSpanID, sampled, "")
+}
+
+func (xrayPropagator) Inject(ctx context.Context, h http.Header) {
+	traceID := XRayTraceID(ctx)
+
+	if traceID == "" {
+		return
+	}
+
+	spanID, ok := traceContextID(SpanID(ctx), 16)
+
+	if !ok {
+		return
+	}
+
+	sampled := "1"
+
+	if unsampled(ctx) {
+		sampled = "0"
+	}
+
+	h.Set(XRayHeader, "Root="+traceID+";Parent="+spanID+";Sampled="+sampled)
+}
@@ -0,0 +1,71 @@
+package qlog
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewXRayTraceID(t *testing.T) {
+	timeNow = func() time.Time { return time.Unix(1465510280, 0) }
+	defer func() { timeNow = time.Now }()
+
+	if id := NewXRayTraceID(); !regexp.MustCompile(`^1-5759e988-[0-9a-f]{24}$`).MatchString(id) || !isXRayTraceID(id) {
+		t.Fatalf("expected an x-ray trace id with the current time but got '%v'", id)
+	}
+}
+
+func TestParseXRayHeader(t *testing.T) {
+	type expected struct {
+		traceID, parentSpanID string
+		sampled, err          bool
+	}
+
+	for header, expected := range map[string]expected{
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1":   {traceID: "1-5759e988-bd862e3fe1be46a994272793", parentSpanID: "53995c3f42cd8ad8", sampled: true},
+		"Sampled=0; Parent=53995c3f42cd8ad8; Root=1-5759e988-bd862e3fe1be46a994272793": {traceID: "1-5759e988-bd862e3fe1be46a994272793", parentSpanID: "53995c3f42cd8ad8"},
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8":             {traceID: "1-5759e988-bd862e3fe1be46a994272793", parentSpanID: "53995c3f42cd8ad8", sampled: true},
+		"Root=1-5759e988-bd862e3fe1be46a994272793":                                     {err: true},
+		"Root=2-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8":             {err: true},
+		"Root=1-5759e988-bd862e3fe1be46a9942727;Parent=53995c3f42cd8ad8":               {err: true},
+		"": {err: true},
+	} {
+		traceID, parentSpanID, sampled, err := ParseXRayHeader(header)
+
+		if (err != nil) != expected.err {
+			t.Fatalf("%v: expected error to be %v but got %v", header, expected.err, err)
+		}
+
+		if traceID != expected.traceID || parentSpanID != expected.parentSpanID || sampled != expected.sampled {
+			t.Fatalf("%v: expected %v, %v, %v but got %v, %v, %v", header, expected.traceID, expected.parentSpanID, expected.sampled, traceID, parentSpanID, sampled)
+		}
+	}
+}
+
+func TestXRayPropagator(t *testing.T) {
+	ctx := XRayPropagator.Extract(context.Background(), http.Header{XRayHeader: {"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}})
+
+	if TraceID(ctx) != "1-5759e988-bd862e3fe1be46a994272793" || ParentSpanID(ctx) != "53995c3f42cd8ad8" {
+		t.Fatalf("expected the context to be a child of the remote segment but got trace '%v' and parent '%v'", TraceID(ctx), ParentSpanID(ctx))
+	}
+
+	h := http.Header{}
+	XRayPropagator.Inject(ctx, h)
+	W3CPropagator.Inject(ctx, h)
+
+	if expected := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=" + SpanID(ctx) + ";Sampled=1"; h.Get(XRayHeader) != expected {
+		t.Fatalf("expected x-ray header '%v' but got '%v'", expected, h.Get(XRayHeader))
+	}
+
+	if expected := "00-5759e988bd862e3fe1be46a994272793-" + SpanID(ctx) + "-01"; h.Get("traceparent") != expected {
+		t.Fatalf("expected the x-ray trace to be converted to traceparent '%v' but got '%v'", expected, h.Get("traceparent"))
+	}
+
+	w3c := ContextFromTraceparent(context.Background(), "00-5759e988bd862e3fe1be46a994272793-00f067aa0ba902b7-01", "")
+
+	if XRayTraceID(w3c) != "1-5759e988-bd862e3fe1be46a994272793" {
+		t.Fatalf("expected the w3c trace to be converted to the x-ray trace but got '%v'", XRayTraceID(w3c))
+	}
+}